@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+
+	"github.com/killabayte/golang-telegram-bot/internal/backup"
+	"github.com/killabayte/golang-telegram-bot/internal/config"
+	"github.com/killabayte/golang-telegram-bot/internal/storage"
+)
+
+// Names of the entries inside a backup archive. Each is restored to the path
+// the (restored) configuration expects, not to where it was found.
+const (
+	backupConfigEntry  = "config.yaml"
+	backupIdeasEntry   = "ideas.json"
+	backupHistoryEntry = "history.db"
+
+	// defaultRestoreConfigPath is used when restore runs without --config.
+	defaultRestoreConfigPath = "config.yaml"
+)
+
+// runBackup writes an encrypted archive of the config file and the selected
+// profile's ideas and history database to archivePath.
+func runBackup(configPath, profileName, archivePath string) error {
+	cfg, err := config.Load(configPath, profileName)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	var files []backup.File
+	if configPath != "" {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return err
+		}
+		files = append(files, backup.File{Name: backupConfigEntry, Data: data})
+	}
+	data, err := os.ReadFile(cfg.IdeasFile)
+	switch {
+	case err == nil:
+		files = append(files, backup.File{Name: backupIdeasEntry, Data: data})
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	if cfg.Storage.Path != "" {
+		if _, err := os.Stat(cfg.Storage.Path); err == nil {
+			history, err := storage.Open(cfg.Storage.Path, 0)
+			if err != nil {
+				return err
+			}
+			data, err := history.Snapshot()
+			history.Close()
+			if err != nil {
+				return err
+			}
+			files = append(files, backup.File{Name: backupHistoryEntry, Data: data})
+		}
+	}
+	if len(files) == 0 {
+		return errors.New("nothing to back up: no --config file and no ideas saved yet")
+	}
+
+	passphrase, err := readPassphrase(true)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(passphrase)
+
+	var archive bytes.Buffer
+	if err := backup.Write(&archive, passphrase, files); err != nil {
+		return err
+	}
+	if err := os.WriteFile(archivePath, archive.Bytes(), 0o600); err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Printf("Backed up %s (%d bytes)\n", f.Name, len(f.Data))
+	}
+	return nil
+}
+
+// runRestore unpacks the archive at archivePath. The config goes to
+// configPath (config.yaml by default), ideas and history to the restored
+// profile's ideas_file and storage.path. Existing files are left alone unless
+// force is set.
+func runRestore(configPath, profileName, archivePath string, force bool) error {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(passphrase)
+
+	files, err := backup.Read(archive, passphrase)
+	if err != nil {
+		return err
+	}
+	entries := make(map[string][]byte, len(files))
+	for _, f := range files {
+		entries[f.Name] = f.Data
+	}
+
+	if data, ok := entries[backupConfigEntry]; ok {
+		if configPath == "" {
+			configPath = defaultRestoreConfigPath
+		}
+		if err := restoreFile(configPath, data, force); err != nil {
+			return err
+		}
+	}
+	cfg, err := config.Load(configPath, profileName)
+	if err != nil {
+		return fmt.Errorf("loading restored config: %w", err)
+	}
+	if data, ok := entries[backupIdeasEntry]; ok {
+		if err := restoreFile(cfg.IdeasFile, data, force); err != nil {
+			return err
+		}
+	}
+	if data, ok := entries[backupHistoryEntry]; ok {
+		if cfg.Storage.Path == "" {
+			fmt.Println("Skipping history.db: the restored profile has no storage.path")
+		} else if err := restoreFile(cfg.Storage.Path, data, force); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func restoreFile(path string, data []byte, force bool) error {
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists; use restore --force to overwrite it", path)
+		}
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %s (%d bytes)\n", path, len(data))
+	return nil
+}
+
+// readPassphrase takes the archive passphrase from BOT_BACKUP_PASSPHRASE or
+// prompts for it. When creating an archive on a terminal it asks twice.
+func readPassphrase(confirm bool) ([]byte, error) {
+	return promptPassphrase("BOT_BACKUP_PASSPHRASE", "Backup passphrase: ", confirm)
+}
+
+// promptPassphrase takes a passphrase from the environment variable env or
+// prompts for it on stderr, asking twice on a terminal if confirm is set.
+func promptPassphrase(env, prompt string, confirm bool) ([]byte, error) {
+	if v := os.Getenv(env); v != "" {
+		return []byte(v), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		line, err := bufio.NewReader(os.Stdin).ReadBytes('\n')
+		if err != nil && len(line) == 0 {
+			return nil, fmt.Errorf("reading passphrase: %w", err)
+		}
+		return bytes.TrimSpace(line), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("reading passphrase: %w", err)
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		defer zeroBytes(again)
+		if err != nil {
+			return nil, fmt.Errorf("reading passphrase: %w", err)
+		}
+		if !bytes.Equal(passphrase, again) {
+			zeroBytes(passphrase)
+			return nil, errors.New("passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/killabayte/golang-telegram-bot/internal/config"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// globalFlags are accepted by every command.
+type globalFlags struct {
+	configPath  string
+	profileName string
+	noColor     bool
+	promptKeys  bool
+	// configIdentity is the age identity file for an encrypted config.
+	configIdentity string
+}
+
+// newRootCommand builds the command tree. Run without a subcommand, the bot
+// reports every open position once.
+func newRootCommand() *cobra.Command {
+	flags := &globalFlags{}
+	var watch, listen bool
+	var output, layout string
+
+	root := &cobra.Command{
+		Use:   "golang-telegram-bot",
+		Short: "Compare MEXC futures positions with their fair prices",
+		Long: "Compares the fair price of every open MEXC futures position with its average\n" +
+			"entry price and reports the difference, once or continuously.",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// Flags parsed fine, so from here on errors aren't about usage.
+			cmd.SilenceUsage = true
+			useColor = colorEnabled(flags.noColor)
+			config.Decryption = configDecryption(flags.configIdentity)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(output); err != nil {
+				return err
+			}
+			if err := checkLayout(layout); err != nil {
+				return err
+			}
+			s, err := openSession(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer s.close()
+			if watch || listen {
+				return runDaemon(cmd.Context(), s, watch, listen)
+			}
+			if s.notifier != nil && output == outputPlain && !cmd.Flags().Changed("layout") {
+				return runReport(cmd.Context(), s)
+			}
+			return writeReport(cmd.Context(), s, cmd.OutOrStdout(), output, layout)
+		},
+	}
+	addOutputFlag(root, &output)
+	root.Flags().StringVar(&layout, "layout", layoutVerbose, "console report layout: verbose, compact or table")
+
+	pf := root.PersistentFlags()
+	pf.StringVar(&flags.configPath, "config", os.Getenv("BOT_CONFIG"), "path to a YAML config file")
+	pf.StringVar(&flags.profileName, "profile", os.Getenv("BOT_PROFILE"), "config profile to use")
+	pf.BoolVar(&flags.noColor, "no-color", false, "disable colored output")
+	pf.BoolVar(&flags.promptKeys, "prompt-keys", false, "read the API key pair from stdin instead of the environment")
+	pf.StringVar(&flags.configIdentity, "config-identity", os.Getenv("BOT_CONFIG_IDENTITY"), "age identity file for an encrypted config")
+
+	// The flags from before there were subcommands still work.
+	root.Flags().BoolVar(&watch, "watch", false, "keep polling and report only changes")
+	root.Flags().BoolVar(&listen, "listen", false, "answer Telegram commands")
+	root.Flags().MarkDeprecated("watch", "use the watch command instead")
+	root.Flags().MarkDeprecated("listen", "use the serve command instead")
+
+	root.AddCommand(
+		newPositionsCommand(flags),
+		newPriceCommand(flags),
+		newWatchCommand(flags),
+		newServeCommand(flags),
+		newAlertsCommand(flags),
+		newBackupCommand(flags),
+		newRestoreCommand(flags),
+		newConfigCommand(flags),
+	)
+	return root
+}
+
+func newPositionsCommand(flags *globalFlags) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "positions",
+		Short: "List open positions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(output); err != nil {
+				return err
+			}
+			s, err := openSession(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer s.close()
+
+			positions, err := s.ex.OpenPositions(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("fetching open positions: %w", err)
+			}
+			var tracked []mexc.Position
+			for _, pos := range positions {
+				if s.cfg.WatchesSymbol(pos.Symbol) {
+					tracked = append(tracked, pos)
+				}
+			}
+			return positionsOutput(tracked).write(cmd.OutOrStdout(), output)
+		},
+	}
+	addOutputFlag(cmd, &output)
+	return cmd
+}
+
+func newPriceCommand(flags *globalFlags) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:     "price SYMBOL",
+		Short:   "Show the fair price of a contract",
+		Example: "  golang-telegram-bot price BTC_USDT",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(output); err != nil {
+				return err
+			}
+			s, err := openSession(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer s.close()
+			history, err := s.openHistory()
+			if err != nil {
+				return err
+			}
+			if history != nil {
+				defer history.Close()
+			}
+
+			q, err := fetchQuote(cmd.Context(), s.ex, history, args[0])
+			if err != nil {
+				return fmt.Errorf("fetching fair price: %w", err)
+			}
+			return quoteOutput(q).write(cmd.OutOrStdout(), output)
+		},
+	}
+	addOutputFlag(cmd, &output)
+	return cmd
+}
+
+func newWatchCommand(flags *globalFlags) *cobra.Command {
+	var listen bool
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Keep polling positions and report only changes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openSession(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer s.close()
+			return runDaemon(cmd.Context(), s, true, listen)
+		},
+	}
+	cmd.Flags().BoolVar(&listen, "listen", false, "also answer Telegram commands")
+	return cmd
+}
+
+func newServeCommand(flags *globalFlags) *cobra.Command {
+	var watch bool
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Answer Telegram commands",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openSession(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer s.close()
+			return runDaemon(cmd.Context(), s, watch, true)
+		},
+	}
+	cmd.Flags().BoolVar(&watch, "watch", false, "also watch positions and report changes")
+	return cmd
+}
+
+func newAlertsCommand(flags *globalFlags) *cobra.Command {
+	alerts := &cobra.Command{
+		Use:   "alerts",
+		Short: "Inspect sent alerts",
+	}
+
+	var output string
+	var since time.Duration
+	var limit int
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List alerts recorded in the history database, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(output); err != nil {
+				return err
+			}
+			s, err := openSession(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer s.close()
+			history, err := s.openHistory()
+			if err != nil {
+				return err
+			}
+			if history == nil {
+				return fmt.Errorf("profile %q has no storage.path, so no alerts are recorded", s.cfg.Name)
+			}
+			defer history.Close()
+
+			events, err := history.Alerts(time.Now().Add(-since), limit)
+			if err != nil {
+				return fmt.Errorf("reading alerts: %w", err)
+			}
+			return alertsOutput(events).write(cmd.OutOrStdout(), output)
+		},
+	}
+	list.Flags().DurationVar(&since, "since", 24*time.Hour, "how far back to list")
+	list.Flags().IntVar(&limit, "limit", 50, "maximum number of alerts")
+	addOutputFlag(list, &output)
+
+	alerts.AddCommand(list)
+	return alerts
+}
+
+func newBackupCommand(flags *globalFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "backup ARCHIVE",
+		Short: "Write the config, ideas and history to an encrypted archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := runBackup(flags.configPath, flags.profileName, args[0]); err != nil {
+				return fmt.Errorf("creating backup: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+func newRestoreCommand(flags *globalFlags) *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "restore ARCHIVE",
+		Short: "Restore the config, ideas and history from an archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := runRestore(flags.configPath, flags.profileName, args[0], force); err != nil {
+				return fmt.Errorf("restoring backup: %w", err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite existing files")
+	return cmd
+}
+
+func newConfigCommand(flags *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Work with the config file",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Check every profile in the config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateConfig(flags.configPath, flags.profileName); err != nil {
+				fmt.Fprintln(cmd.OutOrStdout(), err)
+				return exitError(1)
+			}
+			return nil
+		},
+	})
+	return cmd
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+)
+
+// useColor controls whether console output is wrapped in ANSI color codes.
+var useColor bool
+
+// colorEnabled reports whether stdout should receive ANSI colors. Colors are
+// disabled by --no-color, the NO_COLOR environment variable, or when stdout
+// is not a terminal.
+func colorEnabled(noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize wraps s in the given ANSI code when colors are enabled.
+func colorize(code, s string) string {
+	if !useColor || code == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// breachColor adds bold to code for a position past its threshold.
+func breachColor(code string, breached bool) string {
+	if !breached {
+		return code
+	}
+	return ansiBold + code
+}
+
+// divergenceColor picks green when the fair price has moved in the position's
+// favor and red when it has moved against it.
+func divergenceColor(positionType int, difference float64) string {
+	favorable := difference > 0
+	if positionType == mexc.PositionTypeShort {
+		favorable = !favorable
+	}
+	if favorable {
+		return ansiGreen
+	}
+	return ansiRed
+}
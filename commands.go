@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/internal/storage"
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+	"github.com/killabayte/golang-telegram-bot/pkg/telegram"
+)
+
+// priceLookback is how far back /price compares the current fair price, and
+// priceLookbackTolerance how stale the stored sample for that time may be.
+const (
+	priceLookback          = 24 * time.Hour
+	priceLookbackTolerance = time.Hour
+)
+
+// alertHistoryPageSize is how many alerts a page of /alerts history lists.
+const alertHistoryPageSize = 10
+
+// quote is a contract's fair price, with the price priceLookback earlier
+// when the history database has one.
+type quote struct {
+	Symbol    string   `json:"symbol"`
+	FairPrice float64  `json:"fair_price"`
+	DayAgo    *float64 `json:"fair_price_24h_ago,omitempty"`
+}
+
+// fetchQuote looks up the fair price of symbol. history may be nil.
+func fetchQuote(ctx context.Context, api exchange.Exchange, history *storage.DB, symbol string) (quote, error) {
+	q := quote{Symbol: strings.ToUpper(symbol)}
+	fairPrice, err := api.FairPrice(ctx, q.Symbol)
+	if err != nil {
+		return q, err
+	}
+	q.FairPrice = fairPrice
+	if history == nil || fairPrice == 0 {
+		return q, nil
+	}
+	then, _, ok, err := history.PriceAt(q.Symbol, time.Now().Add(-priceLookback), priceLookbackTolerance)
+	if err != nil {
+		return q, err
+	}
+	if ok && then != 0 {
+		q.DayAgo = &then
+	}
+	return q, nil
+}
+
+func (q quote) String() string {
+	if q.FairPrice == 0 {
+		return fmt.Sprintf("No fair price available for %s.", q.Symbol)
+	}
+	s := fmt.Sprintf("%s fair price: %f", q.Symbol, q.FairPrice)
+	if q.DayAgo != nil {
+		then := *q.DayAgo
+		s += fmt.Sprintf("\n24h ago: %f (%+.2f%%)", then, (q.FairPrice-then)/then*100)
+	}
+	return s
+}
+
+// formatPositions lists positions one per line.
+func formatPositions(positions []mexc.Position) string {
+	if len(positions) == 0 {
+		return "No open positions."
+	}
+	var b strings.Builder
+	for _, pos := range positions {
+		fmt.Fprintf(&b, "%s %s %dx: %g contracts @ %f", pos.Symbol, pos.Side(), pos.Leverage, pos.HoldVol, pos.HoldAvgPrice)
+		if pos.CreateTime != 0 {
+			fmt.Fprintf(&b, ", held %s", formatHeldFor(time.Since(pos.OpenedAt())))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// registerCommands wires the bot's Telegram commands to the exchange. spot is
+// the MEXC client whose spot holdings /portfolio includes, and history the
+// price and alert database; either may be nil.
+func registerCommands(router *telegram.Router, api exchange.Exchange, spot *mexc.Client, history *storage.DB) {
+	router.Handle("positions", "", "List open positions", func(ctx context.Context, args []string) (string, error) {
+		positions, err := api.OpenPositions(ctx)
+		if err != nil {
+			return "", err
+		}
+		return formatPositions(positions), nil
+	})
+
+	router.Handle("price", "SYMBOL", "Show the fair price of a contract, e.g. /price BTC_USDT", func(ctx context.Context, args []string) (string, error) {
+		if len(args) != 1 {
+			return "Usage: /price SYMBOL (for example /price BTC_USDT)", nil
+		}
+		q, err := fetchQuote(ctx, api, history, args[0])
+		if err != nil {
+			return "", err
+		}
+		return q.String(), nil
+	})
+
+	router.Handle("pnl", "", "Show unrealized and realized PnL per position", func(ctx context.Context, args []string) (string, error) {
+		positions, err := api.OpenPositions(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(positions) == 0 {
+			return "No open positions.", nil
+		}
+
+		var b strings.Builder
+		var totalUnrealized, totalRealised float64
+		for _, pos := range positions {
+			fairPrice, err := api.FairPrice(ctx, pos.Symbol)
+			if err != nil {
+				fmt.Fprintf(&b, "%s: error fetching fair price: %v\n", pos.Symbol, err)
+				continue
+			}
+			contractSize, err := exchange.ContractSize(ctx, api, pos.Symbol)
+			if err != nil {
+				fmt.Fprintf(&b, "%s: error fetching contract size: %v\n", pos.Symbol, err)
+				continue
+			}
+
+			unrealized := pos.UnrealizedPnL(fairPrice, contractSize)
+			totalUnrealized += unrealized
+			totalRealised += pos.Realised
+			fmt.Fprintf(&b, "%s %s: unrealized %.4f, realized %.4f\n", pos.Symbol, pos.Side(), unrealized, pos.Realised)
+		}
+		fmt.Fprintf(&b, "Total: unrealized %.4f, realized %.4f", totalUnrealized, totalRealised)
+		return b.String(), nil
+	})
+
+	router.Handle("exposure", "", "Show net long/short exposure per underlying", func(ctx context.Context, args []string) (string, error) {
+		positions, err := api.OpenPositions(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(positions) == 0 {
+			return "No open positions.", nil
+		}
+
+		var b strings.Builder
+		sizes := make(map[string]float64)
+		for _, pos := range positions {
+			if _, ok := sizes[pos.Symbol]; ok {
+				continue
+			}
+			contractSize, err := exchange.ContractSize(ctx, api, pos.Symbol)
+			if err != nil {
+				fmt.Fprintf(&b, "%s: error fetching contract size: %v\n", pos.Symbol, err)
+			}
+			sizes[pos.Symbol] = contractSize
+		}
+
+		for _, e := range monitor.Netting(positions, func(symbol string) float64 { return sizes[symbol] }) {
+			fmt.Fprintf(&b, "%s: net %s", e.Underlying, formatExposure(e))
+			if e.Hedged() {
+				fmt.Fprintf(&b, ", %.0f%% off balance", e.Drift()*100)
+			}
+			b.WriteString("\n")
+		}
+		return strings.TrimSuffix(b.String(), "\n"), nil
+	})
+
+	router.Handle("risk", "", "Show PnL sensitivity to price and funding per position", func(ctx context.Context, args []string) (string, error) {
+		rates, ok := api.(exchange.FundingSource)
+		if !ok {
+			return fmt.Sprintf("%s doesn't report funding rates.", api.Name()), nil
+		}
+		positions, err := api.OpenPositions(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(positions) == 0 {
+			return "No open positions.", nil
+		}
+
+		var b strings.Builder
+		var total mexc.Sensitivity
+		for _, pos := range positions {
+			fairPrice, err := api.FairPrice(ctx, pos.Symbol)
+			if err != nil {
+				fmt.Fprintf(&b, "%s: error fetching fair price: %v\n", pos.Symbol, err)
+				continue
+			}
+			contractSize, err := exchange.ContractSize(ctx, api, pos.Symbol)
+			if err != nil {
+				fmt.Fprintf(&b, "%s: error fetching contract size: %v\n", pos.Symbol, err)
+				continue
+			}
+			funding, err := rates.FundingRate(ctx, pos.Symbol)
+			if err != nil {
+				fmt.Fprintf(&b, "%s: error fetching funding rate: %v\n", pos.Symbol, err)
+				continue
+			}
+
+			s := pos.Sensitivity(fairPrice, contractSize, funding)
+			total = total.Add(s)
+			fmt.Fprintf(&b, "%s %s %dx: notional %.2f, %s\n", pos.Symbol, pos.Side(), pos.Leverage, s.Notional, formatSensitivity(s))
+			fmt.Fprintf(&b, "  a 1%% move is %d%% of margin; funding rate %+.4f%%\n", pos.Leverage, funding.Rate*100)
+		}
+		fmt.Fprintf(&b, "Total: notional %.2f, %s", total.Notional, formatSensitivity(total))
+		return b.String(), nil
+	})
+
+	router.Handle("balance", "", "Show available, frozen and margin balance per currency", func(ctx context.Context, args []string) (string, error) {
+		balances, err := api.Balances(ctx)
+		if err != nil {
+			return "", err
+		}
+		return formatBalances(balances), nil
+	})
+
+	router.Handle("portfolio", "", "Show futures equity and spot holdings valued in USDT", func(ctx context.Context, args []string) (string, error) {
+		balances, err := api.Balances(ctx)
+		if err != nil {
+			return "", err
+		}
+		if spot == nil {
+			return formatPortfolio(balances, nil, nil), nil
+		}
+		spotBalances, err := spot.SpotBalances(ctx)
+		if err != nil {
+			return "", fmt.Errorf("fetching spot balances: %w", err)
+		}
+		prices, err := spot.SpotPrices(ctx)
+		if err != nil {
+			return "", fmt.Errorf("fetching spot prices: %w", err)
+		}
+		return formatPortfolio(balances, mexc.ValueSpot(spotBalances, prices), prices), nil
+	})
+
+	router.Handle("alerts", "history [PAGE]", "Browse sent alerts and whether they resolved, newest first", func(ctx context.Context, args []string) (string, error) {
+		if len(args) > 0 && args[0] == "history" {
+			args = args[1:]
+		}
+		page := 1
+		if len(args) > 0 {
+			var err error
+			if page, err = strconv.Atoi(args[0]); err != nil || page < 1 || len(args) > 1 {
+				return "Usage: /alerts history [PAGE] (for example /alerts history 2)", nil
+			}
+		}
+		if history == nil {
+			return "No alerts are recorded: the profile has no storage.path.", nil
+		}
+		view, err := loadAlertHistory(history, page, alertHistoryPageSize)
+		if err != nil {
+			return "", err
+		}
+		return formatAlertHistory(view, time.Now()), nil
+	})
+}
+
+// formatBalances lists the futures wallet of every currency.
+func formatBalances(balances []exchange.Balance) string {
+	if len(balances) == 0 {
+		return "No balances."
+	}
+	var b strings.Builder
+	for _, bal := range balances {
+		fmt.Fprintf(&b, "%s: equity %.4f, available %.4f, frozen %.4f, margin %.4f, unrealized %+.4f\n",
+			bal.Currency, bal.Equity, bal.Available, bal.Frozen, bal.Margin, bal.Unrealized)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// formatPortfolio lists futures balances and spot holdings with their USDT
+// value and the total. Futures balances are valued at the spot prices; when
+// those are nil, spot is left out and only USDT balances are valued.
+func formatPortfolio(balances []exchange.Balance, holdings []mexc.SpotHolding, prices map[string]float64) string {
+	var b strings.Builder
+	var total float64
+	unpriced := false
+	b.WriteString("Futures:\n")
+	if len(balances) == 0 {
+		b.WriteString("  no balances\n")
+	}
+	for _, bal := range balances {
+		price := mexc.SpotPrice(prices, exchange.BareSymbol(bal.Currency))
+		fmt.Fprintf(&b, "  %s: equity %.4f, available %.4f", bal.Currency, bal.Equity, bal.Available)
+		if price == 0 {
+			unpriced = unpriced || bal.Equity != 0
+		} else if price != 1 {
+			fmt.Fprintf(&b, " (%.2f USDT)", bal.Equity*price)
+		}
+		total += bal.Equity * price
+		b.WriteString("\n")
+	}
+	if prices != nil {
+		b.WriteString("Spot:\n")
+		if len(holdings) == 0 {
+			b.WriteString("  no holdings\n")
+		}
+	}
+	for _, h := range holdings {
+		fmt.Fprintf(&b, "  %s: %g", h.Asset, h.Total())
+		if h.Locked != 0 {
+			fmt.Fprintf(&b, " (%g in orders)", h.Locked)
+		}
+		if h.Price == 0 {
+			unpriced = true
+			b.WriteString(", no USDT price\n")
+			continue
+		}
+		fmt.Fprintf(&b, " = %.2f USDT\n", h.Value())
+		total += h.Value()
+	}
+	fmt.Fprintf(&b, "Total: %.2f USDT", total)
+	if unpriced {
+		b.WriteString(" (excluding assets without a USDT price)")
+	}
+	return b.String()
+}
+
+// alertHistoryView is a page of the alert history.
+type alertHistoryView struct {
+	Alerts []storage.AlertEvent `json:"alerts"`
+	Page   int                  `json:"page"`
+	Pages  int                  `json:"pages"`
+	Total  int                  `json:"total"`
+}
+
+// loadAlertHistory reads page (from 1) of the alert history, size alerts a
+// page.
+func loadAlertHistory(history *storage.DB, page, size int) (alertHistoryView, error) {
+	events, total, err := history.AlertHistory((page-1)*size, size)
+	if err != nil {
+		return alertHistoryView{}, fmt.Errorf("reading alerts: %w", err)
+	}
+	if events == nil {
+		events = []storage.AlertEvent{}
+	}
+	return alertHistoryView{Alerts: events, Page: page, Pages: (total + size - 1) / size, Total: total}, nil
+}
+
+// formatAlertHistory lists a page of alerts with their resolution, as of
+// now.
+func formatAlertHistory(view alertHistoryView, now time.Time) string {
+	if view.Total == 0 {
+		return "No alerts recorded yet."
+	}
+	if len(view.Alerts) == 0 {
+		return fmt.Sprintf("There are only %d pages of alerts.", view.Pages)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Alerts, page %d of %d (%d in all):\n", view.Page, view.Pages, view.Total)
+	for _, e := range view.Alerts {
+		fmt.Fprintf(&b, "%s %s %s", e.Time.Format(time.DateTime), e.Symbol, e.Kind)
+		if status := alertStatus(e, now); status != "" {
+			b.WriteString(": " + status)
+		}
+		b.WriteString("\n")
+	}
+	if view.Page < view.Pages {
+		fmt.Fprintf(&b, "Older: /alerts history %d", view.Page+1)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// alertStatus words whether e resolved. It is empty for events that don't
+// resolve, such as fills.
+func alertStatus(e storage.AlertEvent, now time.Time) string {
+	switch {
+	case e.Active():
+		return "still active after " + formatAlertAge(now.Sub(e.Time))
+	case e.ResolvedAt == nil:
+		return ""
+	case e.Resolution == storage.ResolutionClosed:
+		return "position closed after " + formatAlertAge(e.ResolvedAfter())
+	case e.Resolution == storage.ResolutionChanged:
+		return "position changed after " + formatAlertAge(e.ResolvedAfter())
+	}
+	return "resolved after " + formatAlertAge(e.ResolvedAfter())
+}
+
+// formatAlertAge is formatHeldFor down to the second, as alerts often
+// resolve within a minute.
+func formatAlertAge(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	return formatHeldFor(d)
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/internal/config"
+	"github.com/killabayte/golang-telegram-bot/internal/storage"
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+	"github.com/killabayte/golang-telegram-bot/pkg/telegram"
+)
+
+const (
+	// dashboardCookie holds the session token of a logged in browser.
+	dashboardCookie = "dashboard_session"
+	// loginMaxAge is how old a Login Widget callback may be; the widget
+	// redirects straight after the user confirms.
+	loginMaxAge = 5 * time.Minute
+	// dashboardWindow is how far back charts and the alert list go unless
+	// the request says otherwise.
+	dashboardWindow = 24 * time.Hour
+	// maxDashboardAlerts caps the alerts returned by one request.
+	maxDashboardAlerts = 1000
+	// eventKeepAlive is how often an idle event stream gets a comment, so
+	// proxies don't time it out.
+	eventKeepAlive = 30 * time.Second
+)
+
+//go:embed web
+var webAssets embed.FS
+
+var loginPage = template.Must(template.ParseFS(webAssets, "web/login.html"))
+
+// dashboardEvent is a monitor event as pushed to dashboards.
+type dashboardEvent struct {
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"kind"`
+	Symbol string    `json:"symbol"`
+	Text   string    `json:"text"`
+}
+
+// positionsView is the response of /api/positions.
+type positionsView struct {
+	Updated   time.Time    `json:"updated"`
+	Positions []comparison `json:"positions"`
+}
+
+// dashboard serves the web dashboard and the JSON API behind it. Every page
+// and endpoint but the login requires a session, which is only handed out
+// to the configured Telegram chat.
+type dashboard struct {
+	cfg      *config.Profile
+	ex       exchange.Exchange
+	history  *storage.DB // nil without a history database
+	notifier *telegram.Client
+
+	mu          sync.Mutex
+	sessions    map[string]time.Time // token to expiry
+	latest      *positionsView       // from the last watch poll
+	subscribers map[chan dashboardEvent]struct{}
+}
+
+func newDashboard(s *session, history *storage.DB) *dashboard {
+	return &dashboard{
+		cfg:         s.cfg,
+		ex:          s.ex,
+		history:     history,
+		notifier:    s.notifier,
+		sessions:    make(map[string]time.Time),
+		subscribers: make(map[chan dashboardEvent]struct{}),
+	}
+}
+
+// serve listens on the configured address until ctx is canceled.
+func (d *dashboard) serve(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              d.cfg.Dashboard.Listen,
+		Handler:           d.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// Requests, event streams included, end with the daemon.
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	slog.Info("serving dashboard", "addr", server.Addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (d *dashboard) handler() http.Handler {
+	static, err := fs.Sub(webAssets, "web/static")
+	if err != nil {
+		panic(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /login", d.loginPage)
+	mux.HandleFunc("GET /auth", d.auth)
+	mux.HandleFunc("POST /logout", d.logout)
+	mux.Handle("GET /api/positions", d.authed(d.positions))
+	mux.Handle("GET /api/prices", d.authed(d.prices))
+	mux.Handle("GET /api/alerts", d.authed(d.alerts))
+	mux.Handle("GET /api/alerts/history", d.authed(d.alertHistory))
+	mux.Handle("GET /api/events", d.authed(d.events))
+	files := http.FileServer(http.FS(static))
+	// The login page needs the stylesheet before there is a session.
+	mux.Handle("GET /style.css", files)
+	mux.Handle("GET /", d.authed(files.ServeHTTP))
+	return mux
+}
+
+// authed lets requests with a live session through to next. Others are sent
+// to the login page, or get a 401 on the API.
+func (d *dashboard) authed(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.loggedIn(r) {
+			next(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			writeAPIError(w, http.StatusUnauthorized, errors.New("not logged in"))
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	})
+}
+
+func (d *dashboard) loggedIn(r *http.Request) bool {
+	cookie, err := r.Cookie(dashboardCookie)
+	if err != nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	expires, ok := d.sessions[cookie.Value]
+	if ok && time.Now().After(expires) {
+		delete(d.sessions, cookie.Value)
+		return false
+	}
+	return ok
+}
+
+func (d *dashboard) loginPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	loginPage.Execute(w, struct{ Bot string }{d.cfg.Dashboard.BotUsername})
+}
+
+// auth is the Login Widget's callback. A genuine, recent login as the
+// configured chat starts a session.
+func (d *dashboard) auth(w http.ResponseWriter, r *http.Request) {
+	user, err := d.notifier.VerifyLogin(r.URL.Query(), loginMaxAge)
+	if err != nil {
+		slog.Warn("dashboard login rejected", errAttrs(err, "user", r.URL.Query().Get("username"))...)
+		http.Error(w, "Login failed: "+err.Error(), http.StatusForbidden)
+		return
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		http.Error(w, "Login failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	value := base64.RawURLEncoding.EncodeToString(token)
+	expires := d.startSession(value, time.Now())
+
+	slog.Info("dashboard login", "user", user.Username, "id", user.ID)
+	http.SetCookie(w, &http.Cookie{
+		Name:     dashboardCookie,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// startSession stores a session token logged in at now and returns when it
+// expires. Expired sessions are swept first: only the ones used again are
+// removed by loggedIn, so abandoned logins would otherwise pile up.
+func (d *dashboard) startSession(token string, now time.Time) time.Time {
+	expires := now.Add(d.cfg.Dashboard.SessionTTL)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for t, e := range d.sessions {
+		if now.After(e) {
+			delete(d.sessions, t)
+		}
+	}
+	d.sessions[token] = expires
+	return expires
+}
+
+func (d *dashboard) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(dashboardCookie); err == nil {
+		d.mu.Lock()
+		delete(d.sessions, cookie.Value)
+		d.mu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{Name: dashboardCookie, Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// positions returns the positions of the last watch poll, or polls now when
+// the daemon isn't watching or is taking prices from the stream.
+func (d *dashboard) positions(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	view := d.latest
+	d.mu.Unlock()
+	if view == nil {
+		res := monitor.NewForExchange(d.ex, monitorOptions(d.cfg)).Poll(r.Context(), nil)
+		view = &positionsView{Updated: time.Now(), Positions: comparisons(res)}
+	}
+	writeAPI(w, view)
+}
+
+// prices returns the stored fair prices of the symbol parameter for the
+// last window, e.g. "6h".
+func (d *dashboard) prices(w http.ResponseWriter, r *http.Request) {
+	if d.history == nil {
+		writeAPIError(w, http.StatusNotFound, errors.New("no history database is configured"))
+		return
+	}
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		writeAPIError(w, http.StatusBadRequest, errors.New("symbol is required"))
+		return
+	}
+	window, err := windowParam(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	samples, err := d.history.Prices(symbol, time.Now().Add(-window))
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if samples == nil {
+		samples = []storage.PriceSample{}
+	}
+	writeAPI(w, samples)
+}
+
+// alerts returns the alerts sent in the last window, newest first, up to
+// the limit parameter.
+func (d *dashboard) alerts(w http.ResponseWriter, r *http.Request) {
+	if d.history == nil {
+		writeAPIError(w, http.StatusNotFound, errors.New("no history database is configured"))
+		return
+	}
+	window, err := windowParam(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxDashboardAlerts {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxDashboardAlerts))
+			return
+		}
+	}
+	events, err := d.history.Alerts(time.Now().Add(-window), limit)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if events == nil {
+		events = []storage.AlertEvent{}
+	}
+	writeAPI(w, events)
+}
+
+// alertHistory returns a page of the whole alert history, newest first:
+// page counts from 1 and limit alerts make a page.
+func (d *dashboard) alertHistory(w http.ResponseWriter, r *http.Request) {
+	if d.history == nil {
+		writeAPIError(w, http.StatusNotFound, errors.New("no history database is configured"))
+		return
+	}
+	page, limit := 1, 100
+	var err error
+	if v := r.URL.Query().Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			writeAPIError(w, http.StatusBadRequest, errors.New("page must be a positive number"))
+			return
+		}
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxDashboardAlerts {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxDashboardAlerts))
+			return
+		}
+	}
+	view, err := loadAlertHistory(d.history, page, limit)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeAPI(w, view)
+}
+
+// events streams monitor events as server-sent events.
+func (d *dashboard) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+
+	ch := make(chan dashboardEvent, 16)
+	d.mu.Lock()
+	d.subscribers[ch] = struct{}{}
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.subscribers, ch)
+		d.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(eventKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case ev := <-ch:
+			data, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		flusher.Flush()
+	}
+}
+
+// publish pushes ev to every open event stream, skipping streams that have
+// fallen behind.
+func (d *dashboard) publish(ev monitor.Event) {
+	line, _ := eventLine(ev)
+	if line == "" {
+		return
+	}
+	de := dashboardEvent{Time: time.Now(), Kind: ev.Kind.String(), Symbol: eventSymbol(ev), Text: line}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for ch := range d.subscribers {
+		select {
+		case ch <- de:
+		default:
+		}
+	}
+}
+
+// setCycle keeps the positions of a watch poll for /api/positions.
+func (d *dashboard) setCycle(res monitor.CycleResult) {
+	view := &positionsView{Updated: time.Now(), Positions: comparisons(res)}
+	d.mu.Lock()
+	d.latest = view
+	d.mu.Unlock()
+}
+
+// dashboardHandler pushes every event to the dashboard before passing it on.
+type dashboardHandler struct {
+	monitor.Handler
+	dashboard *dashboard
+}
+
+func (h *dashboardHandler) HandleEvent(ev monitor.Event) {
+	h.dashboard.publish(ev)
+	h.Handler.HandleEvent(ev)
+}
+
+// windowParam parses the window parameter, a duration such as "6h".
+func windowParam(r *http.Request) (time.Duration, error) {
+	v := r.URL.Query().Get("window")
+	if v == "" {
+		return dashboardWindow, nil
+	}
+	window, err := time.ParseDuration(v)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("window %q is not a positive duration", v)
+	}
+	return window, nil
+}
+
+func writeAPI(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/internal/config"
+)
+
+func TestDashboardSessionSweep(t *testing.T) {
+	cfg := &config.Profile{}
+	cfg.Dashboard.SessionTTL = time.Hour
+	d := &dashboard{cfg: cfg, sessions: make(map[string]time.Time)}
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	logins := []struct {
+		token string
+		at    time.Duration // since start
+		live  []string      // sessions left afterwards
+	}{
+		{"a", 0, []string{"a"}},
+		{"b", 30 * time.Minute, []string{"a", "b"}},
+		{"c", time.Hour, []string{"a", "b", "c"}}, // a expires at, not before, an hour
+		{"d", 61 * time.Minute, []string{"b", "c", "d"}},
+		{"e", 5 * time.Hour, []string{"e"}},
+	}
+	for _, l := range logins {
+		if got := d.startSession(l.token, start.Add(l.at)); !got.Equal(start.Add(l.at + time.Hour)) {
+			t.Errorf("session %s expires at %v, want an hour after login", l.token, got)
+		}
+		var live []string
+		for _, token := range []string{"a", "b", "c", "d", "e"} {
+			if _, ok := d.sessions[token]; ok {
+				live = append(live, token)
+			}
+		}
+		if fmt.Sprint(live) != fmt.Sprint(l.live) {
+			t.Errorf("after login %s: sessions %v, want %v", l.token, live, l.live)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultEgressCheckURL = "https://api.ipify.org"
+	egressCheckTimeout    = 10 * time.Second
+)
+
+// publicEgressIP asks checkURL for the address our requests appear to come
+// from. The endpoint is expected to return the bare IP as plain text.
+func publicEgressIP(ctx context.Context, client *http.Client, checkURL string) (net.IP, error) {
+	ctx, cancel := context.WithTimeout(ctx, egressCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", checkURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 256))
+	if err != nil {
+		return nil, err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP in response: %q", body)
+	}
+	return ip, nil
+}
+
+// checkEgressIP warns when the public egress IP is not in the allowlist,
+// which usually means an IP-restricted API key is about to start failing
+// signature checks. An empty allowlist disables the check.
+func checkEgressIP(ctx context.Context, client *http.Client, checkURL string, allowlist []string) {
+	if len(allowlist) == 0 {
+		return
+	}
+
+	ip, err := publicEgressIP(ctx, client, checkURL)
+	if err != nil {
+		slog.Warn("could not determine public egress IP", errAttrs(err, "url", checkURL)...)
+		return
+	}
+
+	for _, entry := range allowlist {
+		if expected := net.ParseIP(strings.TrimSpace(entry)); expected != nil && expected.Equal(ip) {
+			return
+		}
+	}
+	slog.Warn("public egress IP is not in the expected IPs; IP-restricted API keys will be rejected", "ip", ip.String(), "expected", strings.Join(allowlist, ", "))
+}
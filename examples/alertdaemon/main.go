@@ -0,0 +1,62 @@
+// Command alertdaemon watches open positions and prints divergence, imbalance
+// and stale position alerts to the console. It shows the monitor and alert
+// packages without Telegram or a config file.
+//
+//	MEXC_ACCESS_KEY=... MEXC_SECRET_KEY=... go run ./examples/alertdaemon
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/alert"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+)
+
+// console prints every event it receives.
+type console struct{}
+
+func (console) HandleEvent(ev monitor.Event) {
+	pos := ev.Position
+	switch ev.Kind {
+	case monitor.Updated:
+		diff := (ev.FairPrice - pos.HoldAvgPrice) / pos.HoldAvgPrice * 100
+		fmt.Printf("%s %s: fair price %f vs entry %f (%+.2f%%)\n", pos.Symbol, pos.Side(), ev.FairPrice, pos.HoldAvgPrice, diff)
+	case monitor.ImbalanceAlert:
+		fmt.Printf("%s: order book imbalance %+.2f\n", pos.Symbol, ev.Imbalance)
+	case monitor.Stale:
+		fmt.Printf("%s %s: open for %s\n", pos.Symbol, pos.Side(), ev.HeldFor.Round(time.Minute))
+	case monitor.Closed:
+		fmt.Printf("%s %s: closed\n", pos.Symbol, pos.Side())
+	}
+}
+
+func (console) HandleError(symbol string, err error) {
+	fmt.Fprintf(os.Stderr, "error %s: %v\n", symbol, err)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	api := mexc.NewClient(os.Getenv("MEXC_ACCESS_KEY"), []byte(os.Getenv("MEXC_SECRET_KEY")), mexc.DefaultBaseURL)
+	mon := monitor.New(api, monitor.Options{
+		Interval: 30 * time.Second,
+		// Only moves of 2% or more, at most every 15 minutes per position,
+		// re-armed once the move shrinks below 1.6%.
+		Threshold: func(string) monitor.Threshold { return monitor.Threshold{Percent: 2} },
+		Alerts: alert.NewManager(alert.Policy{
+			Cooldown:   15 * time.Minute,
+			Repeat:     time.Hour,
+			Hysteresis: 0.2,
+		}),
+		ImbalanceLevels:    20,
+		ImbalanceThreshold: 0.6,
+		StaleAfter:         72 * time.Hour,
+	})
+	mon.Run(ctx, console{})
+}
@@ -0,0 +1,47 @@
+// Command pricecheck prints the fair price, contract size and order book
+// imbalance of the contracts named on the command line, using only public
+// MEXC endpoints.
+//
+//	go run ./examples/pricecheck BTC_USDT ETH_USDT
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: pricecheck SYMBOL...")
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Public endpoints don't need an API key.
+	api := mexc.NewClient("", nil, mexc.DefaultBaseURL)
+
+	for _, symbol := range os.Args[1:] {
+		price, err := api.FairPrice(ctx, symbol)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", symbol, err)
+			continue
+		}
+		detail, err := api.ContractDetail(ctx, symbol)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", symbol, err)
+			continue
+		}
+		book, err := api.Depth(ctx, symbol, 10)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", symbol, err)
+			continue
+		}
+		fmt.Printf("%s fair price %f, contract size %g, top-10 imbalance %+.2f\n", symbol, price, detail.ContractSize, book.Imbalance(10))
+	}
+}
@@ -0,0 +1,91 @@
+// Command strategy is a skeleton for a signal built on streamed fair prices.
+// It keeps a fast and a slow exponential moving average per symbol and
+// reports when they cross; replace signal with your own logic.
+//
+// Signals go to Telegram when TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID are
+// set, and to the console otherwise. No orders are placed.
+//
+//	go run ./examples/strategy BTC_USDT ETH_USDT
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/telegram"
+)
+
+// Smoothing factors for the moving averages; streamed fair prices arrive
+// about once a second.
+const (
+	fastAlpha = 2.0 / (30 + 1)
+	slowAlpha = 2.0 / (300 + 1)
+)
+
+// averages is the strategy state for one symbol.
+type averages struct {
+	fast, slow float64
+	above      bool // fast was above slow after the last update
+	primed     bool
+}
+
+// signal updates a with price and returns a non-empty message when the fast
+// average crosses the slow one.
+func (a *averages) signal(symbol string, price float64) string {
+	if !a.primed {
+		a.fast, a.slow, a.primed = price, price, true
+		return ""
+	}
+	a.fast += fastAlpha * (price - a.fast)
+	a.slow += slowAlpha * (price - a.slow)
+
+	above := a.fast > a.slow
+	if above == a.above {
+		return ""
+	}
+	a.above = above
+	if above {
+		return fmt.Sprintf("%s: fast average crossed above slow at %f", symbol, price)
+	}
+	return fmt.Sprintf("%s: fast average crossed below slow at %f", symbol, price)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: strategy SYMBOL...")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	notify := func(line string) { fmt.Println(line) }
+	if token, chatID := os.Getenv("TELEGRAM_BOT_TOKEN"), os.Getenv("TELEGRAM_CHAT_ID"); token != "" && chatID != "" {
+		client := telegram.NewClient(token, chatID)
+		notify = func(line string) {
+			if err := client.SendMessage(line); err != nil {
+				fmt.Fprintln(os.Stderr, "telegram:", err)
+			}
+		}
+	}
+
+	stream := mexc.NewPriceStream(mexc.DefaultStreamURL)
+	stream.OnError = func(err error) { fmt.Fprintln(os.Stderr, "stream:", err) }
+	stream.SetSymbols(os.Args[1:])
+	go stream.Run(ctx)
+
+	state := make(map[string]*averages)
+	for update := range stream.Updates() {
+		a, ok := state[update.Symbol]
+		if !ok {
+			a = &averages{}
+			state[update.Symbol] = a
+		}
+		if line := a.signal(update.Symbol, update.Price); line != "" {
+			notify(line)
+		}
+	}
+}
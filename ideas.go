@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/internal/ideas"
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+	"github.com/killabayte/golang-telegram-bot/pkg/telegram"
+)
+
+// registerIdeaCommands adds /idea and /ideas backed by store.
+func registerIdeaCommands(router *telegram.Router, api exchange.Exchange, store *ideas.Store) {
+	router.Handle("idea", "SYMBOL long|short TARGET INVALIDATION [thesis]", "Log a trade idea and track whether target or invalidation is hit first", func(ctx context.Context, args []string) (string, error) {
+		const usage = "Usage: /idea SYMBOL long|short TARGET INVALIDATION [thesis], e.g. /idea BTC_USDT long 70000 58000 breakout retest"
+		if len(args) < 4 {
+			return usage, nil
+		}
+		target, err1 := strconv.ParseFloat(args[2], 64)
+		invalidation, err2 := strconv.ParseFloat(args[3], 64)
+		if err1 != nil || err2 != nil {
+			return usage, nil
+		}
+
+		idea := ideas.Idea{
+			Symbol:       strings.ToUpper(args[0]),
+			Direction:    strings.ToLower(args[1]),
+			Target:       target,
+			Invalidation: invalidation,
+			Thesis:       strings.Join(args[4:], " "),
+			CreatedAt:    time.Now(),
+		}
+		entry, err := api.FairPrice(ctx, idea.Symbol)
+		if err != nil {
+			return "", err
+		}
+		if entry == 0 {
+			return fmt.Sprintf("No fair price available for %s.", idea.Symbol), nil
+		}
+		idea.EntryPrice = entry
+
+		saved, err := store.Add(idea)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Idea #%d logged: %s %s from %f, target %f, invalidation %f", saved.ID, saved.Symbol, saved.Direction, saved.EntryPrice, saved.Target, saved.Invalidation), nil
+	})
+
+	router.Handle("ideas", "", "List open ideas and the idea hit rate", func(ctx context.Context, args []string) (string, error) {
+		var b strings.Builder
+		for _, idea := range store.List() {
+			if idea.Outcome != ideas.Open {
+				continue
+			}
+			fmt.Fprintf(&b, "#%d %s %s from %f: target %f, invalidation %f (%s ago)\n", idea.ID, idea.Symbol, idea.Direction, idea.EntryPrice, idea.Target, idea.Invalidation, formatHeldFor(time.Since(idea.CreatedAt)))
+		}
+		if b.Len() == 0 {
+			b.WriteString("No open ideas.\n")
+		}
+
+		st := store.Stats()
+		fmt.Fprintf(&b, "Resolved: %d hit target, %d invalidated (hit rate %.0f%%)", st.HitTarget, st.Invalidated, st.HitRate()*100)
+		return b.String(), nil
+	})
+}
+
+// trackIdeas checks open ideas against fair prices every interval and
+// reports the ones that reach their target or invalidation.
+func trackIdeas(ctx context.Context, api exchange.Exchange, store *ideas.Store, interval time.Duration, out *reporter) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, symbol := range store.OpenSymbols() {
+			price, err := api.FairPrice(ctx, symbol)
+			if err != nil {
+				out.HandleError(symbol, fmt.Errorf("checking ideas: %w", err))
+				continue
+			}
+			resolved, err := store.Update(symbol, price, time.Now())
+			if err != nil {
+				out.HandleError(symbol, err)
+			}
+			for _, idea := range resolved {
+				out.send(ideaResolvedLine(idea), "")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func ideaResolvedLine(idea ideas.Idea) string {
+	verb := "hit its target"
+	if idea.Outcome == ideas.Invalidated {
+		verb = "was invalidated"
+	}
+	return fmt.Sprintf("Idea #%d (%s %s from %f) %s at %f after %s", idea.ID, idea.Symbol, idea.Direction, idea.EntryPrice, verb, idea.ExitPrice, formatHeldFor(idea.ResolvedAt.Sub(idea.CreatedAt)))
+}
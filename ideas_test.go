@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/internal/ideas"
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+)
+
+// priceExchange serves fair prices from a map; other methods aren't used.
+type priceExchange struct {
+	exchange.Exchange
+	prices map[string]float64
+}
+
+func (e priceExchange) FairPrice(ctx context.Context, symbol string) (float64, error) {
+	price, ok := e.prices[symbol]
+	if !ok {
+		return 0, fmt.Errorf("no price for %s", symbol)
+	}
+	return price, nil
+}
+
+func TestTrackIdeas(t *testing.T) {
+	store, err := ideas.OpenStore(filepath.Join(t.TempDir(), "ideas.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().Add(-26 * time.Hour)
+	for _, idea := range []ideas.Idea{
+		{Symbol: "BTC_USDT", Direction: "long", EntryPrice: 60000, Target: 70000, Invalidation: 58000, CreatedAt: created},
+		{Symbol: "ETH_USDT", Direction: "short", EntryPrice: 3000, Target: 2500, Invalidation: 3200, CreatedAt: created},
+		{Symbol: "SOL_USDT", Direction: "long", EntryPrice: 150, Target: 200, Invalidation: 120, CreatedAt: created},
+		{Symbol: "XRP_USDT", Direction: "long", EntryPrice: 0.5, Target: 0.6, Invalidation: 0.4, CreatedAt: created},
+	} {
+		if _, err := store.Add(idea); err != nil {
+			t.Fatal(err)
+		}
+	}
+	api := priceExchange{prices: map[string]float64{"BTC_USDT": 70500, "ETH_USDT": 3250, "SOL_USDT": 160}}
+	r, f := newTestReporter(t, nil)
+
+	// A canceled context stops trackIdeas after its first check.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	trackIdeas(ctx, api, store, time.Hour, r)
+	r.flush(5 * time.Second)
+
+	want := []string{
+		"Idea #1 (BTC_USDT long from 60000.000000) hit its target at 70500.000000 after 1d 2h",
+		"Idea #2 (ETH_USDT short from 3000.000000) was invalidated at 3250.000000 after 1d 2h",
+	}
+	if len(f.messages) != len(want) {
+		t.Fatalf("sent %+v, want %d messages", f.messages, len(want))
+	}
+	for i, msg := range f.messages {
+		if msg.Text != want[i] {
+			t.Errorf("message %d = %q, want %q", i, msg.Text, want[i])
+		}
+	}
+	// SOL is between its levels and XRP had no price, so both stay open.
+	if st := store.Stats(); st != (ideas.Stats{Open: 2, HitTarget: 1, Invalidated: 1}) {
+		t.Errorf("Stats = %+v", st)
+	}
+}
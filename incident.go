@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultIncidentWindow is the incident window used when only
+// incidents.alerts is configured.
+const defaultIncidentWindow = 2 * time.Minute
+
+// incidentTextLimit keeps an incident's head message under Telegram's 4096
+// character limit, with room for the summary.
+const incidentTextLimit = 3500
+
+// incidents groups bursts of Telegram alerts: once alerts fire within window
+// of each other, they are collected into one incident whose head message is
+// edited as alerts come and go, instead of each being sent on its own. Only
+// reporter.deliver uses it.
+type incidents struct {
+	alerts int
+	window time.Duration
+
+	// recent are the alerts sent on their own within window, oldest first.
+	recent []incidentAlert
+	// current is the latest incident. It takes new alerts until window
+	// passes without one, and resolutions until all of its alerts resolved.
+	current *incident
+}
+
+// newIncidents returns the grouper for an incidents config, or nil when
+// grouping is disabled.
+func newIncidents(alerts int, window time.Duration) *incidents {
+	if alerts <= 0 {
+		return nil
+	}
+	if window <= 0 {
+		window = defaultIncidentWindow
+	}
+	return &incidents{alerts: alerts, window: window}
+}
+
+// incidentAlert is an alert as listed in an incident.
+type incidentAlert struct {
+	at       time.Time
+	text     string
+	alertKey string
+	kind     string
+	resolved bool
+}
+
+// incident is a burst of alerts reported in one head message.
+type incident struct {
+	head   int64 // Telegram message ID, zero until sent
+	alerts []incidentAlert
+	// last is when the latest alert joined.
+	last time.Time
+	// dirty is set when the head message is out of date.
+	dirty bool
+}
+
+// add records an alert sent at now and reports whether it belongs to an
+// incident, which it opens if it completes a burst. An alert that belongs to
+// an incident must not be sent on its own.
+func (g *incidents) add(msg outgoing, now time.Time) bool {
+	a := incidentAlert{at: now, text: msg.text, alertKey: msg.alertKey, kind: msg.kind}
+	if inc := g.current; inc != nil && inc.head != 0 && now.Sub(inc.last) <= g.window {
+		inc.alerts = append(inc.alerts, a)
+		inc.last, inc.dirty = now, true
+		return true
+	}
+
+	expired := 0
+	for expired < len(g.recent) && now.Sub(g.recent[expired].at) > g.window {
+		expired++
+	}
+	g.recent = append(g.recent[expired:], a)
+	if len(g.recent) < g.alerts {
+		return false
+	}
+	g.current = &incident{alerts: g.recent, last: now, dirty: true}
+	g.recent = nil
+	return true
+}
+
+// resolve marks the alerts under alertKey in the current incident resolved
+// and reports whether there were any. over is set when that leaves no alert
+// of the incident active; the incident is then done.
+func (g *incidents) resolve(alertKey string) (found, over bool) {
+	inc := g.current
+	if inc == nil || inc.head == 0 {
+		return false, false
+	}
+	active := 0
+	for i := range inc.alerts {
+		a := &inc.alerts[i]
+		if a.alertKey == alertKey && !a.resolved {
+			a.resolved, found = true, true
+		}
+		if !a.resolved {
+			active++
+		}
+	}
+	if !found {
+		return false, false
+	}
+	inc.dirty = true
+	return true, active == 0
+}
+
+// text renders the head message in Telegram HTML: a summary of the alerts by
+// kind, then each alert, newest first, in an expandable quote.
+func (inc *incident) text() string {
+	counts := make(map[string]int)
+	resolved := 0
+	for _, a := range inc.alerts {
+		counts[a.kind]++
+		if a.resolved {
+			resolved++
+		}
+	}
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		if counts[kinds[i]] != counts[kinds[j]] {
+			return counts[kinds[i]] > counts[kinds[j]]
+		}
+		return kinds[i] < kinds[j]
+	})
+	byKind := make([]string, len(kinds))
+	for i, kind := range kinds {
+		byKind[i] = fmt.Sprintf("%d %s", counts[kind], strings.ReplaceAll(kind, "_", " "))
+	}
+
+	var b strings.Builder
+	started := inc.alerts[0].at
+	fmt.Fprintf(&b, "<b>Incident: %d alerts since %s", len(inc.alerts), started.Format("15:04:05"))
+	if resolved == len(inc.alerts) {
+		b.WriteString(", all resolved")
+	} else if resolved > 0 {
+		fmt.Fprintf(&b, ", %d resolved", resolved)
+	}
+	fmt.Fprintf(&b, "</b>\n%s\n<blockquote expandable>", strings.Join(byKind, ", "))
+	length := 0
+	for i := len(inc.alerts) - 1; i >= 0; i-- {
+		a := inc.alerts[i]
+		line := a.at.Format("15:04:05") + " " + a.text
+		if a.resolved {
+			line += " (resolved)"
+		}
+		if length += len(line); length > incidentTextLimit {
+			fmt.Fprintf(&b, "and %d earlier\n", i+1)
+			break
+		}
+		b.WriteString(html.EscapeString(line) + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n") + "</blockquote>"
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+var incidentStart = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func at(seconds int) time.Time { return incidentStart.Add(time.Duration(seconds) * time.Second) }
+
+func alertMsg(key string) outgoing {
+	return outgoing{text: key + " alert", alertKey: "divergence:" + key, kind: "updated"}
+}
+
+func TestIncidentsAdd(t *testing.T) {
+	// Each step adds an alert at a time in seconds, and wants whether it
+	// joined an incident. An incident is sent once grouped, which the test
+	// does by giving it a head.
+	type step struct {
+		key     string
+		at      int
+		grouped bool
+	}
+	tests := []struct {
+		name   string
+		alerts int
+		window time.Duration
+		steps  []step
+		// incident lists the keys of the current incident's alerts.
+		incident []string
+	}{
+		{"below the burst size", 3, time.Minute, []step{
+			{"A", 0, false}, {"B", 10, false},
+		}, nil},
+		{"burst opens an incident", 3, time.Minute, []step{
+			{"A", 0, false}, {"B", 10, false}, {"C", 20, true},
+		}, []string{"A", "B", "C"}},
+		{"alerts outside the window don't count", 3, time.Minute, []step{
+			{"A", 0, false}, {"B", 10, false}, {"C", 62, false}, {"D", 65, true},
+		}, []string{"B", "C", "D"}},
+		{"window measured from each alert", 2, time.Minute, []step{
+			{"A", 0, false}, {"B", 61, false}, {"C", 122, false},
+		}, nil},
+		{"incident takes later alerts", 2, time.Minute, []step{
+			{"A", 0, false}, {"B", 10, true}, {"C", 60, true}, {"D", 115, true},
+		}, []string{"A", "B", "C", "D"}},
+		{"incident ends after a quiet window", 2, time.Minute, []step{
+			{"A", 0, false}, {"B", 10, true}, {"C", 71, false}, {"D", 80, true},
+		}, []string{"C", "D"}},
+		{"burst of one groups everything", 1, time.Minute, []step{
+			{"A", 0, true}, {"B", 30, true},
+		}, []string{"A", "B"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newIncidents(tt.alerts, tt.window)
+			for i, s := range tt.steps {
+				if got := g.add(alertMsg(s.key), at(s.at)); got != s.grouped {
+					t.Fatalf("step %d: add(%s at %ds) = %v, want %v", i+1, s.key, s.at, got, s.grouped)
+				}
+				if g.current != nil && g.current.head == 0 {
+					g.current.head = int64(100 + i)
+				}
+			}
+
+			var keys []string
+			if g.current != nil {
+				for _, a := range g.current.alerts {
+					keys = append(keys, strings.TrimPrefix(a.alertKey, "divergence:"))
+				}
+			}
+			if fmt.Sprint(keys) != fmt.Sprint(tt.incident) {
+				t.Errorf("incident has %v, want %v", keys, tt.incident)
+			}
+		})
+	}
+}
+
+func TestNewIncidents(t *testing.T) {
+	if g := newIncidents(0, time.Minute); g != nil {
+		t.Error("grouping enabled without a burst size")
+	}
+	if g := newIncidents(3, 0); g == nil || g.window != defaultIncidentWindow {
+		t.Errorf("newIncidents(3, 0) = %+v, want the default window", g)
+	}
+}
+
+func TestIncidentsResolve(t *testing.T) {
+	newIncident := func() *incidents {
+		g := newIncidents(3, time.Minute)
+		g.add(alertMsg("A"), at(0))
+		g.add(alertMsg("B"), at(5))
+		g.add(alertMsg("A"), at(10)) // A repeated
+		g.current.head = 100
+		g.current.dirty = false
+		return g
+	}
+
+	tests := []struct {
+		name    string
+		resolve []string
+		// found and over are the results of the last resolve.
+		found, over bool
+		resolved    []bool
+	}{
+		{"unknown key", []string{"C"}, false, false, []bool{false, false, false}},
+		{"grouped alert resolves all its entries", []string{"A"}, true, false, []bool{true, false, true}},
+		{"last alert ends the incident", []string{"A", "B"}, true, true, []bool{true, true, true}},
+		{"already resolved", []string{"A", "A"}, false, false, []bool{true, false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newIncident()
+			var found, over bool
+			for _, key := range tt.resolve {
+				found, over = g.resolve("divergence:" + key)
+			}
+			if found != tt.found || over != tt.over {
+				t.Errorf("resolve = %v, %v, want %v, %v", found, over, tt.found, tt.over)
+			}
+			for i, a := range g.current.alerts {
+				if a.resolved != tt.resolved[i] {
+					t.Errorf("alert %d resolved = %v, want %v", i, a.resolved, tt.resolved[i])
+				}
+			}
+			if g.current.dirty != (len(tt.resolve) > 0 && tt.resolve[0] != "C") {
+				t.Errorf("dirty = %v after resolving %v", g.current.dirty, tt.resolve)
+			}
+		})
+	}
+
+	t.Run("before the head is sent", func(t *testing.T) {
+		g := newIncidents(1, time.Minute)
+		g.add(alertMsg("A"), at(0))
+		if found, _ := g.resolve("divergence:A"); found {
+			t.Error("resolved an alert of an incident without a head message")
+		}
+	})
+}
+
+func TestIncidentText(t *testing.T) {
+	alert := func(seconds int, kind, text string, resolved bool) incidentAlert {
+		return incidentAlert{at: at(seconds), kind: kind, text: text, alertKey: text, resolved: resolved}
+	}
+
+	tests := []struct {
+		name   string
+		alerts []incidentAlert
+		want   string
+	}{
+		{"summary by kind, newest first", []incidentAlert{
+			alert(0, "updated", "BTC_USDT diverged", false),
+			alert(5, "data_missing", "ETH_USDT no price", false),
+			alert(9, "updated", "SOL_USDT diverged", false),
+		}, "<b>Incident: 3 alerts since 12:00:00</b>\n2 updated, 1 data missing\n<blockquote expandable>" +
+			"12:00:09 SOL_USDT diverged\n12:00:05 ETH_USDT no price\n12:00:00 BTC_USDT diverged</blockquote>"},
+		{"kinds with equal counts by name", []incidentAlert{
+			alert(0, "stale", "a", false),
+			alert(1, "hedge_drift", "b", false),
+		}, "<b>Incident: 2 alerts since 12:00:00</b>\n1 hedge drift, 1 stale\n<blockquote expandable>" +
+			"12:00:01 b\n12:00:00 a</blockquote>"},
+		{"some resolved", []incidentAlert{
+			alert(0, "updated", "a", true),
+			alert(1, "updated", "b", false),
+		}, "<b>Incident: 2 alerts since 12:00:00, 1 resolved</b>\n2 updated\n<blockquote expandable>" +
+			"12:00:01 b\n12:00:00 a (resolved)</blockquote>"},
+		{"all resolved", []incidentAlert{
+			alert(0, "updated", "a", true),
+			alert(1, "updated", "b", true),
+		}, "<b>Incident: 2 alerts since 12:00:00, all resolved</b>\n2 updated\n<blockquote expandable>" +
+			"12:00:01 b (resolved)\n12:00:00 a (resolved)</blockquote>"},
+		{"html escaped", []incidentAlert{
+			alert(0, "updated", `<b>A&B</b> "quoted"`, false),
+			alert(1, "updated", "x < y", false),
+		}, "<b>Incident: 2 alerts since 12:00:00</b>\n2 updated\n<blockquote expandable>" +
+			"12:00:01 x &lt; y\n12:00:00 &lt;b&gt;A&amp;B&lt;/b&gt; &#34;quoted&#34;</blockquote>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inc := &incident{alerts: tt.alerts}
+			if got := inc.text(); got != tt.want {
+				t.Errorf("text() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncidentTextTruncated(t *testing.T) {
+	// Each line is "12:00:00 " and 91 characters of text: 100 in all, so
+	// 35 fit the limit.
+	inc := &incident{}
+	for i := 0; i < 50; i++ {
+		inc.alerts = append(inc.alerts, incidentAlert{at: at(i), kind: "updated", text: fmt.Sprintf("alert %02d %s", i, strings.Repeat("x", 82))})
+	}
+	text := inc.text()
+
+	if !strings.Contains(text, "alert 49 ") || !strings.Contains(text, "alert 15 ") {
+		t.Error("newest alerts missing from the text")
+	}
+	if strings.Contains(text, "alert 14 ") {
+		t.Error("text lists more alerts than fit the limit")
+	}
+	if !strings.HasSuffix(text, "\nand 15 earlier</blockquote>") {
+		t.Errorf("text ends %q, want a count of the 15 alerts left out", text[len(text)-40:])
+	}
+	if len(text) > 4096 {
+		t.Errorf("text is %d characters, over Telegram's limit", len(text))
+	}
+
+	// Telegram counts the limit after parsing entities, so escaping
+	// doesn't change how many alerts fit.
+	inc = &incident{}
+	for i := 0; i < 50; i++ {
+		inc.alerts = append(inc.alerts, incidentAlert{at: at(i), kind: "updated", text: strings.Repeat("&", 91)})
+	}
+	if text := inc.text(); !strings.HasSuffix(text, "\nand 15 earlier</blockquote>") {
+		t.Errorf("escaped text ends %q, want a count of the 15 alerts left out", text[len(text)-40:])
+	}
+}
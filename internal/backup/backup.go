@@ -0,0 +1,173 @@
+// Package backup reads and writes passphrase-encrypted archives of the bot's
+// files, so its configuration and data can be moved to another machine.
+//
+// An archive is a gzipped tar, sealed with AES-256-GCM under a key derived
+// from the passphrase with scrypt:
+//
+//	magic | salt (16 bytes) | nonce (12 bytes) | ciphertext
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	magic    = "GTBBAK1\n"
+	saltSize = 16
+
+	// scrypt parameters recommended for interactive use.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keySize = 32
+)
+
+// maxArchiveSize guards against reading something that is clearly not one of
+// our archives into memory. Write enforces it too, so that every archive it
+// produces can be restored. It is a variable for tests.
+var maxArchiveSize = 64 << 20
+
+var (
+	// ErrDecrypt is returned when an archive can't be opened, which almost
+	// always means the passphrase is wrong.
+	ErrDecrypt = errors.New("backup: wrong passphrase or corrupted archive")
+	// ErrTooLarge is returned for archives over the size Read accepts.
+	ErrTooLarge = errors.New("backup: archive too large")
+)
+
+// File is one entry in an archive.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Write packs files into an archive encrypted with passphrase. Archives that
+// Read would refuse as too large are not written; Write returns ErrTooLarge
+// instead.
+func Write(w io.Writer, passphrase []byte, files []File) error {
+	if len(passphrase) == 0 {
+		return errors.New("backup: empty passphrase")
+	}
+
+	var plain bytes.Buffer
+	gz := gzip.NewWriter(&plain)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.Name, Mode: 0o600, Size: int64(len(f.Data)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("backup: adding %s: %w", f.Name, err)
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			return fmt.Errorf("backup: adding %s: %w", f.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	size := len(magic) + saltSize + len(nonce) + plain.Len() + aead.Overhead()
+	if size > maxArchiveSize {
+		return fmt.Errorf("%w: %d bytes compressed, restore reads at most %d", ErrTooLarge, size, maxArchiveSize)
+	}
+	out := make([]byte, 0, size)
+	out = append(out, magic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	out = aead.Seal(out, nonce, plain.Bytes(), []byte(magic))
+	_, err = w.Write(out)
+	return err
+}
+
+// Read decrypts an archive written by Write and returns its files.
+func Read(r io.Reader, passphrase []byte) ([]File, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxArchiveSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxArchiveSize {
+		return nil, ErrTooLarge
+	}
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, errors.New("backup: not a backup archive")
+	}
+	data = data[len(magic):]
+	if len(data) < saltSize {
+		return nil, ErrDecrypt
+	}
+	salt, data := data[:saltSize], data[saltSize:]
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, []byte(magic))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	var files []File
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("backup: %w", err)
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("backup: reading %s: %w", hdr.Name, err)
+		}
+		files = append(files, File{Name: hdr.Name, Data: body})
+	}
+	return files, nil
+}
+
+func newAEAD(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
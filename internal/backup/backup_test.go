@@ -0,0 +1,113 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+var testFiles = []File{
+	{Name: "config.yaml", Data: []byte("telegram:\n  chat_id: 42\n")},
+	{Name: "ideas.json", Data: nil},
+	{Name: "history.db", Data: bytes.Repeat([]byte{0, 1, 2, 0xff}, 4096)},
+}
+
+func writeArchive(t *testing.T, passphrase string, files []File) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, []byte(passphrase), files); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	archive := writeArchive(t, "correct horse", testFiles)
+	if bytes.Contains(archive, []byte("chat_id")) {
+		t.Error("archive contains plaintext")
+	}
+
+	files, err := Read(bytes.NewReader(archive), []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(testFiles) {
+		t.Fatalf("got %d files, want %d", len(files), len(testFiles))
+	}
+	for i, f := range files {
+		if f.Name != testFiles[i].Name || !bytes.Equal(f.Data, testFiles[i].Data) {
+			t.Errorf("files[%d] = %s (%d bytes), want %s (%d bytes)", i, f.Name, len(f.Data), testFiles[i].Name, len(testFiles[i].Data))
+		}
+	}
+}
+
+func TestWriteEmptyPassphrase(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, nil, testFiles); err == nil {
+		t.Error("Write accepted an empty passphrase")
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	archive := writeArchive(t, "correct horse", testFiles)
+	tamper := func(i int) []byte {
+		b := bytes.Clone(archive)
+		b[i] ^= 0x01
+		return b
+	}
+	tests := []struct {
+		name       string
+		archive    []byte
+		passphrase string
+		want       error // nil for any error
+	}{
+		{"wrong passphrase", archive, "correct horse battery", ErrDecrypt},
+		{"tampered salt", tamper(len(magic)), "correct horse", ErrDecrypt},
+		{"tampered nonce", tamper(len(magic) + saltSize), "correct horse", ErrDecrypt},
+		{"tampered ciphertext", tamper(len(archive) / 2), "correct horse", ErrDecrypt},
+		{"tampered tag", tamper(len(archive) - 1), "correct horse", ErrDecrypt},
+		{"truncated ciphertext", archive[:len(archive)-1], "correct horse", ErrDecrypt},
+		{"truncated to the salt", archive[:len(magic)+saltSize/2], "correct horse", ErrDecrypt},
+		{"truncated to the nonce", archive[:len(magic)+saltSize+4], "correct horse", ErrDecrypt},
+		{"not an archive", []byte("hello"), "correct horse", nil},
+		{"tampered magic", tamper(0), "correct horse", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Read(bytes.NewReader(tt.archive), []byte(tt.passphrase))
+			if err == nil {
+				t.Fatalf("Read succeeded with %d files", len(files))
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	defer func(size int) { maxArchiveSize = size }(maxArchiveSize)
+	maxArchiveSize = 64 << 10
+
+	// Random data doesn't compress, so this is over the limit.
+	big := make([]byte, maxArchiveSize)
+	rand.Read(big)
+	var buf bytes.Buffer
+	if err := Write(&buf, []byte("pass"), []File{{Name: "history.db", Data: big}}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Write err = %v, want ErrTooLarge", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Write wrote %d bytes of an archive over the limit", buf.Len())
+	}
+
+	// Anything Write accepts, Read does too.
+	archive := writeArchive(t, "pass", []File{{Name: "history.db", Data: big[:maxArchiveSize/2]}})
+	if _, err := Read(bytes.NewReader(archive), []byte("pass")); err != nil {
+		t.Errorf("Read of an archive under the limit: %v", err)
+	}
+
+	oversized := append([]byte(magic), make([]byte, maxArchiveSize)...)
+	if _, err := Read(bytes.NewReader(oversized), []byte("pass")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Read err = %v, want ErrTooLarge", err)
+	}
+}
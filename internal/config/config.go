@@ -0,0 +1,594 @@
+// Package config loads bot settings from a YAML file with named profiles,
+// overlaid with environment variables.
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/binance"
+	"github.com/killabayte/golang-telegram-bot/pkg/bybit"
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+)
+
+// DefaultProfile is used when neither the caller nor the file selects one.
+const DefaultProfile = "default"
+
+// DefaultIdeasFile is where trade ideas are kept when not configured.
+const DefaultIdeasFile = "ideas.json"
+
+// DefaultPollInterval is how often watch mode polls when not configured.
+const DefaultPollInterval = monitor.DefaultInterval
+
+// DefaultDashboardSessionTTL is how long a dashboard login lasts when not
+// configured.
+const DefaultDashboardSessionTTL = 24 * time.Hour
+
+// File is the on-disk layout: a set of profiles plus the one to use by default.
+type File struct {
+	Profile  string             `yaml:"profile"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Telegram holds the bot credentials and the chat it reports to.
+type Telegram struct {
+	Token  string `yaml:"token"`
+	ChatID string `yaml:"chat_id"`
+}
+
+// Imbalance configures order book imbalance tracking.
+type Imbalance struct {
+	// Levels is how many depth levels per side are compared; zero disables tracking.
+	Levels int `yaml:"levels"`
+	// InReports appends the imbalance to divergence reports.
+	InReports bool `yaml:"in_reports"`
+	// Threshold sends a separate alert when |imbalance| reaches it (0 to 1).
+	Threshold float64 `yaml:"threshold"`
+}
+
+// Funding configures funding rate tracking.
+type Funding struct {
+	// InReports appends the next and last settled funding rates to divergence reports.
+	InReports bool `yaml:"in_reports"`
+	// Percent sends a separate alert when a position pays at least this many
+	// percent of its notional at the next settlement; zero disables the alert.
+	Percent float64 `yaml:"percent"`
+}
+
+// StalePositions configures nudges for positions held too long.
+type StalePositions struct {
+	// After is how long a position may stay open before a nudge; zero disables nudges.
+	After time.Duration `yaml:"after"`
+	// Repeat re-sends the nudge at this interval; zero nudges once.
+	Repeat time.Duration `yaml:"repeat"`
+}
+
+// Threshold is the minimum move worth reporting; see Thresholds.
+type Threshold struct {
+	Percent  float64 `yaml:"percent"`
+	Absolute float64 `yaml:"absolute"`
+}
+
+// Thresholds limits divergence reports to meaningful moves. A move is
+// reported when it meets any non-zero limit. An entry under Symbols replaces
+// the defaults for that contract entirely.
+type Thresholds struct {
+	Threshold `yaml:",inline"`
+	Symbols   map[string]Threshold `yaml:"symbols"`
+}
+
+// For returns the threshold that applies to symbol, whose case doesn't
+// matter. An entry for an exchange-qualified symbol such as
+// "binance:BTC_USDT" takes precedence over one for the bare symbol. Profile
+// validation rejects entries that differ only in case.
+func (t Thresholds) For(symbol string) Threshold {
+	for s, override := range t.Symbols {
+		if strings.EqualFold(s, symbol) {
+			return override
+		}
+	}
+	bare := exchange.BareSymbol(symbol)
+	for s, override := range t.Symbols {
+		if strings.EqualFold(s, bare) {
+			return override
+		}
+	}
+	return t.Threshold
+}
+
+// Hedges configures the check on long and short legs held on the same
+// underlying.
+type Hedges struct {
+	// Tolerance alerts when net exposure reaches this fraction (0 to 1) of
+	// the larger leg; zero disables the check.
+	Tolerance float64 `yaml:"tolerance"`
+	// Rebalance closes part of the oversized leg when a hedge drifts.
+	Rebalance Rebalance `yaml:"rebalance"`
+}
+
+// Rebalance configures the hedge rebalancer. Orders are only placed when
+// Live is set; otherwise the planned order is reported and nothing is traded.
+type Rebalance struct {
+	Enabled bool `yaml:"enabled"`
+	Live    bool `yaml:"live"`
+	// Ratio is the target short exposure as a fraction of long; 1 is delta-neutral.
+	Ratio float64 `yaml:"ratio"`
+	// MaxOrderValue caps one order's notional in quote currency (e.g. USDT).
+	MaxOrderValue float64 `yaml:"max_order_value"`
+	// MaxOrdersPerDay caps orders over any 24 hours.
+	MaxOrdersPerDay int `yaml:"max_orders_per_day"`
+	// Cooldown is the minimum gap between orders on one underlying.
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+// MissingData configures alerts for symbols whose fair price can't be fetched.
+type MissingData struct {
+	// After alerts once a symbol has failed this many polls in a row; zero
+	// only logs the failures.
+	After int `yaml:"after"`
+}
+
+// EquityDrop configures alerts for falls in account equity.
+type EquityDrop struct {
+	// Percent alerts when a currency's equity falls this many percent below
+	// its highest value within Window; zero disables the check.
+	Percent float64       `yaml:"percent"`
+	Window  time.Duration `yaml:"window"`
+}
+
+// Incidents configures grouping of alert bursts on Telegram.
+type Incidents struct {
+	// Alerts is how many alerts within Window open an incident, which
+	// collects them and any that follow into one message; zero disables
+	// grouping.
+	Alerts int           `yaml:"alerts"`
+	Window time.Duration `yaml:"window"`
+}
+
+// Alerts controls how often divergence and imbalance alerts repeat.
+type Alerts struct {
+	// Cooldown is the minimum gap between two alerts for the same position or symbol.
+	Cooldown time.Duration `yaml:"cooldown"`
+	// Repeat re-sends an alert while its condition holds; zero alerts once.
+	Repeat time.Duration `yaml:"repeat"`
+	// Hysteresis is how far (0 to 1) a value must fall back below its
+	// threshold before the alert re-arms.
+	Hysteresis float64 `yaml:"hysteresis"`
+}
+
+// Retry controls retries of failed MEXC requests. Zero values use the
+// defaults in mexc.DefaultRetryPolicy.
+type Retry struct {
+	// MaxAttempts is the total number of tries per request; 1 disables retries.
+	MaxAttempts int `yaml:"max_attempts"`
+	// BaseDelay is the initial backoff, doubled on each retry.
+	BaseDelay time.Duration `yaml:"base_delay"`
+	// MaxDelay caps the backoff.
+	MaxDelay time.Duration `yaml:"max_delay"`
+}
+
+// Policy returns the retry policy with defaults filled in.
+func (r Retry) Policy() mexc.RetryPolicy {
+	policy := mexc.DefaultRetryPolicy
+	if r.MaxAttempts != 0 {
+		policy.MaxAttempts = r.MaxAttempts
+	}
+	if r.BaseDelay != 0 {
+		policy.BaseDelay = r.BaseDelay
+	}
+	if r.MaxDelay != 0 {
+		policy.MaxDelay = r.MaxDelay
+	}
+	return policy
+}
+
+// RateLimit caps requests to one MEXC endpoint group.
+type RateLimit struct {
+	// Rate is the sustained limit in requests per second.
+	Rate float64 `yaml:"rate"`
+	// Burst is how many requests may go at once before Rate applies.
+	Burst int `yaml:"burst"`
+}
+
+// RateLimits overrides request limits by endpoint group: market, account or
+// order.
+type RateLimits map[string]RateLimit
+
+// Limits returns mexc.DefaultRateLimits with the overrides applied.
+func (r RateLimits) Limits() map[string]mexc.RateLimit {
+	limits := make(map[string]mexc.RateLimit, len(mexc.DefaultRateLimits))
+	for group, limit := range mexc.DefaultRateLimits {
+		limits[group] = limit
+	}
+	for group, limit := range r {
+		limits[group] = mexc.RateLimit{Rate: limit.Rate, Burst: limit.Burst}
+	}
+	return limits
+}
+
+// HTTP tunes the connections to the exchange's API. Zero values use the
+// defaults in mexc.DefaultTransportOptions.
+type HTTP struct {
+	// MaxIdleConnsPerHost is how many idle connections are kept for reuse.
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host"`
+	// IdleConnTimeout closes connections idle for longer.
+	IdleConnTimeout time.Duration `yaml:"idle_conn_timeout"`
+	// KeepAlive is the TCP keep-alive interval.
+	KeepAlive time.Duration `yaml:"keep_alive"`
+	// DisableHTTP2 restricts requests to HTTP/1.1.
+	DisableHTTP2 bool `yaml:"disable_http2"`
+}
+
+// TransportOptions returns the transport settings with defaults filled in.
+func (h HTTP) TransportOptions() mexc.TransportOptions {
+	opts := mexc.DefaultTransportOptions
+	if h.MaxIdleConnsPerHost != 0 {
+		opts.MaxIdleConnsPerHost = h.MaxIdleConnsPerHost
+	}
+	if h.IdleConnTimeout != 0 {
+		opts.IdleConnTimeout = h.IdleConnTimeout
+	}
+	if h.KeepAlive != 0 {
+		opts.KeepAlive = h.KeepAlive
+	}
+	opts.DisableHTTP2 = h.DisableHTTP2
+	return opts
+}
+
+// Log configures the bot's diagnostic logging.
+type Log struct {
+	// Level is debug, info, warn or error; empty means info.
+	Level string `yaml:"level"`
+	// Format is text or json; empty means text.
+	Format string `yaml:"format"`
+}
+
+// Storage configures the SQLite history database.
+type Storage struct {
+	// Path is the database file; empty disables history and alert state persistence.
+	Path string `yaml:"path"`
+	// Retention is how long prices, snapshots and sent alerts are kept; zero keeps everything.
+	Retention time.Duration `yaml:"retention"`
+}
+
+// Stream configures the WebSocket fair price feed used by watch mode.
+type Stream struct {
+	// Enabled replaces per-symbol fair price polling with the stream.
+	Enabled bool `yaml:"enabled"`
+	// URL is the WebSocket endpoint; empty uses the MEXC default.
+	URL string `yaml:"url"`
+	// Private also logs in to receive position, order and ADL pushes.
+	Private bool `yaml:"private"`
+}
+
+// Dashboard configures the web dashboard served by watch and serve.
+type Dashboard struct {
+	// Listen is the address to serve on, e.g. "127.0.0.1:8080"; empty
+	// disables the dashboard.
+	Listen string `yaml:"listen"`
+	// BotUsername is the bot's username without "@", which the Telegram
+	// Login Widget needs. Users log in as the Telegram chat.
+	BotUsername string `yaml:"bot_username"`
+	// SessionTTL is how long a login lasts.
+	SessionTTL time.Duration `yaml:"session_ttl"`
+}
+
+// Spot configures the MEXC spot account of the same key pair.
+type Spot struct {
+	// Enabled adds spot holdings to /portfolio. The key needs spot account
+	// read permission.
+	Enabled bool `yaml:"enabled"`
+	// BaseURL is the spot API endpoint; empty uses the MEXC default.
+	BaseURL string `yaml:"base_url"`
+}
+
+// Exchanges an account can be on.
+const (
+	ExchangeMEXC    = "mexc"
+	ExchangeBinance = "binance"
+	ExchangeBybit   = "bybit"
+)
+
+// Account configures an exchange account monitored alongside the MEXC one.
+type Account struct {
+	// Label and Exchange are only read for entries of Profile.Accounts. The
+	// label names the account in messages by qualifying its symbols, e.g.
+	// "sub:BTC_USDT".
+	Label    string `yaml:"label"`
+	Exchange string `yaml:"exchange"`
+
+	APIKey    string `yaml:"api_key"`
+	SecretKey string `yaml:"secret_key"`
+	// BaseURL and StreamURL default to the exchange's production endpoints.
+	BaseURL   string `yaml:"base_url"`
+	StreamURL string `yaml:"stream_url"`
+}
+
+// Enabled reports whether an API key is configured.
+func (a Account) Enabled() bool {
+	return a.APIKey != ""
+}
+
+// setDefaults fills in the production endpoints of exchange.
+func (a *Account) setDefaults(exchange string) {
+	var baseURL, streamURL string
+	switch exchange {
+	case ExchangeMEXC:
+		baseURL, streamURL = mexc.DefaultBaseURL, mexc.DefaultStreamURL
+	case ExchangeBinance:
+		baseURL, streamURL = binance.DefaultBaseURL, binance.DefaultStreamURL
+	case ExchangeBybit:
+		baseURL, streamURL = bybit.DefaultBaseURL, bybit.DefaultStreamURL
+	}
+	if a.BaseURL == "" {
+		a.BaseURL = baseURL
+	}
+	if a.StreamURL == "" {
+		a.StreamURL = streamURL
+	}
+}
+
+// Profile is one complete set of settings, e.g. "prod" or "testnet".
+type Profile struct {
+	Name string `yaml:"-"`
+
+	BaseURL   string `yaml:"base_url"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	// SecondaryAccessKey and SecondarySecretKey are a standby key pair used
+	// once MEXC rejects the primary, for rotating keys without downtime.
+	SecondaryAccessKey string `yaml:"secondary_access_key"`
+	SecondarySecretKey string `yaml:"secondary_secret_key"`
+	// Retry controls retries of failed API requests.
+	Retry Retry `yaml:"retry"`
+	// RateLimits keeps requests within the exchange's limits.
+	RateLimits RateLimits `yaml:"rate_limits"`
+	// HTTP tunes connection reuse.
+	HTTP HTTP `yaml:"http"`
+	// Spot reads the spot account alongside futures positions.
+	Spot Spot `yaml:"spot"`
+
+	// Binance adds a Binance USDT-M futures account to reports and alerts.
+	Binance Account `yaml:"binance"`
+	// Bybit adds the linear contracts of a Bybit unified trading account.
+	Bybit Account `yaml:"bybit"`
+	// Accounts adds any number of further accounts, e.g. sub-accounts
+	// running other strategies. Each needs a unique label.
+	Accounts []Account `yaml:"accounts"`
+	// Label names the MEXC account above; it is required alongside
+	// Accounts so that every message says which account it is about.
+	Label string `yaml:"label"`
+
+	// Symbols limits reports to these contracts. Empty means every open position.
+	Symbols []string `yaml:"symbols"`
+
+	// PollInterval is how often watch mode refreshes positions and prices.
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// Thresholds filters divergence reports.
+	Thresholds Thresholds `yaml:"thresholds"`
+
+	// Stream switches watch mode to pushed fair prices.
+	Stream Stream `yaml:"stream"`
+	// Concurrency caps parallel fair price requests per poll; zero uses the default.
+	Concurrency int `yaml:"concurrency"`
+
+	Imbalance      Imbalance      `yaml:"imbalance"`
+	Funding        Funding        `yaml:"funding"`
+	StalePositions StalePositions `yaml:"stale_positions"`
+	Hedges         Hedges         `yaml:"hedges"`
+	MissingData    MissingData    `yaml:"missing_data"`
+	EquityDrop     EquityDrop     `yaml:"equity_drop"`
+	Alerts         Alerts         `yaml:"alerts"`
+	Incidents      Incidents      `yaml:"incidents"`
+
+	// IdeasFile is where /idea trade ideas are saved.
+	IdeasFile string  `yaml:"ideas_file"`
+	Storage   Storage `yaml:"storage"`
+
+	ExpectedIPs    []string `yaml:"expected_ips"`
+	EgressCheckURL string   `yaml:"egress_check_url"`
+
+	Telegram  Telegram  `yaml:"telegram"`
+	Dashboard Dashboard `yaml:"dashboard"`
+	Log       Log       `yaml:"log"`
+}
+
+// Load reads the config file at path and returns the selected profile with
+// environment overrides applied. The profile is chosen by name, then by the
+// file's "profile" key, then DefaultProfile. An empty path skips the file and
+// builds the profile from the environment alone. Unknown keys and invalid
+// settings are reported as errors; see ValidateFile.
+func Load(path, name string) (*Profile, error) {
+	var profile Profile
+	var root *yaml.Node
+
+	if path != "" {
+		file, node, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		root = node
+
+		if name == "" {
+			name = file.Profile
+		}
+		if name == "" {
+			name = DefaultProfile
+		}
+
+		p, ok := file.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("config %s has no profile %q (available: %s)", path, name, strings.Join(profileNames(file), ", "))
+		}
+		profile = p
+	}
+
+	if name == "" {
+		name = DefaultProfile
+	}
+	profile.Name = name
+
+	if err := profile.applyEnv(); err != nil {
+		return nil, err
+	}
+	if profile.BaseURL == "" {
+		profile.BaseURL = mexc.DefaultBaseURL
+	}
+	if profile.Stream.URL == "" {
+		profile.Stream.URL = mexc.DefaultStreamURL
+	}
+	if profile.Spot.BaseURL == "" {
+		profile.Spot.BaseURL = mexc.DefaultSpotBaseURL
+	}
+	profile.Binance.setDefaults(ExchangeBinance)
+	profile.Bybit.setDefaults(ExchangeBybit)
+	for i := range profile.Accounts {
+		profile.Accounts[i].setDefaults(profile.Accounts[i].Exchange)
+	}
+	if profile.IdeasFile == "" {
+		profile.IdeasFile = DefaultIdeasFile
+	}
+	if profile.PollInterval == 0 {
+		profile.PollInterval = DefaultPollInterval
+	}
+	if profile.Dashboard.SessionTTL == 0 {
+		profile.Dashboard.SessionTTL = DefaultDashboardSessionTTL
+	}
+	if err := profile.validate(path, name, root); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// applyEnv overrides file settings with any non-empty environment variables.
+func (p *Profile) applyEnv() error {
+	overrides := []struct {
+		env    string
+		target *string
+	}{
+		{"MEXC_BASE_URL", &p.BaseURL},
+		{"MEXC_SPOT_BASE_URL", &p.Spot.BaseURL},
+		{"MEXC_ACCESS_KEY", &p.AccessKey},
+		{"MEXC_SECRET_KEY", &p.SecretKey},
+		{"MEXC_SECONDARY_ACCESS_KEY", &p.SecondaryAccessKey},
+		{"MEXC_SECONDARY_SECRET_KEY", &p.SecondarySecretKey},
+		{"BINANCE_BASE_URL", &p.Binance.BaseURL},
+		{"BINANCE_API_KEY", &p.Binance.APIKey},
+		{"BINANCE_SECRET_KEY", &p.Binance.SecretKey},
+		{"BYBIT_BASE_URL", &p.Bybit.BaseURL},
+		{"BYBIT_API_KEY", &p.Bybit.APIKey},
+		{"BYBIT_SECRET_KEY", &p.Bybit.SecretKey},
+		{"TELEGRAM_BOT_TOKEN", &p.Telegram.Token},
+		{"TELEGRAM_CHAT_ID", &p.Telegram.ChatID},
+		{"EGRESS_CHECK_URL", &p.EgressCheckURL},
+		{"BOT_LOG_LEVEL", &p.Log.Level},
+		{"BOT_LOG_FORMAT", &p.Log.Format},
+	}
+	for _, o := range overrides {
+		if v := os.Getenv(o.env); v != "" {
+			*o.target = v
+		}
+	}
+
+	if v := os.Getenv("MEXC_SYMBOLS"); v != "" {
+		p.Symbols = splitList(v)
+	}
+	if v := os.Getenv("MEXC_EXPECTED_IPS"); v != "" {
+		p.ExpectedIPs = splitList(v)
+	}
+	floats := []struct {
+		env    string
+		target *float64
+	}{
+		{"BOT_THRESHOLD_PERCENT", &p.Thresholds.Percent},
+		{"BOT_THRESHOLD_ABSOLUTE", &p.Thresholds.Absolute},
+	}
+	for _, o := range floats {
+		if v := os.Getenv(o.env); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", o.env, err)
+			}
+			*o.target = f
+		}
+	}
+	if v := os.Getenv("BOT_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("BOT_CONCURRENCY: %w", err)
+		}
+		p.Concurrency = n
+	}
+	if v := os.Getenv("BOT_POLL_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("BOT_POLL_INTERVAL: %w", err)
+		}
+		p.PollInterval = d
+	}
+	return nil
+}
+
+// WatchesSymbol reports whether symbol should be included in reports.
+func (p *Profile) WatchesSymbol(symbol string) bool {
+	if len(p.Symbols) == 0 {
+		return true
+	}
+	bare := exchange.BareSymbol(symbol)
+	for _, s := range p.Symbols {
+		if strings.EqualFold(s, symbol) || strings.EqualFold(s, bare) {
+			return true
+		}
+	}
+	return false
+}
+
+// OtherAccounts returns every enabled account besides the top-level MEXC
+// one: the binance and bybit sections, which have no label, followed by
+// Accounts.
+func (p *Profile) OtherAccounts() []Account {
+	var accounts []Account
+	if p.Binance.Enabled() {
+		a := p.Binance
+		a.Label, a.Exchange = "", ExchangeBinance
+		accounts = append(accounts, a)
+	}
+	if p.Bybit.Enabled() {
+		a := p.Bybit
+		a.Label, a.Exchange = "", ExchangeBybit
+		accounts = append(accounts, a)
+	}
+	return append(accounts, p.Accounts...)
+}
+
+// TelegramEnabled reports whether both Telegram settings are present.
+func (p *Profile) TelegramEnabled() bool {
+	return p.Telegram.Token != "" && p.Telegram.ChatID != ""
+}
+
+func profileNames(file File) []string {
+	names := make([]string, 0, len(file.Profiles))
+	for name := range file.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
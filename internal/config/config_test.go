@@ -0,0 +1,352 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/binance"
+	"github.com/killabayte/golang-telegram-bot/pkg/bybit"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// envVars are every variable applyEnv reads.
+var envVars = []string{
+	"MEXC_BASE_URL", "MEXC_SPOT_BASE_URL", "MEXC_ACCESS_KEY", "MEXC_SECRET_KEY",
+	"MEXC_SECONDARY_ACCESS_KEY", "MEXC_SECONDARY_SECRET_KEY",
+	"BINANCE_BASE_URL", "BINANCE_API_KEY", "BINANCE_SECRET_KEY",
+	"BYBIT_BASE_URL", "BYBIT_API_KEY", "BYBIT_SECRET_KEY",
+	"TELEGRAM_BOT_TOKEN", "TELEGRAM_CHAT_ID", "EGRESS_CHECK_URL",
+	"BOT_LOG_LEVEL", "BOT_LOG_FORMAT", "MEXC_SYMBOLS", "MEXC_EXPECTED_IPS",
+	"BOT_THRESHOLD_PERCENT", "BOT_THRESHOLD_ABSOLUTE", "BOT_CONCURRENCY", "BOT_POLL_INTERVAL",
+}
+
+// writeConfig clears the environment overrides and writes config to a
+// temporary file.
+func writeConfig(t *testing.T, config string) string {
+	t.Helper()
+	for _, env := range envVars {
+		t.Setenv(env, "")
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fieldErrors returns the FieldErrors joined in err, failing on any other
+// error.
+func fieldErrors(t *testing.T, err error) []*FieldError {
+	t.Helper()
+	var fes []*FieldError
+	for _, e := range unjoin(err) {
+		var fe *FieldError
+		if !errors.As(e, &fe) {
+			t.Fatalf("error %v is not a *FieldError", e)
+		}
+		fes = append(fes, fe)
+	}
+	return fes
+}
+
+const profilesConfig = `profile: prod
+profiles:
+  prod:
+    access_key: prod-key
+    secret_key: prod-secret
+    symbols: [BTC_USDT, ETH_USDT]
+    poll_interval: 1m
+  testnet:
+    base_url: https://testnet.example.com
+    access_key: test-key
+    secret_key: test-secret
+`
+
+func TestLoadProfileSelection(t *testing.T) {
+	path := writeConfig(t, profilesConfig)
+
+	tests := []struct {
+		name    string
+		want    string
+		wantKey string
+	}{
+		{"", "prod", "prod-key"}, // the file's profile key
+		{"testnet", "testnet", "test-key"},
+	}
+	for _, tt := range tests {
+		p, err := Load(path, tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Name != tt.want || p.AccessKey != tt.wantKey {
+			t.Errorf("Load(%q) = profile %q with key %q, want %q with %q", tt.name, p.Name, p.AccessKey, tt.want, tt.wantKey)
+		}
+	}
+
+	p, err := Load(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(p.Symbols, ",") != "BTC_USDT,ETH_USDT" || p.PollInterval != time.Minute {
+		t.Errorf("prod = symbols %v every %s", p.Symbols, p.PollInterval)
+	}
+
+	_, err = Load(path, "staging")
+	if err == nil || !strings.Contains(err.Error(), `no profile "staging" (available: prod, testnet)`) {
+		t.Errorf("Load(staging) err = %v", err)
+	}
+}
+
+func TestLoadDefaultProfile(t *testing.T) {
+	path := writeConfig(t, "profiles:\n  default:\n    symbols: [BTC_USDT]\n")
+	p, err := Load(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != DefaultProfile || len(p.Symbols) != 1 {
+		t.Errorf("Load = %q with %v, want the default profile", p.Name, p.Symbols)
+	}
+}
+
+func TestLoadEnvOverrides(t *testing.T) {
+	path := writeConfig(t, profilesConfig+`    telegram:
+      token: file-token
+      chat_id: "1"
+    thresholds:
+      percent: 2
+`)
+	t.Setenv("MEXC_ACCESS_KEY", "env-key")
+	t.Setenv("MEXC_SECRET_KEY", "env-secret")
+	t.Setenv("TELEGRAM_CHAT_ID", "2")
+	t.Setenv("MEXC_SYMBOLS", " SOL_USDT, ,XRP_USDT ")
+	t.Setenv("BOT_THRESHOLD_PERCENT", "0.5")
+	t.Setenv("BOT_POLL_INTERVAL", "15s")
+
+	p, err := Load(path, "testnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AccessKey != "env-key" || p.SecretKey != "env-secret" {
+		t.Errorf("key pair = %q, %q, want the environment's", p.AccessKey, p.SecretKey)
+	}
+	if p.Telegram.Token != "file-token" || p.Telegram.ChatID != "2" {
+		t.Errorf("telegram = %+v, want the file's token and the environment's chat", p.Telegram)
+	}
+	if strings.Join(p.Symbols, ",") != "SOL_USDT,XRP_USDT" {
+		t.Errorf("symbols = %q", p.Symbols)
+	}
+	if p.Thresholds.Percent != 0.5 || p.PollInterval != 15*time.Second {
+		t.Errorf("threshold %v, poll interval %s, want 0.5 and 15s", p.Thresholds.Percent, p.PollInterval)
+	}
+	if p.BaseURL != "https://testnet.example.com" {
+		t.Errorf("base_url = %q, want the file's as MEXC_BASE_URL is empty", p.BaseURL)
+	}
+}
+
+func TestLoadEnvOnly(t *testing.T) {
+	writeConfig(t, "")
+	t.Setenv("MEXC_ACCESS_KEY", "env-key")
+	t.Setenv("MEXC_SECRET_KEY", "env-secret")
+
+	p, err := Load("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != DefaultProfile || p.AccessKey != "env-key" {
+		t.Errorf("Load = %q with key %q", p.Name, p.AccessKey)
+	}
+
+	t.Setenv("BOT_POLL_INTERVAL", "soon")
+	if _, err := Load("", ""); err == nil || !strings.Contains(err.Error(), "BOT_POLL_INTERVAL") {
+		t.Errorf("err = %v, want one naming BOT_POLL_INTERVAL", err)
+	}
+}
+
+func TestLoadKeyPairs(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		env    map[string]string
+		want   string // field with the error
+		line   int    // of the setting, or of the deepest key above it
+	}{
+		{"access key only", "access_key: key", nil, "profiles.default.secret_key", 2},
+		{"secret key only", "secret_key: secret", nil, "profiles.default.secret_key", 3},
+		{"secret from the environment", "access_key: key", map[string]string{"MEXC_SECRET_KEY": "secret"}, "", 0},
+		{"access key from the environment only", "symbols: [BTC_USDT]", map[string]string{"MEXC_ACCESS_KEY": "key"}, "profiles.default.secret_key", 2},
+		{"binance", "binance: {api_key: key}", nil, "profiles.default.binance.secret_key", 3},
+		{"secondary", "access_key: key\n    secret_key: secret\n    secondary_access_key: other", nil, "profiles.default.secondary_secret_key", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, "profiles:\n  default:\n    "+tt.config+"\n")
+			for env, v := range tt.env {
+				t.Setenv(env, v)
+			}
+			_, err := Load(path, "")
+			if tt.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			fes := fieldErrors(t, err)
+			if len(fes) != 1 || fes[0].Field != tt.want || !strings.Contains(fes[0].Message, "must be set together") {
+				t.Fatalf("err = %v, want one error on %s", err, tt.want)
+			}
+			if fes[0].File != path || fes[0].Line != tt.line {
+				t.Errorf("error at %s:%d, want %s:%d", fes[0].File, fes[0].Line, path, tt.line)
+			}
+		})
+	}
+}
+
+func TestLoadStrictDecoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   FieldError // File is filled in
+	}{
+		{
+			name:   "unknown key",
+			config: "profiles:\n  default:\n    symbols: [BTC_USDT]\n    thresholds:\n      percnt: 2\n",
+			want:   FieldError{Line: 5, Field: "profiles.default.thresholds.percnt", Message: "unknown key"},
+		},
+		{
+			name:   "unknown key in a list",
+			config: "profiles:\n  default:\n    accounts:\n      - label: sub\n        exchnge: bybit\n",
+			want:   FieldError{Line: 5, Field: "profiles.default.accounts.0.exchnge", Message: "unknown key"},
+		},
+		{
+			name:   "unknown top-level key",
+			config: "profile: default\nprofils: {}\n",
+			want:   FieldError{Line: 2, Field: "profils", Message: "unknown key"},
+		},
+		{
+			name:   "wrong type",
+			config: "profiles:\n  default:\n    poll_interval: often\n",
+			want:   FieldError{Line: 3, Field: "profiles.default.poll_interval", Message: "cannot unmarshal !!str `often` into time.Duration"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.config)
+			tt.want.File = path
+			for _, check := range []struct {
+				name string
+				err  error
+			}{
+				{"Load", func() error { _, err := Load(path, ""); return err }()},
+				{"ValidateFile", ValidateFile(path)},
+			} {
+				fes := fieldErrors(t, check.err)
+				if len(fes) != 1 || *fes[0] != tt.want {
+					t.Errorf("%s err = %v, want %v", check.name, check.err, &tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	path := writeConfig(t, "profiles:\n  default:\n    accounts:\n      - {label: sub, exchange: bybit, api_key: key, secret_key: secret}\n")
+	p, err := Load(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.BaseURL != mexc.DefaultBaseURL || p.Stream.URL != mexc.DefaultStreamURL || p.Spot.BaseURL != mexc.DefaultSpotBaseURL {
+		t.Errorf("MEXC URLs = %q, %q, %q, want the defaults", p.BaseURL, p.Stream.URL, p.Spot.BaseURL)
+	}
+	if p.Binance.BaseURL != binance.DefaultBaseURL || p.Bybit.BaseURL != bybit.DefaultBaseURL {
+		t.Errorf("binance %q, bybit %q, want the defaults", p.Binance.BaseURL, p.Bybit.BaseURL)
+	}
+	if a := p.Accounts[0]; a.BaseURL != bybit.DefaultBaseURL || a.StreamURL != bybit.DefaultStreamURL {
+		t.Errorf("account URLs = %q, %q, want Bybit's", a.BaseURL, a.StreamURL)
+	}
+	if p.IdeasFile != DefaultIdeasFile || p.PollInterval != DefaultPollInterval || p.Dashboard.SessionTTL != DefaultDashboardSessionTTL {
+		t.Errorf("ideas file %q, poll interval %s, session TTL %s, want the defaults", p.IdeasFile, p.PollInterval, p.Dashboard.SessionTTL)
+	}
+
+	if got := (Retry{}).Policy(); got != mexc.DefaultRetryPolicy {
+		t.Errorf("zero Retry policy = %+v, want %+v", got, mexc.DefaultRetryPolicy)
+	}
+	if got := (Retry{MaxAttempts: 1}).Policy(); got.MaxAttempts != 1 || got.BaseDelay != mexc.DefaultRetryPolicy.BaseDelay {
+		t.Errorf("Retry{MaxAttempts: 1} policy = %+v, want the other defaults kept", got)
+	}
+	limits := RateLimits{mexc.GroupOrder: {Rate: 2, Burst: 1}}.Limits()
+	if limits[mexc.GroupOrder] != (mexc.RateLimit{Rate: 2, Burst: 1}) || limits[mexc.GroupMarket] != mexc.DefaultRateLimits[mexc.GroupMarket] {
+		t.Errorf("limits = %+v, want order overridden and market defaulted", limits)
+	}
+	if got := (HTTP{DisableHTTP2: true}).TransportOptions(); got.MaxIdleConnsPerHost != mexc.DefaultTransportOptions.MaxIdleConnsPerHost || !got.DisableHTTP2 {
+		t.Errorf("transport options = %+v, want the defaults with HTTP/2 disabled", got)
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		field  string // with the error, after profiles.default
+		line   int
+		want   string
+	}{
+		{"private stream", "stream: {private: true}", "stream.private", 3, "requires stream.enabled"},
+		{"stream scheme", "stream: {url: https://example.com}", "stream.url", 3, "scheme must be ws or wss"},
+		{"negative poll interval", "poll_interval: -1s", "poll_interval", 3, "must not be negative"},
+		{"telegram pair", "telegram: {token: t}", "telegram", 3, "token and chat_id must be set together"},
+		{"dashboard without telegram", "dashboard: {listen: ':8080', bot_username: bot}", "dashboard.listen", 3, "requires telegram"},
+		{"imbalance without levels", "imbalance: {in_reports: true}", "imbalance.levels", 3, "must be set to use imbalance.in_reports"},
+		{"stale repeat", "stale_positions: {repeat: 1h}", "stale_positions.repeat", 3, "requires stale_positions.after"},
+		{"rebalance without tolerance", "hedges:\n      rebalance: {enabled: true, ratio: 1, max_order_value: 100, max_orders_per_day: 2}", "hedges.rebalance.enabled", 4, "requires hedges.tolerance"},
+		{"retention without path", "storage: {retention: 24h}", "storage.retention", 3, "requires storage.path"},
+		{"rate limit group", "rate_limits: {trades: {rate: 1}}", "rate_limits.trades", 3, "unknown endpoint group"},
+		{"single incident alert", "incidents: {alerts: 1}", "incidents.alerts", 3, "must be 0 or at least 2"},
+		{"log format", "log: {format: xml}", "log.format", 3, `"xml" is not text or json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, "profiles:\n  default:\n    "+tt.config+"\n")
+			_, err := Load(path, "")
+			fes := fieldErrors(t, err)
+			if len(fes) != 1 {
+				t.Fatalf("err = %v, want one error", err)
+			}
+			fe := fes[0]
+			if fe.Field != "profiles.default."+tt.field || fe.Line != tt.line || !strings.Contains(fe.Message, tt.want) {
+				t.Errorf("err = %v, want %q on %s at line %d", fe, tt.want, tt.field, tt.line)
+			}
+		})
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	path := writeConfig(t, `profile: staging
+profiles:
+  prod:
+    access_key: key
+  testnet:
+    log: {level: loud}
+`)
+	// The environment doesn't complete the pair as it does for Load.
+	t.Setenv("MEXC_SECRET_KEY", "secret")
+
+	fes := fieldErrors(t, ValidateFile(path))
+	var got []string
+	for _, fe := range fes {
+		got = append(got, fmt.Sprintf("%d %s", fe.Line, fe.Field))
+	}
+	want := []string{"1 profile", "3 profiles.prod.secret_key", "6 profiles.testnet.log.level"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("errors = %v, want %v", got, want)
+	}
+
+	path = writeConfig(t, profilesConfig)
+	if err := ValidateFile(path); err != nil {
+		t.Errorf("ValidateFile(valid config) = %v", err)
+	}
+}
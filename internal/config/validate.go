@@ -0,0 +1,525 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/killabayte/golang-telegram-bot/internal/cryptfile"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// FieldError is a setting that parsed but can't be used as given.
+type FieldError struct {
+	// File and Line locate the setting; Line is zero when it came from the
+	// environment or isn't in the file at all.
+	File string
+	Line int
+	// Field is the dotted path of the setting, e.g. "profiles.prod.stream.private".
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	switch {
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s: %s", e.File, e.Line, e.Field, e.Message)
+	case e.File != "":
+		return fmt.Sprintf("%s: %s: %s", e.File, e.Field, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidateFile checks every profile in the config file at path without
+// applying environment overrides. It reports unknown keys and values of the
+// wrong type as well as settings that don't make sense together.
+func ValidateFile(path string) error {
+	file, root, err := readFile(path)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if file.Profile != "" {
+		if _, ok := file.Profiles[file.Profile]; !ok {
+			errs = append(errs, &FieldError{
+				File:    path,
+				Line:    lineOf(root, "profile"),
+				Field:   "profile",
+				Message: fmt.Sprintf("no profile %q (available: %s)", file.Profile, strings.Join(profileNames(file), ", ")),
+			})
+		}
+	}
+	for _, name := range profileNames(file) {
+		p := file.Profiles[name]
+		if err := p.validate(path, name, root); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Decryption supplies the passphrase or identity for config files stored
+// encrypted with age or GPG, which are decrypted in memory when read.
+var Decryption cryptfile.Keys
+
+// readFile strictly decodes the config at path. The returned node is the
+// parsed document, used to point errors at lines.
+func readFile(path string) (File, *yaml.Node, error) {
+	var file File
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return file, nil, fmt.Errorf("reading config: %w", err)
+	}
+	if format := cryptfile.Detect(data); format != cryptfile.Plain {
+		if data, err = cryptfile.Decrypt(context.Background(), data, Decryption); err != nil {
+			return file, nil, fmt.Errorf("decrypting %s config %s: %w", format, path, err)
+		}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return file, nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && err != io.EOF {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			errs := make([]error, len(typeErr.Errors))
+			for i, msg := range typeErr.Errors {
+				errs[i] = decodeError(path, &root, msg)
+			}
+			return file, nil, errors.Join(errs...)
+		}
+		return file, nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return file, &root, nil
+}
+
+var (
+	unknownKey = regexp.MustCompile(`^line (\d+): field (\S+) not found in type`)
+	wrongType  = regexp.MustCompile("^line (\\d+): (cannot unmarshal !!\\w+ `([^`]*)`.*)$")
+)
+
+// decodeError turns one of yaml's strict decoding messages, which only give
+// a line, into a FieldError naming the setting.
+func decodeError(path string, root *yaml.Node, msg string) error {
+	if m := unknownKey.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		field := fieldAt(root, func(key, _ *yaml.Node) bool { return key.Line == line && key.Value == m[2] })
+		if field == "" {
+			field = m[2]
+		}
+		return &FieldError{File: path, Line: line, Field: field, Message: "unknown key"}
+	}
+	if m := wrongType.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		field := fieldAt(root, func(_, value *yaml.Node) bool {
+			return value.Line == line && value.Kind == yaml.ScalarNode && value.Value == m[3]
+		})
+		if field != "" {
+			return &FieldError{File: path, Line: line, Field: field, Message: m[2]}
+		}
+	}
+	return fmt.Errorf("%s: %s", path, msg)
+}
+
+// fieldAt returns the dotted path of the first key in the document, depth
+// first, for which match holds, or "" if there is none.
+func fieldAt(root *yaml.Node, match func(key, value *yaml.Node) bool) string {
+	var walk func(node *yaml.Node, path []string) []string
+	walk = func(node *yaml.Node, path []string) []string {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				if found := walk(child, path); found != nil {
+					return found
+				}
+			}
+		case yaml.SequenceNode:
+			for i, child := range node.Content {
+				if found := walk(child, append(path, strconv.Itoa(i))); found != nil {
+					return found
+				}
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				keyPath := append(append([]string{}, path...), key.Value)
+				if match(key, value) {
+					return keyPath
+				}
+				if found := walk(value, keyPath); found != nil {
+					return found
+				}
+			}
+		}
+		return nil
+	}
+	return strings.Join(walk(root, nil), ".")
+}
+
+// validate checks p's values. path and root locate the profile in its file
+// and may be empty when the profile was built from the environment alone.
+func (p *Profile) validate(path, name string, root *yaml.Node) error {
+	v := &validator{file: path, root: root}
+	if path != "" {
+		v.prefix = []string{"profiles", name}
+	}
+
+	v.checkURL("base_url", p.BaseURL, "http", "https")
+	v.checkURL("egress_check_url", p.EgressCheckURL, "http", "https")
+	v.checkURL("stream.url", p.Stream.URL, "ws", "wss")
+	v.checkURL("spot.base_url", p.Spot.BaseURL, "http", "https")
+	if p.Stream.Private && !p.Stream.Enabled {
+		v.fail("stream.private", "requires stream.enabled")
+	}
+	if (p.AccessKey == "") != (p.SecretKey == "") {
+		v.fail("secret_key", "access_key and secret_key must be set together")
+	}
+	if (p.SecondaryAccessKey == "") != (p.SecondarySecretKey == "") {
+		v.fail("secondary_secret_key", "secondary_access_key and secondary_secret_key must be set together")
+	}
+	if p.SecondaryAccessKey != "" && p.SecondaryAccessKey == p.AccessKey {
+		v.fail("secondary_access_key", "must differ from access_key")
+	}
+	v.checkAccount("binance", p.Binance)
+	v.checkAccount("bybit", p.Bybit)
+	v.checkAccounts(p)
+	if (p.Telegram.Token == "") != (p.Telegram.ChatID == "") {
+		v.fail("telegram", "token and chat_id must be set together")
+	}
+	if d := p.Dashboard; d.Listen != "" {
+		if _, _, err := net.SplitHostPort(d.Listen); err != nil {
+			v.fail("dashboard.listen", fmt.Sprintf("%q is not a host:port address", d.Listen))
+		}
+		if !p.TelegramEnabled() {
+			v.fail("dashboard.listen", "requires telegram, whose chat logs in to the dashboard")
+		}
+		if d.BotUsername == "" {
+			v.fail("dashboard.bot_username", "is required for the Telegram login")
+		}
+	}
+	if p.Dashboard.SessionTTL < 0 {
+		v.fail("dashboard.session_ttl", "must not be negative")
+	}
+
+	if p.Retry.MaxAttempts < 0 {
+		v.fail("retry.max_attempts", "must not be negative")
+	}
+	if p.Retry.BaseDelay < 0 {
+		v.fail("retry.base_delay", "must not be negative")
+	}
+	if p.Retry.MaxDelay < 0 {
+		v.fail("retry.max_delay", "must not be negative")
+	}
+
+	if p.HTTP.MaxIdleConnsPerHost < 0 {
+		v.fail("http.max_idle_conns_per_host", "must not be negative")
+	}
+	if p.HTTP.IdleConnTimeout < 0 {
+		v.fail("http.idle_conn_timeout", "must not be negative")
+	}
+	if p.HTTP.KeepAlive < 0 {
+		v.fail("http.keep_alive", "must not be negative")
+	}
+
+	for group, limit := range p.RateLimits {
+		field := "rate_limits." + group
+		if _, ok := mexc.DefaultRateLimits[group]; !ok {
+			v.fail(field, fmt.Sprintf("unknown endpoint group %q (want %s, %s or %s)", group, mexc.GroupMarket, mexc.GroupAccount, mexc.GroupOrder))
+			continue
+		}
+		if limit.Rate <= 0 {
+			v.fail(field+".rate", "must be greater than 0")
+		}
+		if limit.Burst < 0 {
+			v.fail(field+".burst", "must not be negative")
+		}
+	}
+
+	if p.PollInterval < 0 {
+		v.fail("poll_interval", "must not be negative")
+	}
+	if p.Concurrency < 0 {
+		v.fail("concurrency", "must not be negative")
+	}
+
+	v.checkThreshold("thresholds", p.Thresholds.Threshold)
+	symbols := make([]string, 0, len(p.Thresholds.Symbols))
+	for symbol := range p.Thresholds.Symbols {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	// Symbols match regardless of case, so two entries differing only in
+	// case would leave the threshold to map order.
+	seen := make(map[string]string, len(symbols))
+	for _, symbol := range symbols {
+		field := "thresholds.symbols." + symbol
+		if other, ok := seen[strings.ToUpper(symbol)]; ok {
+			v.fail(field, fmt.Sprintf("duplicates %s, as symbols match regardless of case", other))
+			continue
+		}
+		seen[strings.ToUpper(symbol)] = symbol
+		v.checkThreshold(field, p.Thresholds.Symbols[symbol])
+	}
+
+	if p.Imbalance.Levels < 0 {
+		v.fail("imbalance.levels", "must not be negative")
+	}
+	if p.Imbalance.Threshold < 0 || p.Imbalance.Threshold > 1 {
+		v.fail("imbalance.threshold", "must be between 0 and 1")
+	}
+	if p.Imbalance.Levels == 0 && (p.Imbalance.InReports || p.Imbalance.Threshold > 0) {
+		v.fail("imbalance.levels", "must be set to use imbalance.in_reports or imbalance.threshold")
+	}
+
+	if p.Funding.Percent < 0 || p.Funding.Percent >= 100 {
+		v.fail("funding.percent", "must be at least 0 and below 100")
+	}
+
+	if p.StalePositions.After < 0 {
+		v.fail("stale_positions.after", "must not be negative")
+	}
+	if p.StalePositions.Repeat < 0 {
+		v.fail("stale_positions.repeat", "must not be negative")
+	}
+	if p.StalePositions.Repeat > 0 && p.StalePositions.After == 0 {
+		v.fail("stale_positions.repeat", "requires stale_positions.after")
+	}
+
+	if p.Hedges.Tolerance < 0 || p.Hedges.Tolerance > 1 {
+		v.fail("hedges.tolerance", "must be between 0 and 1")
+	}
+	if r := p.Hedges.Rebalance; r.Enabled {
+		if p.Hedges.Tolerance == 0 {
+			v.fail("hedges.rebalance.enabled", "requires hedges.tolerance")
+		}
+		if r.Ratio <= 0 {
+			v.fail("hedges.rebalance.ratio", "must be greater than 0")
+		}
+		if r.MaxOrderValue <= 0 {
+			v.fail("hedges.rebalance.max_order_value", "must be greater than 0")
+		}
+		if r.MaxOrdersPerDay <= 0 {
+			v.fail("hedges.rebalance.max_orders_per_day", "must be greater than 0")
+		}
+		if r.Cooldown < 0 {
+			v.fail("hedges.rebalance.cooldown", "must not be negative")
+		}
+	}
+
+	if p.MissingData.After < 0 {
+		v.fail("missing_data.after", "must not be negative")
+	}
+
+	if p.EquityDrop.Percent < 0 || p.EquityDrop.Percent >= 100 {
+		v.fail("equity_drop.percent", "must be at least 0 and below 100")
+	}
+	if p.EquityDrop.Window < 0 {
+		v.fail("equity_drop.window", "must not be negative")
+	}
+	if p.EquityDrop.Window > 0 && p.EquityDrop.Percent == 0 {
+		v.fail("equity_drop.window", "requires equity_drop.percent")
+	}
+
+	if p.Incidents.Alerts < 0 || p.Incidents.Alerts == 1 {
+		v.fail("incidents.alerts", "must be 0 or at least 2")
+	}
+	if p.Incidents.Window < 0 {
+		v.fail("incidents.window", "must not be negative")
+	}
+	if p.Incidents.Window > 0 && p.Incidents.Alerts == 0 {
+		v.fail("incidents.window", "requires incidents.alerts")
+	}
+
+	if p.Alerts.Cooldown < 0 {
+		v.fail("alerts.cooldown", "must not be negative")
+	}
+	if p.Alerts.Repeat < 0 {
+		v.fail("alerts.repeat", "must not be negative")
+	}
+	if p.Alerts.Hysteresis < 0 || p.Alerts.Hysteresis >= 1 {
+		v.fail("alerts.hysteresis", "must be at least 0 and below 1")
+	}
+
+	if p.Storage.Retention < 0 {
+		v.fail("storage.retention", "must not be negative")
+	}
+	if p.Storage.Retention > 0 && p.Storage.Path == "" {
+		v.fail("storage.retention", "requires storage.path")
+	}
+
+	if p.Log.Level != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(p.Log.Level)); err != nil {
+			v.fail("log.level", fmt.Sprintf("%q is not one of debug, info, warn or error", p.Log.Level))
+		}
+	}
+	switch p.Log.Format {
+	case "", "text", "json":
+	default:
+		v.fail("log.format", fmt.Sprintf("%q is not text or json", p.Log.Format))
+	}
+
+	for _, ip := range p.ExpectedIPs {
+		if net.ParseIP(ip) == nil {
+			v.fail("expected_ips", fmt.Sprintf("%q is not an IP address", ip))
+		}
+	}
+
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	file   string
+	root   *yaml.Node
+	prefix []string
+	errs   []error
+}
+
+func (v *validator) fail(field, message string) {
+	path := append(append([]string{}, v.prefix...), strings.Split(field, ".")...)
+	v.errs = append(v.errs, &FieldError{
+		File:    v.file,
+		Line:    lineOf(v.root, path...),
+		Field:   strings.Join(path, "."),
+		Message: message,
+	})
+}
+
+func (v *validator) checkAccount(field string, a Account) {
+	v.checkURL(field+".base_url", a.BaseURL, "http", "https")
+	v.checkURL(field+".stream_url", a.StreamURL, "ws", "wss")
+	if (a.APIKey == "") != (a.SecretKey == "") {
+		v.fail(field+".secret_key", "api_key and secret_key must be set together")
+	}
+}
+
+// accountLabel is what an account label may look like: it becomes a symbol
+// prefix, so it must not contain ":".
+var accountLabel = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+func (v *validator) checkAccounts(p *Profile) {
+	// The binance and bybit sections are labeled with the exchange name.
+	labels := make(map[string]bool)
+	if p.Binance.Enabled() {
+		labels[ExchangeBinance] = true
+	}
+	if p.Bybit.Enabled() {
+		labels[ExchangeBybit] = true
+	}
+	checkLabel := func(field, label string) {
+		switch {
+		case !accountLabel.MatchString(label):
+			v.fail(field, "must be lowercase letters, digits, \"-\" and \"_\"")
+		case labels[label]:
+			v.fail(field, fmt.Sprintf("%q is already used by another account", label))
+		}
+		labels[label] = true
+	}
+
+	switch {
+	case p.Label != "":
+		checkLabel("label", p.Label)
+	case p.AccessKey != "" && len(p.Accounts) > 0:
+		v.fail("label", "is required when accounts are configured")
+	}
+	for i, a := range p.Accounts {
+		field := fmt.Sprintf("accounts.%d", i)
+		if a.Label == "" {
+			v.fail(field+".label", "is required")
+		} else {
+			checkLabel(field+".label", a.Label)
+		}
+		switch a.Exchange {
+		case ExchangeMEXC, ExchangeBinance, ExchangeBybit:
+		case "":
+			v.fail(field+".exchange", "is required")
+		default:
+			v.fail(field+".exchange", fmt.Sprintf("unknown exchange %q (want mexc, binance or bybit)", a.Exchange))
+		}
+		if !a.Enabled() {
+			v.fail(field+".api_key", "is required")
+		}
+		v.checkAccount(field, a)
+	}
+}
+
+func (v *validator) checkURL(field, value string, schemes ...string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		v.fail(field, fmt.Sprintf("%q is not an absolute URL", value))
+		return
+	}
+	for _, s := range schemes {
+		if u.Scheme == s {
+			return
+		}
+	}
+	v.fail(field, fmt.Sprintf("scheme must be %s", strings.Join(schemes, " or ")))
+}
+
+func (v *validator) checkThreshold(field string, t Threshold) {
+	if t.Percent < 0 {
+		v.fail(field+".percent", "must not be negative")
+	}
+	if t.Absolute < 0 {
+		v.fail(field+".absolute", "must not be negative")
+	}
+}
+
+// lineOf returns the line of the deepest key along path that exists in the
+// document, or zero if none does.
+func lineOf(root *yaml.Node, path ...string) int {
+	if root == nil {
+		return 0
+	}
+	node, line := root, 0
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, key := range path {
+		if node.Kind == yaml.SequenceNode {
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node.Content) {
+				break
+			}
+			node = node.Content[i]
+			line = node.Line
+			continue
+		}
+		if node.Kind != yaml.MappingNode {
+			break
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				line, next = node.Content[i].Line, node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line
+}
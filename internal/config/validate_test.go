@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateThresholdSymbols(t *testing.T) {
+	tests := []struct {
+		name    string
+		symbols string
+		want    []string // fields with errors
+	}{
+		{"distinct", "BTC_USDT: {percent: 1}\n        binance:BTC_USDT: {percent: 2}", nil},
+		{"case duplicate", "BTC_USDT: {percent: 1}\n        btc_usdt: {percent: 2}", []string{"thresholds.symbols.btc_usdt"}},
+		{"qualified case duplicate", "binance:BTC_USDT: {percent: 1}\n        Binance:btc_usdt: {percent: 2}", []string{"thresholds.symbols.binance:BTC_USDT"}},
+		{"negative", "BTC_USDT: {percent: -1}", []string{"thresholds.symbols.BTC_USDT.percent"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			config := "profiles:\n  default:\n    thresholds:\n      symbols:\n        " + tt.symbols + "\n"
+			if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var fields []string
+			err := ValidateFile(path)
+			for _, e := range unjoin(err) {
+				var fe *FieldError
+				if !errors.As(e, &fe) {
+					t.Fatalf("error %v is not a *FieldError", e)
+				}
+				fields = append(fields, strings.TrimPrefix(fe.Field, "profiles.default."))
+			}
+			if strings.Join(fields, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("errors on %v, want %v (%v)", fields, tt.want, err)
+			}
+		})
+	}
+}
+
+func unjoin(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, unjoin(e)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
+
+func TestThresholdsFor(t *testing.T) {
+	th := Thresholds{
+		Threshold: Threshold{Percent: 1},
+		Symbols: map[string]Threshold{
+			"btc_usdt":         {Percent: 2},
+			"BINANCE:BTC_USDT": {Percent: 3},
+		},
+	}
+	tests := []struct {
+		symbol string
+		want   float64
+	}{
+		{"BTC_USDT", 2},
+		{"binance:BTC_USDT", 3},
+		{"bybit:BTC_USDT", 2},
+		{"ETH_USDT", 1},
+	}
+	for _, tt := range tests {
+		if got := th.For(tt.symbol).Percent; got != tt.want {
+			t.Errorf("For(%q).Percent = %v, want %v", tt.symbol, got, tt.want)
+		}
+	}
+}
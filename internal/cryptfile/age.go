@@ -0,0 +1,107 @@
+package cryptfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+const (
+	ageIntro      = "age-encryption.org/v1\n"
+	ageArmorBegin = armor.Header
+
+	// ageMaxScryptLogN bounds the work a file can demand; age itself writes
+	// 18 by default.
+	ageMaxScryptLogN = 22
+)
+
+// decryptAge decrypts a binary or armored age file with the passphrase or
+// the identities in keys.
+func decryptAge(data []byte, keys Keys) ([]byte, error) {
+	var in io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(data, []byte(ageArmorBegin)) {
+		in = armor.NewReader(in)
+	}
+
+	id := &lazyIdentity{keys: keys}
+	r, err := age.Decrypt(in, id)
+	if err != nil {
+		if id.err != nil {
+			return nil, id.err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	return plaintext, nil
+}
+
+// lazyIdentity asks for the passphrase or reads the identity file only once
+// the header shows which one the file needs. Problems getting at the keys
+// are kept in err, so they aren't reported as a wrong key.
+type lazyIdentity struct {
+	keys Keys
+	err  error
+}
+
+func (l *lazyIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	if len(stanzas) == 1 && stanzas[0].Type == "scrypt" {
+		if l.keys.Passphrase == nil {
+			l.err = errors.New("cryptfile: file is passphrase-encrypted but no passphrase is available")
+			return nil, l.err
+		}
+		passphrase, err := l.keys.Passphrase()
+		if err != nil {
+			l.err = err
+			return nil, err
+		}
+		id, err := age.NewScryptIdentity(string(passphrase))
+		if err != nil {
+			l.err = fmt.Errorf("cryptfile: %w", err)
+			return nil, l.err
+		}
+		id.SetMaxWorkFactor(ageMaxScryptLogN)
+		return id.Unwrap(stanzas)
+	}
+
+	if l.keys.IdentityFile == "" {
+		l.err = errors.New("cryptfile: file is encrypted to a public key but no identity file is set")
+		return nil, l.err
+	}
+	identities, err := readAgeIdentities(l.keys.IdentityFile)
+	if err != nil {
+		l.err = err
+		return nil, err
+	}
+	for _, id := range identities {
+		fileKey, err := id.Unwrap(stanzas)
+		if errors.Is(err, age.ErrIncorrectIdentity) {
+			continue
+		}
+		return fileKey, err
+	}
+	return nil, age.ErrIncorrectIdentity
+}
+
+// readAgeIdentities reads the identities of an identity file, as written by
+// age-keygen.
+func readAgeIdentities(path string) ([]age.Identity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cryptfile: reading identity file: %w", err)
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("cryptfile: %s: %w", path, err)
+	}
+	return identities, nil
+}
@@ -0,0 +1,260 @@
+package cryptfile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// The files in testdata were made with the age CLI:
+//
+//	age-keygen -o key.txt
+//	age -r <public key> [-a] -o x25519.age[.asc] small.txt
+//	age -p [-a] -o passphrase.age[.asc] small.txt
+//	age -r <public key> -o x25519-large.age large.txt
+//	age -p -o passphrase-large.age large.txt
+//
+// with the passphrase below and small.txt and large.txt as returned by
+// smallPlaintext and largePlaintext.
+const testPassphrase = "correct horse battery staple"
+
+const testIdentityFile = "testdata/key.txt"
+
+func smallPlaintext() []byte {
+	return []byte("token: \"123:abc\"\nchat_id: \"42\"\n")
+}
+
+// largePlaintext spans four 64 KiB payload chunks, the last one partial.
+func largePlaintext() []byte {
+	var b bytes.Buffer
+	for i := 0; b.Len() < 200<<10+123; i++ {
+		fmt.Fprintf(&b, "line %06d of the multi-chunk test payload\n", i)
+	}
+	return b.Bytes()[:200<<10+123]
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func passphraseKeys(passphrase string) Keys {
+	return Keys{Passphrase: func() ([]byte, error) { return []byte(passphrase), nil }}
+}
+
+func TestDecryptAgeInterop(t *testing.T) {
+	tests := []struct {
+		file string
+		keys Keys
+		want []byte
+	}{
+		{"passphrase.age", passphraseKeys(testPassphrase), smallPlaintext()},
+		{"passphrase.age.asc", passphraseKeys(testPassphrase), smallPlaintext()},
+		{"passphrase-large.age", passphraseKeys(testPassphrase), largePlaintext()},
+		{"x25519.age", Keys{IdentityFile: testIdentityFile}, smallPlaintext()},
+		{"x25519.age.asc", Keys{IdentityFile: testIdentityFile}, smallPlaintext()},
+		{"x25519-large.age", Keys{IdentityFile: testIdentityFile}, largePlaintext()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data := readTestdata(t, tt.file)
+			if got := Detect(data); got != Age {
+				t.Fatalf("Detect = %v, want age", got)
+			}
+			got, err := Decrypt(context.Background(), data, tt.keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Decrypt returned %d bytes that differ from the %d byte plaintext", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestDecryptAgeRoundTrip(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	identityFile := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(identityFile, []byte("# test\n"+id.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, id.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(largePlaintext())
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Decrypt(context.Background(), buf.Bytes(), Keys{IdentityFile: identityFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, largePlaintext()) {
+		t.Error("round trip changed the plaintext")
+	}
+}
+
+func TestDecryptAgeWrongKey(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherIdentity := filepath.Join(t.TempDir(), "other.txt")
+	if err := os.WriteFile(otherIdentity, []byte(id.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		file string
+		keys Keys
+	}{
+		{"wrong passphrase", "passphrase.age", passphraseKeys("not the passphrase")},
+		{"wrong identity", "x25519.age", Keys{IdentityFile: otherIdentity}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decrypt(context.Background(), readTestdata(t, tt.file), tt.keys)
+			if !errors.Is(err, ErrDecrypt) {
+				t.Errorf("err = %v, want ErrDecrypt", err)
+			}
+		})
+	}
+}
+
+func TestDecryptAgeMissingKeys(t *testing.T) {
+	promptErr := errors.New("no terminal")
+	tests := []struct {
+		name string
+		file string
+		keys Keys
+		want error
+	}{
+		{"no passphrase", "passphrase.age", Keys{IdentityFile: testIdentityFile}, nil},
+		{"passphrase prompt fails", "passphrase.age", Keys{Passphrase: func() ([]byte, error) { return nil, promptErr }}, promptErr},
+		{"no identity file", "x25519.age", passphraseKeys(testPassphrase), nil},
+		{"missing identity file", "x25519.age", Keys{IdentityFile: "testdata/does-not-exist.txt"}, os.ErrNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decrypt(context.Background(), readTestdata(t, tt.file), tt.keys)
+			if err == nil {
+				t.Fatal("Decrypt succeeded")
+			}
+			if errors.Is(err, ErrDecrypt) {
+				t.Errorf("err = %v, which blames the key rather than its absence", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecryptAgeTampered(t *testing.T) {
+	// Offsets into x25519.age: its header is the intro line, one X25519
+	// stanza of an argument line and a body line, and the MAC line.
+	x25519 := readTestdata(t, "x25519.age")
+	headerEnd := bytes.Index(x25519, []byte("\n---")) + 1
+	payload := bytes.IndexByte(x25519[headerEnd:], '\n') + headerEnd + 1
+	stanzaBody := bytes.Index(x25519, []byte("\n-> ")) + 1
+	stanzaBody += bytes.IndexByte(x25519[stanzaBody:], '\n') + 1
+
+	flip := func(data []byte, i int) []byte {
+		data = bytes.Clone(data)
+		// Stay within the base64 alphabet so the header still parses.
+		if data[i] == 'A' {
+			data[i] = 'B'
+		} else {
+			data[i] = 'A'
+		}
+		return data
+	}
+	flipBit := func(data []byte, i int) []byte {
+		data = bytes.Clone(data)
+		data[i] ^= 1
+		return data
+	}
+
+	large := readTestdata(t, "x25519-large.age")
+	largeHeaderEnd := bytes.Index(large, []byte("\n---")) + 1
+	largePayload := bytes.IndexByte(large[largeHeaderEnd:], '\n') + largeHeaderEnd + 1
+
+	armored := readTestdata(t, "x25519.age.asc")
+	armoredBody := bytes.IndexByte(armored, '\n') + 1
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"stanza body", flip(x25519, stanzaBody+5)},
+		{"stanza argument", flip(x25519, stanzaBody-10)},
+		{"header MAC", flip(x25519, headerEnd+10)},
+		{"payload nonce", flipBit(x25519, payload+3)},
+		{"payload", flipBit(x25519, len(x25519)-5)},
+		{"middle chunk", flipBit(large, largePayload+16+(64<<10)+100)},
+		{"truncated to whole chunks", large[:largePayload+16+2*(64<<10+16)]},
+		{"truncated last chunk", large[:len(large)-1]},
+		{"appended data", append(bytes.Clone(x25519), 0)},
+		{"armor body", flip(armored, armoredBody+60)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decrypt(context.Background(), tt.data, Keys{IdentityFile: testIdentityFile})
+			if !errors.Is(err, ErrDecrypt) {
+				t.Errorf("err = %v, want ErrDecrypt", err)
+			}
+		})
+	}
+}
+
+func TestDecryptAgeTamperedPassphraseHeader(t *testing.T) {
+	data := bytes.Clone(readTestdata(t, "passphrase.age"))
+	// The scrypt stanza's body is the third line.
+	i := bytes.Index(data, []byte(" 18\n")) + 6
+	data[i] ^= 0x20 // swaps the case of a base64 letter
+
+	_, err := Decrypt(context.Background(), data, passphraseKeys(testPassphrase))
+	if !errors.Is(err, ErrDecrypt) {
+		t.Errorf("err = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want Format
+	}{
+		{[]byte("profiles:\n  default: {}\n"), Plain},
+		{nil, Plain},
+		{[]byte(ageIntro + "-> X25519 abc\n"), Age},
+		{[]byte(ageArmorBegin + "\nYWdl\n"), Age},
+		{[]byte("-----BEGIN PGP MESSAGE-----\n"), GPG},
+		{[]byte{0x8c, 0x0d, 0x04}, GPG}, // old-format symmetric session key packet
+		{[]byte{0xc3, 0x0d, 0x04}, GPG}, // new-format symmetric session key packet
+		{[]byte{0x85, 0x01}, GPG},       // old-format public key session key packet
+		{[]byte{0xc2, 0x01}, Plain},     // signature packet
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.data); got != tt.want {
+			t.Errorf("Detect(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
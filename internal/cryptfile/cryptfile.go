@@ -0,0 +1,121 @@
+// Package cryptfile decrypts files stored encrypted with age or GPG, so the
+// config can live in a dotfile repository without exposing its keys.
+//
+// age files, binary or armored, are decrypted in process with a passphrase
+// (age -p) or an X25519 identity file (age -r). GPG files are handed to the
+// gpg binary, which finds secret keys and prompts through its agent as usual.
+package cryptfile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Format is how a file is encrypted.
+type Format int
+
+const (
+	Plain Format = iota
+	Age
+	GPG
+)
+
+func (f Format) String() string {
+	switch f {
+	case Age:
+		return "age"
+	case GPG:
+		return "GPG"
+	}
+	return "plain"
+}
+
+// Detect tells from its first bytes how data is encrypted.
+func Detect(data []byte) Format {
+	switch {
+	case bytes.HasPrefix(data, []byte(ageIntro)), bytes.HasPrefix(data, []byte(ageArmorBegin)):
+		return Age
+	case bytes.HasPrefix(data, []byte("-----BEGIN PGP MESSAGE-----")):
+		return GPG
+	case len(data) > 0 && data[0]&0x80 != 0:
+		// A binary OpenPGP message starts with a session key packet: tag 1
+		// (public key) or 3 (passphrase), in the new or the old packet format.
+		tag := data[0] & 0x3f
+		if data[0]&0x40 == 0 {
+			tag = (data[0] >> 2) & 0x0f
+		}
+		if tag == 1 || tag == 3 {
+			return GPG
+		}
+	}
+	return Plain
+}
+
+// Keys supplies what decryption may need. Each is only consulted when the
+// file calls for it.
+type Keys struct {
+	// Passphrase returns the passphrase of an age file encrypted with one.
+	Passphrase func() ([]byte, error)
+	// IdentityFile is an age identity file, as written by age-keygen.
+	IdentityFile string
+	// GPGPassphrase, if set, is given to gpg for a passphrase-encrypted file
+	// or a protected secret key. Without it gpg asks through its agent.
+	GPGPassphrase []byte
+}
+
+// ErrDecrypt is returned when an age file can't be decrypted with the keys
+// given.
+var ErrDecrypt = errors.New("cryptfile: wrong passphrase or identity, or corrupted file")
+
+// Decrypt returns the plaintext of data, which is returned unchanged when it
+// isn't encrypted.
+func Decrypt(ctx context.Context, data []byte, keys Keys) ([]byte, error) {
+	switch Detect(data) {
+	case Age:
+		return decryptAge(data, keys)
+	case GPG:
+		return decryptGPG(ctx, data, keys.GPGPassphrase)
+	}
+	return data, nil
+}
+
+// decryptGPG runs gpg with data on stdin. A passphrase is passed on a pipe
+// rather than the command line, so it doesn't show up in the process list.
+func decryptGPG(ctx context.Context, data, passphrase []byte) ([]byte, error) {
+	args := []string{"--quiet", "--decrypt"}
+	if passphrase != nil {
+		args = append([]string{"--batch", "--pinentry-mode", "loopback", "--passphrase-fd", "3"}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "gpg", args...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if passphrase != nil {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		cmd.ExtraFiles = []*os.File{r}
+		go func() {
+			w.Write(passphrase)
+			w.Close()
+		}()
+	}
+	if err := cmd.Run(); err != nil {
+		// gpg's last line says why, already prefixed with "gpg: ".
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if msg := lines[len(lines)-1]; msg != "" {
+			return nil, fmt.Errorf("cryptfile: %s", msg)
+		}
+		return nil, fmt.Errorf("cryptfile: running gpg: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
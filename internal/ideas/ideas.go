@@ -0,0 +1,218 @@
+// Package ideas records trade theses and tracks whether price reached the
+// target or the invalidation level first.
+package ideas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Outcome is how an idea resolved.
+type Outcome string
+
+const (
+	Open        Outcome = "open"
+	HitTarget   Outcome = "target"
+	Invalidated Outcome = "invalidated"
+)
+
+// Idea is a trade thesis captured before (or instead of) entering.
+type Idea struct {
+	ID           int       `json:"id"`
+	Symbol       string    `json:"symbol"`
+	Direction    string    `json:"direction"` // "long" or "short"
+	Target       float64   `json:"target"`
+	Invalidation float64   `json:"invalidation"`
+	Thesis       string    `json:"thesis,omitempty"`
+	EntryPrice   float64   `json:"entry_price"` // fair price when captured
+	CreatedAt    time.Time `json:"created_at"`
+
+	Outcome    Outcome   `json:"outcome"`
+	ExitPrice  float64   `json:"exit_price,omitempty"`
+	ResolvedAt time.Time `json:"resolved_at,omitempty"`
+}
+
+// Validate checks that the levels are on the correct sides of the entry price.
+func (i Idea) Validate() error {
+	switch i.Direction {
+	case "long":
+		if !(i.Invalidation < i.EntryPrice && i.EntryPrice < i.Target) {
+			return fmt.Errorf("a long idea needs invalidation < current price (%f) < target", i.EntryPrice)
+		}
+	case "short":
+		if !(i.Target < i.EntryPrice && i.EntryPrice < i.Invalidation) {
+			return fmt.Errorf("a short idea needs target < current price (%f) < invalidation", i.EntryPrice)
+		}
+	default:
+		return fmt.Errorf("direction must be long or short, got %q", i.Direction)
+	}
+	return nil
+}
+
+// check returns the outcome implied by price, or Open if neither level was reached.
+func (i Idea) check(price float64) Outcome {
+	if i.Direction == "long" {
+		switch {
+		case price >= i.Target:
+			return HitTarget
+		case price <= i.Invalidation:
+			return Invalidated
+		}
+		return Open
+	}
+	switch {
+	case price <= i.Target:
+		return HitTarget
+	case price >= i.Invalidation:
+		return Invalidated
+	}
+	return Open
+}
+
+// Stats summarizes resolved ideas.
+type Stats struct {
+	Open, HitTarget, Invalidated int
+}
+
+// HitRate is the share of resolved ideas that reached their target.
+func (s Stats) HitRate() float64 {
+	resolved := s.HitTarget + s.Invalidated
+	if resolved == 0 {
+		return 0
+	}
+	return float64(s.HitTarget) / float64(resolved)
+}
+
+// Store keeps ideas in a JSON file. It is safe for concurrent use.
+type Store struct {
+	path string
+
+	mu    sync.Mutex
+	ideas []Idea
+}
+
+// OpenStore loads the ideas saved at path; a missing file is an empty store.
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading ideas: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.ideas); err != nil {
+		return nil, fmt.Errorf("parsing ideas %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Add validates and saves idea, assigning its ID.
+func (s *Store) Add(idea Idea) (Idea, error) {
+	if err := idea.Validate(); err != nil {
+		return Idea{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idea.ID = 1
+	if n := len(s.ideas); n > 0 {
+		idea.ID = s.ideas[n-1].ID + 1
+	}
+	idea.Outcome = Open
+	s.ideas = append(s.ideas, idea)
+	if err := s.save(); err != nil {
+		s.ideas = s.ideas[:len(s.ideas)-1]
+		return Idea{}, err
+	}
+	return idea, nil
+}
+
+// List returns a copy of all ideas, oldest first.
+func (s *Store) List() []Idea {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Idea(nil), s.ideas...)
+}
+
+// OpenSymbols returns the distinct symbols with unresolved ideas.
+func (s *Store) OpenSymbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, idea := range s.ideas {
+		if idea.Outcome == Open && !seen[idea.Symbol] {
+			seen[idea.Symbol] = true
+			symbols = append(symbols, idea.Symbol)
+		}
+	}
+	return symbols
+}
+
+// Update resolves open ideas on symbol against price and returns the ones
+// that resolved.
+func (s *Store) Update(symbol string, price float64, at time.Time) ([]Idea, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var resolved []Idea
+	for i := range s.ideas {
+		idea := &s.ideas[i]
+		if idea.Outcome != Open || idea.Symbol != symbol {
+			continue
+		}
+		if outcome := idea.check(price); outcome != Open {
+			idea.Outcome = outcome
+			idea.ExitPrice = price
+			idea.ResolvedAt = at
+			resolved = append(resolved, *idea)
+		}
+	}
+	if len(resolved) == 0 {
+		return nil, nil
+	}
+	return resolved, s.save()
+}
+
+// Stats counts ideas by outcome.
+func (s *Store) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var st Stats
+	for _, idea := range s.ideas {
+		switch idea.Outcome {
+		case Open:
+			st.Open++
+		case HitTarget:
+			st.HitTarget++
+		case Invalidated:
+			st.Invalidated++
+		}
+	}
+	return st
+}
+
+// save writes the store atomically. The caller must hold s.mu.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.ideas, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("saving ideas: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("saving ideas: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package ideas
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var created = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func long(symbol string, entry, target, invalidation float64) Idea {
+	return Idea{Symbol: symbol, Direction: "long", EntryPrice: entry, Target: target, Invalidation: invalidation, CreatedAt: created}
+}
+
+func short(symbol string, entry, target, invalidation float64) Idea {
+	return Idea{Symbol: symbol, Direction: "short", EntryPrice: entry, Target: target, Invalidation: invalidation, CreatedAt: created}
+}
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := OpenStore(filepath.Join(t.TempDir(), "ideas.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		idea Idea
+		want string // in the error; empty for valid
+	}{
+		{"long", long("BTC_USDT", 60000, 70000, 58000), ""},
+		{"short", short("BTC_USDT", 60000, 50000, 62000), ""},
+		{"long target below entry", long("BTC_USDT", 60000, 59000, 58000), "a long idea needs"},
+		{"long invalidation above entry", long("BTC_USDT", 60000, 70000, 61000), "a long idea needs"},
+		{"long target at entry", long("BTC_USDT", 60000, 60000, 58000), "a long idea needs"},
+		{"short with long levels", short("BTC_USDT", 60000, 70000, 58000), "a short idea needs"},
+		{"short invalidation at entry", short("BTC_USDT", 60000, 50000, 60000), "a short idea needs"},
+		{"direction", Idea{Direction: "sideways", EntryPrice: 1}, `direction must be long or short, got "sideways"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.idea.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	l := long("BTC_USDT", 60000, 70000, 58000)
+	s := short("BTC_USDT", 60000, 50000, 62000)
+	tests := []struct {
+		idea  Idea
+		price float64
+		want  Outcome
+	}{
+		{l, 60000, Open},
+		{l, 69999, Open},
+		{l, 70000, HitTarget}, // reaching a level is enough
+		{l, 75000, HitTarget},
+		{l, 58001, Open},
+		{l, 58000, Invalidated},
+		{l, 40000, Invalidated},
+		{s, 60000, Open},
+		{s, 50000, HitTarget},
+		{s, 45000, HitTarget},
+		{s, 61999, Open},
+		{s, 62000, Invalidated},
+	}
+	for _, tt := range tests {
+		if got := tt.idea.check(tt.price); got != tt.want {
+			t.Errorf("%s idea at %v = %s, want %s", tt.idea.Direction, tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestStoreUpdate(t *testing.T) {
+	s := openTestStore(t)
+	for _, idea := range []Idea{
+		long("BTC_USDT", 60000, 70000, 58000),
+		short("BTC_USDT", 60000, 50000, 71000),
+		long("ETH_USDT", 3000, 3500, 2800),
+		long("BTC_USDT", 60000, 65000, 55000),
+	} {
+		if _, err := s.Add(idea); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Join(s.OpenSymbols(), ","); got != "BTC_USDT,ETH_USDT" {
+		t.Errorf("OpenSymbols = %s, want each symbol once, in order of capture", got)
+	}
+
+	// A BTC price only resolves BTC ideas, and only those whose level it
+	// reached.
+	at := created.Add(2 * time.Hour)
+	resolved, err := s.Update("BTC_USDT", 70000, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 2 || resolved[0].ID != 1 || resolved[1].ID != 4 {
+		t.Fatalf("resolved = %+v, want ideas 1 and 4", resolved)
+	}
+	for _, idea := range resolved {
+		if idea.Outcome != HitTarget || idea.ExitPrice != 70000 || !idea.ResolvedAt.Equal(at) {
+			t.Errorf("idea #%d = %s at %v on %v, want the target hit at 70000", idea.ID, idea.Outcome, idea.ExitPrice, idea.ResolvedAt)
+		}
+	}
+
+	// Resolved ideas don't resolve again, even the other way.
+	resolved, err = s.Update("BTC_USDT", 71000, at.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 1 || resolved[0].ID != 2 || resolved[0].Outcome != Invalidated {
+		t.Errorf("resolved = %+v, want only idea 2, invalidated", resolved)
+	}
+	if resolved, _ := s.Update("ETH_USDT", 3200, at); resolved != nil {
+		t.Errorf("resolved = %+v for a price between the levels", resolved)
+	}
+
+	st := s.Stats()
+	if st != (Stats{Open: 1, HitTarget: 2, Invalidated: 1}) {
+		t.Errorf("Stats = %+v", st)
+	}
+	if st.HitRate() != 2.0/3 {
+		t.Errorf("HitRate = %v, want 2/3 of the resolved ideas", st.HitRate())
+	}
+	if got := strings.Join(s.OpenSymbols(), ","); got != "ETH_USDT" {
+		t.Errorf("OpenSymbols = %s, want ETH_USDT", got)
+	}
+}
+
+func TestHitRateNoneResolved(t *testing.T) {
+	if got := (Stats{Open: 3}).HitRate(); got != 0 {
+		t.Errorf("HitRate = %v, want 0", got)
+	}
+}
+
+func TestStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ideas.json")
+	s, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(long("BTC_USDT", 60000, 70000, 58000)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(long("BTC_USDT", 60000, 50000, 58000)); err == nil {
+		t.Error("Add saved an invalid idea")
+	}
+	if _, err := s.Update("BTC_USDT", 57000, created.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := reopened.List()
+	if len(list) != 1 || list[0].Outcome != Invalidated || list[0].ExitPrice != 57000 {
+		t.Fatalf("reopened = %+v, want the invalidated idea", list)
+	}
+	// IDs continue after the saved ones.
+	idea, err := reopened.Add(short("ETH_USDT", 3000, 2500, 3200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idea.ID != 2 || idea.Outcome != Open {
+		t.Errorf("added idea #%d %s, want #2 open", idea.ID, idea.Outcome)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenStore(path); err == nil || !strings.Contains(err.Error(), "parsing ideas") {
+		t.Errorf("OpenStore(corrupt file) err = %v", err)
+	}
+}
@@ -0,0 +1,460 @@
+// Package storage keeps the bot's history in a SQLite database: fair price
+// samples, position snapshots, the alerts that were sent and whether they
+// resolved, and the alert state needed to avoid repeating them after a
+// restart.
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/alert"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+const (
+	// priceSampleInterval is the minimum spacing of stored fair prices per
+	// symbol; streamed prices arrive far more often than history needs.
+	priceSampleInterval = time.Minute
+	// pruneInterval is how often rows older than the retention are removed.
+	pruneInterval = time.Hour
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS price_samples (
+	symbol TEXT NOT NULL,
+	time   INTEGER NOT NULL,
+	price  REAL NOT NULL
+);
+CREATE INDEX IF NOT EXISTS price_samples_symbol_time ON price_samples (symbol, time);
+
+CREATE TABLE IF NOT EXISTS position_snapshots (
+	time           INTEGER NOT NULL,
+	position_id    INTEGER NOT NULL,
+	symbol         TEXT NOT NULL,
+	position_type  INTEGER NOT NULL,
+	hold_vol       REAL NOT NULL,
+	hold_avg_price REAL NOT NULL,
+	leverage       INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS position_snapshots_position_time ON position_snapshots (position_id, time);
+
+CREATE TABLE IF NOT EXISTS alert_events (
+	time        INTEGER NOT NULL,
+	kind        TEXT NOT NULL,
+	symbol      TEXT NOT NULL,
+	position_id INTEGER NOT NULL,
+	fair_price  REAL NOT NULL,
+	alert_key   TEXT NOT NULL DEFAULT '',
+	resolved_at    INTEGER NOT NULL DEFAULT 0,
+	resolution     TEXT NOT NULL DEFAULT '',
+	resolved_after INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS alert_events_time ON alert_events (time);
+
+CREATE TABLE IF NOT EXISTS alert_state (
+	key        TEXT PRIMARY KEY,
+	armed      INTEGER NOT NULL,
+	last_fired INTEGER NOT NULL
+);
+`
+
+// addedColumns are columns added to a table after it was first released.
+// Open adds them to databases created before.
+var addedColumns = []struct{ table, column, definition string }{
+	{"alert_events", "alert_key", "TEXT NOT NULL DEFAULT ''"},
+	{"alert_events", "resolved_at", "INTEGER NOT NULL DEFAULT 0"},
+	{"alert_events", "resolution", "TEXT NOT NULL DEFAULT ''"},
+	{"alert_events", "resolved_after", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// indexes are created after addedColumns, as they may cover those columns.
+const indexes = `
+CREATE INDEX IF NOT EXISTS alert_events_key ON alert_events (alert_key, resolved_at);
+`
+
+// Resolutions recorded by ResolveAlerts.
+const (
+	// ResolutionCleared means the alert's condition went away.
+	ResolutionCleared = "cleared"
+	// ResolutionClosed means the position the alert was about was closed.
+	ResolutionClosed = "closed"
+	// ResolutionChanged means the entry price or size of the position the
+	// alert was about changed, which starts a new alert.
+	ResolutionChanged = "changed"
+)
+
+// snapshotKey identifies a position across refreshes.
+type snapshotKey struct {
+	positionID   int64
+	symbol       string
+	positionType int
+}
+
+// snapshot is the part of a position worth recording when it changes.
+type snapshot struct {
+	holdVol      float64
+	holdAvgPrice float64
+	leverage     int
+}
+
+// DB is an open history database. It is safe for concurrent use.
+type DB struct {
+	db        *sql.DB
+	retention time.Duration
+
+	mu         sync.Mutex
+	lastSample map[string]time.Time
+	lastSnap   map[snapshotKey]snapshot
+	lastPrune  time.Time
+}
+
+// Open opens or creates the database at path. Rows older than retention are
+// pruned as new ones are written; zero keeps everything.
+func Open(path string, retention time.Duration) (*DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	// SQLite allows one writer at a time; a single connection avoids
+	// "database is locked" errors between our own goroutines.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating schema in %s: %w", path, err)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("upgrading schema in %s: %w", path, err)
+	}
+
+	d := &DB{
+		db:         db,
+		retention:  retention,
+		lastSample: make(map[string]time.Time),
+		lastSnap:   make(map[snapshotKey]snapshot),
+	}
+	if err := d.prune(time.Now()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// migrate adds the missing addedColumns and creates the indexes.
+func migrate(db *sql.DB) error {
+	for _, c := range addedColumns {
+		var exists bool
+		err := db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE ` + c.table + ` ADD COLUMN ` + c.column + ` ` + c.definition); err != nil {
+			return fmt.Errorf("adding %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	_, err := db.Exec(indexes)
+	return err
+}
+
+// Close closes the database.
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// RecordPrice stores a fair price sample, at most one per symbol per minute.
+func (d *DB) RecordPrice(symbol string, price float64, at time.Time) error {
+	d.mu.Lock()
+	if last, ok := d.lastSample[symbol]; ok && at.Sub(last) < priceSampleInterval {
+		d.mu.Unlock()
+		return nil
+	}
+	d.lastSample[symbol] = at
+	d.mu.Unlock()
+
+	if _, err := d.db.Exec(`INSERT INTO price_samples (symbol, time, price) VALUES (?, ?, ?)`, symbol, at.UnixMilli(), price); err != nil {
+		return err
+	}
+	return d.prune(at)
+}
+
+// PriceAt returns the last price sampled for symbol at or before at, as long
+// as it is no older than at minus tolerance.
+func (d *DB) PriceAt(symbol string, at time.Time, tolerance time.Duration) (price float64, sampledAt time.Time, ok bool, err error) {
+	var ms int64
+	err = d.db.QueryRow(
+		`SELECT price, time FROM price_samples WHERE symbol = ? AND time <= ? AND time >= ? ORDER BY time DESC LIMIT 1`,
+		symbol, at.UnixMilli(), at.Add(-tolerance).UnixMilli(),
+	).Scan(&price, &ms)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, time.Time{}, false, nil
+	}
+	if err != nil {
+		return 0, time.Time{}, false, err
+	}
+	return price, time.UnixMilli(ms), true, nil
+}
+
+// PriceSample is a fair price stored by RecordPrice.
+type PriceSample struct {
+	Time  time.Time `json:"time"`
+	Price float64   `json:"price"`
+}
+
+// Prices returns the prices sampled for symbol at or after since, oldest
+// first.
+func (d *DB) Prices(symbol string, since time.Time) ([]PriceSample, error) {
+	rows, err := d.db.Query(
+		`SELECT time, price FROM price_samples WHERE symbol = ? AND time >= ? ORDER BY time`,
+		symbol, since.UnixMilli(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []PriceSample
+	for rows.Next() {
+		var s PriceSample
+		var ms int64
+		if err := rows.Scan(&ms, &s.Price); err != nil {
+			return nil, err
+		}
+		s.Time = time.UnixMilli(ms)
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
+
+// RecordPositions stores a snapshot of every position whose size, entry or
+// leverage changed since it was last recorded.
+func (d *DB) RecordPositions(positions []mexc.Position, at time.Time) error {
+	d.mu.Lock()
+	var changed []mexc.Position
+	for _, pos := range positions {
+		s := snapshot{holdVol: pos.HoldVol, holdAvgPrice: pos.HoldAvgPrice, leverage: pos.Leverage}
+		key := snapshotKey{positionID: pos.PositionID, symbol: pos.Symbol, positionType: pos.PositionType}
+		if last, ok := d.lastSnap[key]; ok && last == s {
+			continue
+		}
+		d.lastSnap[key] = s
+		changed = append(changed, pos)
+	}
+	d.mu.Unlock()
+
+	for _, pos := range changed {
+		_, err := d.db.Exec(
+			`INSERT INTO position_snapshots (time, position_id, symbol, position_type, hold_vol, hold_avg_price, leverage) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			at.UnixMilli(), pos.PositionID, pos.Symbol, pos.PositionType, pos.HoldVol, pos.HoldAvgPrice, pos.Leverage,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordAlert stores an alert that was sent. kind names the kind of event,
+// e.g. "updated" or "closed"; symbol may be an underlying for alerts that
+// span contracts. alertKey names the alert policy key of alerts that can
+// resolve, and is empty for one-off events such as fills.
+func (d *DB) RecordAlert(kind, symbol, alertKey string, positionID int64, fairPrice float64, at time.Time) error {
+	_, err := d.db.Exec(
+		`INSERT INTO alert_events (time, kind, symbol, position_id, fair_price, alert_key) VALUES (?, ?, ?, ?, ?, ?)`,
+		at.UnixMilli(), kind, symbol, positionID, fairPrice, alertKey,
+	)
+	return err
+}
+
+// ResolveAlerts marks every unresolved alert recorded with alertKey as
+// resolved at at, for the given reason: ResolutionCleared, ResolutionClosed
+// or ResolutionChanged. It returns when the first of them was sent, and records
+// the time from then to at as their time to resolution; ok is false when
+// there was nothing to resolve.
+func (d *DB) ResolveAlerts(alertKey, resolution string, at time.Time) (firstSent time.Time, ok bool, err error) {
+	var ms sql.NullInt64
+	err = d.db.QueryRow(`SELECT MIN(time) FROM alert_events WHERE alert_key = ? AND resolved_at = 0`, alertKey).Scan(&ms)
+	if err != nil || !ms.Valid {
+		return time.Time{}, false, err
+	}
+	_, err = d.db.Exec(
+		`UPDATE alert_events SET resolved_at = ?, resolution = ?, resolved_after = ? WHERE alert_key = ? AND resolved_at = 0`,
+		at.UnixMilli(), resolution, at.UnixMilli()-ms.Int64, alertKey,
+	)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.UnixMilli(ms.Int64), true, nil
+}
+
+// AlertEvent is an alert stored by RecordAlert.
+type AlertEvent struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"`
+	Symbol     string    `json:"symbol"`
+	PositionID int64     `json:"position_id"`
+	FairPrice  float64   `json:"fair_price"`
+	// AlertKey is empty for events that don't resolve.
+	AlertKey string `json:"alert_key,omitempty"`
+	// ResolvedAt and Resolution are set once ResolveAlerts resolved the
+	// alert. ResolvedAfterSeconds is how long the condition lasted, from the
+	// first alert about it, which may be earlier than this one when alerts
+	// repeat.
+	ResolvedAt           *time.Time `json:"resolved_at,omitempty"`
+	Resolution           string     `json:"resolution,omitempty"`
+	ResolvedAfterSeconds float64    `json:"resolved_after_seconds,omitempty"`
+}
+
+// ResolvedAfter is ResolvedAfterSeconds as a duration.
+func (e AlertEvent) ResolvedAfter() time.Duration {
+	return time.Duration(e.ResolvedAfterSeconds * float64(time.Second))
+}
+
+// Active reports whether the alert's condition may still hold: it can
+// resolve and hasn't.
+func (e AlertEvent) Active() bool {
+	return e.AlertKey != "" && e.ResolvedAt == nil
+}
+
+const alertColumns = `time, kind, symbol, position_id, fair_price, alert_key, resolved_at, resolution, resolved_after`
+
+// Alerts returns up to limit alerts sent at or after since, newest first.
+func (d *DB) Alerts(since time.Time, limit int) ([]AlertEvent, error) {
+	rows, err := d.db.Query(
+		`SELECT `+alertColumns+` FROM alert_events WHERE time >= ? ORDER BY time DESC LIMIT ?`,
+		since.UnixMilli(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanAlerts(rows)
+}
+
+// AlertHistory returns limit alerts starting offset alerts from the newest,
+// and how many alerts are stored in all, for paging through the history.
+func (d *DB) AlertHistory(offset, limit int) (events []AlertEvent, total int, err error) {
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM alert_events`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := d.db.Query(
+		`SELECT `+alertColumns+` FROM alert_events ORDER BY time DESC, rowid DESC LIMIT ? OFFSET ?`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	events, err = scanAlerts(rows)
+	return events, total, err
+}
+
+func scanAlerts(rows *sql.Rows) ([]AlertEvent, error) {
+	defer rows.Close()
+
+	var events []AlertEvent
+	for rows.Next() {
+		var e AlertEvent
+		var ms, resolvedMs, afterMs int64
+		if err := rows.Scan(&ms, &e.Kind, &e.Symbol, &e.PositionID, &e.FairPrice, &e.AlertKey, &resolvedMs, &e.Resolution, &afterMs); err != nil {
+			return nil, err
+		}
+		e.Time = time.UnixMilli(ms)
+		if resolvedMs != 0 {
+			resolvedAt := time.UnixMilli(resolvedMs)
+			e.ResolvedAt = &resolvedAt
+			// Alerts resolved before resolved_after was recorded count
+			// from themselves.
+			if afterMs == 0 {
+				afterMs = resolvedMs - ms
+			}
+			e.ResolvedAfterSeconds = float64(afterMs) / 1000
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// LoadAlertStates implements alert.Store.
+func (d *DB) LoadAlertStates() (map[string]alert.State, error) {
+	rows, err := d.db.Query(`SELECT key, armed, last_fired FROM alert_state`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]alert.State)
+	for rows.Next() {
+		var key string
+		var armed bool
+		var ms int64
+		if err := rows.Scan(&key, &armed, &ms); err != nil {
+			return nil, err
+		}
+		states[key] = alert.State{Armed: armed, LastFired: time.UnixMilli(ms)}
+	}
+	return states, rows.Err()
+}
+
+// SaveAlertState implements alert.Store.
+func (d *DB) SaveAlertState(key string, s alert.State) error {
+	_, err := d.db.Exec(
+		`INSERT INTO alert_state (key, armed, last_fired) VALUES (?, ?, ?)
+		 ON CONFLICT (key) DO UPDATE SET armed = excluded.armed, last_fired = excluded.last_fired`,
+		key, s.Armed, s.LastFired.UnixMilli(),
+	)
+	return err
+}
+
+// DeleteAlertState implements alert.Store.
+func (d *DB) DeleteAlertState(key string) error {
+	_, err := d.db.Exec(`DELETE FROM alert_state WHERE key = ?`, key)
+	return err
+}
+
+// Snapshot returns a consistent copy of the whole database file, safe to take
+// while the bot is writing to it.
+func (d *DB) Snapshot() ([]byte, error) {
+	tmp, err := os.CreateTemp("", "bot-history-*.db")
+	if err != nil {
+		return nil, err
+	}
+	path := tmp.Name()
+	tmp.Close()
+	defer os.Remove(path)
+
+	if _, err := d.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return nil, fmt.Errorf("copying database: %w", err)
+	}
+	return os.ReadFile(path)
+}
+
+// prune removes history older than the retention, at most once per
+// pruneInterval. Alert state is kept; the monitor drops stale keys itself.
+func (d *DB) prune(now time.Time) error {
+	if d.retention <= 0 {
+		return nil
+	}
+	d.mu.Lock()
+	if now.Sub(d.lastPrune) < pruneInterval {
+		d.mu.Unlock()
+		return nil
+	}
+	d.lastPrune = now
+	d.mu.Unlock()
+
+	cutoff := now.Add(-d.retention).UnixMilli()
+	for _, table := range []string{"price_samples", "position_snapshots", "alert_events"} {
+		if _, err := d.db.Exec(`DELETE FROM `+table+` WHERE time < ?`, cutoff); err != nil {
+			return fmt.Errorf("pruning %s: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package storage
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/alert"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+var t0 = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func openTest(t *testing.T, retention time.Duration) *DB {
+	t.Helper()
+	d, err := Open(filepath.Join(t.TempDir(), "bot.db"), retention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func count(t *testing.T, d *DB, table string) int {
+	t.Helper()
+	var n int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.db")
+	old, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// alert_events as first released, before alert_key and the resolution
+	// columns.
+	_, err = old.Exec(`
+		CREATE TABLE alert_events (
+			time        INTEGER NOT NULL,
+			kind        TEXT NOT NULL,
+			symbol      TEXT NOT NULL,
+			position_id INTEGER NOT NULL,
+			fair_price  REAL NOT NULL
+		);
+		INSERT INTO alert_events VALUES (1709294400000, 'updated', 'BTC_USDT', 7, 60000);
+	`)
+	old.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	for _, c := range addedColumns {
+		var exists bool
+		if err := d.db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column).Scan(&exists); err != nil || !exists {
+			t.Errorf("%s.%s missing after Open: %v", c.table, c.column, err)
+		}
+	}
+	var index int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'alert_events_key'`).Scan(&index); err != nil || index != 1 {
+		t.Errorf("alert_events_key index missing: %v", err)
+	}
+
+	events, total, err := d.AlertHistory(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(events) != 1 {
+		t.Fatalf("got %d of %d events, want the old one", len(events), total)
+	}
+	if e := events[0]; e.Symbol != "BTC_USDT" || e.PositionID != 7 || e.AlertKey != "" || e.Active() {
+		t.Errorf("old event = %+v, want it kept without a key", e)
+	}
+
+	// Opening the upgraded database again is a no-op.
+	d.Close()
+	again, err := Open(path, 0)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	again.Close()
+}
+
+func TestResolveAlerts(t *testing.T) {
+	d := openTest(t, 0)
+	for _, a := range []struct {
+		key string
+		at  time.Duration
+	}{
+		{"div:1", 0},
+		{"div:1", 10 * time.Minute}, // a repeat
+		{"div:2", 5 * time.Minute},
+		{"", 6 * time.Minute}, // a fill, which never resolves
+	} {
+		if err := d.RecordAlert("updated", "BTC_USDT", a.key, 1, 60000, t0.Add(a.at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, ok, err := d.ResolveAlerts("div:1", ResolutionCleared, t0.Add(30*time.Minute))
+	if err != nil || !ok || !first.Equal(t0) {
+		t.Fatalf("ResolveAlerts = %v, %t, %v, want the first alert's time", first, ok, err)
+	}
+	// Already resolved alerts aren't resolved again.
+	if _, ok, err := d.ResolveAlerts("div:1", ResolutionClosed, t0.Add(time.Hour)); ok || err != nil {
+		t.Errorf("second ResolveAlerts = %t, %v, want nothing to resolve", ok, err)
+	}
+	if _, ok, err := d.ResolveAlerts("div:9", ResolutionCleared, t0); ok || err != nil {
+		t.Errorf("ResolveAlerts of an unknown key = %t, %v", ok, err)
+	}
+
+	events, _, err := d.AlertHistory(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byTime := make(map[time.Duration]AlertEvent)
+	for _, e := range events {
+		byTime[e.Time.Sub(t0)] = e
+	}
+	for _, at := range []time.Duration{0, 10 * time.Minute} {
+		e := byTime[at]
+		if e.ResolvedAt == nil || !e.ResolvedAt.Equal(t0.Add(30*time.Minute)) || e.Resolution != ResolutionCleared {
+			t.Errorf("alert at %s = %+v, want resolved as cleared at 30m", at, e)
+		}
+		// Both count from the first alert about the condition.
+		if e.ResolvedAfter() != 30*time.Minute {
+			t.Errorf("alert at %s resolved after %s, want 30m", at, e.ResolvedAfter())
+		}
+	}
+	if e := byTime[5*time.Minute]; !e.Active() {
+		t.Errorf("div:2 = %+v, want it still active", e)
+	}
+	if e := byTime[6*time.Minute]; e.Active() || e.ResolvedAt != nil {
+		t.Errorf("fill = %+v, want neither active nor resolved", e)
+	}
+}
+
+// Alerts resolved before resolved_after was stored count from themselves.
+func TestResolvedAfterFallback(t *testing.T) {
+	d := openTest(t, 0)
+	_, err := d.db.Exec(
+		`INSERT INTO alert_events (time, kind, symbol, position_id, fair_price, alert_key, resolved_at, resolution) VALUES (?, 'updated', 'BTC_USDT', 1, 60000, 'div:1', ?, 'cleared')`,
+		t0.UnixMilli(), t0.Add(90*time.Second).UnixMilli(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := d.Alerts(t0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].ResolvedAfter() != 90*time.Second {
+		t.Errorf("events = %+v, want one resolved after 90s", events)
+	}
+}
+
+func TestAlertHistoryPaging(t *testing.T) {
+	d := openTest(t, 0)
+	for i := 0; i < 5; i++ {
+		if err := d.RecordAlert("updated", "BTC_USDT", "", int64(i), 60000, t0.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Two alerts in the same millisecond still page in a stable order.
+	if err := d.RecordAlert("closed", "BTC_USDT", "", 5, 60000, t0.Add(4*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		offset, limit int
+		want          []int64 // position IDs, newest first
+	}{
+		{0, 2, []int64{5, 4}},
+		{2, 2, []int64{3, 2}},
+		{4, 2, []int64{1, 0}},
+		{5, 10, []int64{0}},
+		{6, 2, nil},
+	}
+	for _, tt := range tests {
+		events, total, err := d.AlertHistory(tt.offset, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 6 {
+			t.Errorf("AlertHistory(%d, %d) total = %d, want 6", tt.offset, tt.limit, total)
+		}
+		var got []int64
+		for _, e := range events {
+			got = append(got, e.PositionID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("AlertHistory(%d, %d) = %v, want %v", tt.offset, tt.limit, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("AlertHistory(%d, %d) = %v, want %v", tt.offset, tt.limit, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestRecordPrice(t *testing.T) {
+	d := openTest(t, 0)
+	for _, s := range []struct {
+		symbol string
+		at     time.Duration
+		price  float64
+	}{
+		{"BTC_USDT", 0, 60000},
+		{"BTC_USDT", 30 * time.Second, 60010}, // inside the minute: dropped
+		{"ETH_USDT", 30 * time.Second, 3000},  // other symbols are separate
+		{"BTC_USDT", time.Minute, 60020},      // exactly a minute later: kept
+		{"BTC_USDT", 90 * time.Second, 60030}, // inside the minute of the 1m sample
+		{"BTC_USDT", 2 * time.Minute, 60040},
+	} {
+		if err := d.RecordPrice(s.symbol, s.price, t0.Add(s.at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	samples, err := d.Prices("BTC_USDT", t0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{60000, 60020, 60040}
+	if len(samples) != len(want) {
+		t.Fatalf("samples = %+v, want prices %v", samples, want)
+	}
+	for i, s := range samples {
+		if s.Price != want[i] {
+			t.Errorf("samples[%d] = %+v, want price %v", i, s, want[i])
+		}
+	}
+
+	price, at, ok, err := d.PriceAt("BTC_USDT", t0.Add(100*time.Second), time.Minute)
+	if err != nil || !ok || price != 60020 || !at.Equal(t0.Add(time.Minute)) {
+		t.Errorf("PriceAt = %v at %v, %t, %v, want 60020 at 1m", price, at, ok, err)
+	}
+	if _, _, ok, err := d.PriceAt("BTC_USDT", t0.Add(10*time.Minute), time.Minute); ok || err != nil {
+		t.Errorf("PriceAt beyond the tolerance = %t, %v", ok, err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	d := openTest(t, 24*time.Hour)
+	// The prune on Open counts as the last one.
+	d.mu.Lock()
+	d.lastPrune = t0
+	d.mu.Unlock()
+
+	pos := mexc.Position{PositionID: 1, Symbol: "BTC_USDT", PositionType: mexc.PositionTypeLong, HoldVol: 10, HoldAvgPrice: 60000, Leverage: 10}
+	if err := d.RecordPositions([]mexc.Position{pos}, t0); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RecordAlert("updated", "BTC_USDT", "div:1", 1, 60000, t0); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SaveAlertState("div:1", alert.State{LastFired: t0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RecordPrice("BTC_USDT", 60000, t0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Within pruneInterval of the last prune nothing is removed, even when
+	// past the retention.
+	if err := d.RecordPrice("BTC_USDT", 60100, t0.Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, d, "price_samples"); n != 2 {
+		t.Fatalf("%d price samples, want 2", n)
+	}
+
+	if err := d.RecordPrice("BTC_USDT", 61000, t0.Add(24*time.Hour+15*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	for table, want := range map[string]int{
+		"price_samples":      2, // the 30m sample is inside the retention
+		"position_snapshots": 0,
+		"alert_events":       0,
+		"alert_state":        1, // kept; the monitor drops stale keys itself
+	} {
+		if n := count(t, d, table); n != want {
+			t.Errorf("%s has %d rows after pruning, want %d", table, n, want)
+		}
+	}
+}
+
+func TestPruneDisabled(t *testing.T) {
+	d := openTest(t, 0)
+	if err := d.RecordPrice("BTC_USDT", 60000, t0); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RecordPrice("BTC_USDT", 60000, t0.Add(365*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, d, "price_samples"); n != 2 {
+		t.Errorf("%d price samples, want both kept without a retention", n)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	d := openTest(t, 0)
+	if err := d.RecordAlert("updated", "BTC_USDT", "div:1", 1, 60000, t0); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SaveAlertState("div:1", alert.State{Armed: false, LastFired: t0}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := d.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Writes after the snapshot aren't in it.
+	if err := d.RecordAlert("updated", "ETH_USDT", "div:2", 2, 3000, t0.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "restored.db")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	events, total, err := restored.AlertHistory(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || events[0].Symbol != "BTC_USDT" || !events[0].Active() {
+		t.Errorf("restored events = %+v, want the one alert before the snapshot", events)
+	}
+	states, err := restored.LoadAlertStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := states["div:1"]; !ok || s.Armed || !s.LastFired.Equal(t0) {
+		t.Errorf("restored states = %+v", states)
+	}
+}
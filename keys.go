@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+
+	"github.com/killabayte/golang-telegram-bot/internal/cryptfile"
+)
+
+// promptKeys reads the API key pair from stdin. The secret is read without
+// echo when stdin is a terminal and is only ever held in memory; callers
+// should wipe it with zeroBytes once they are done.
+func promptKeys() (string, []byte, error) {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Fprint(os.Stderr, "MEXC access key: ")
+	accessKey, err := reader.ReadString('\n')
+	if err != nil {
+		return "", nil, fmt.Errorf("reading access key: %w", err)
+	}
+
+	fmt.Fprint(os.Stderr, "MEXC secret key: ")
+	var secretKey []byte
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		secretKey, err = term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+	} else {
+		secretKey, err = reader.ReadBytes('\n')
+	}
+	if err != nil {
+		zeroBytes(secretKey)
+		return "", nil, fmt.Errorf("reading secret key: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(secretKey)
+	secret := make([]byte, len(trimmed))
+	copy(secret, trimmed)
+	zeroBytes(secretKey)
+
+	return string(bytes.TrimSpace([]byte(accessKey))), secret, nil
+}
+
+// zeroBytes overwrites b in place so key material doesn't linger in memory.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// configDecryption returns the keys for reading an encrypted config. The
+// passphrase comes from BOT_CONFIG_PASSPHRASE or is asked for once, when a
+// passphrase-encrypted file is first read; validate reads the file twice.
+func configDecryption(identityFile string) cryptfile.Keys {
+	var passphrase []byte
+	keys := cryptfile.Keys{
+		IdentityFile: identityFile,
+		Passphrase: func() ([]byte, error) {
+			if passphrase != nil {
+				return passphrase, nil
+			}
+			p, err := promptPassphrase("BOT_CONFIG_PASSPHRASE", "Config passphrase: ", false)
+			if err != nil {
+				return nil, err
+			}
+			passphrase = p
+			return p, nil
+		},
+	}
+	if v := os.Getenv("BOT_CONFIG_PASSPHRASE"); v != "" {
+		keys.GPGPassphrase = []byte(v)
+	}
+	return keys
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/binance"
+	"github.com/killabayte/golang-telegram-bot/pkg/bybit"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/telegram"
+)
+
+// newLogger builds the process logger. format is "text" or "json" and level
+// one of "debug", "info", "warn" or "error"; empty values mean text and info.
+func newLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	var opts slog.HandlerOptions
+	if level != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("log level: %w", err)
+		}
+		opts.Level = l
+	}
+
+	switch format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, &opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, &opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+// errAttrs returns err as slog arguments, along with the endpoint and status
+// code of the request that failed, when known.
+func errAttrs(err error, args ...any) []any {
+	args = append(args, "err", err)
+
+	var reqErr *mexc.RequestError
+	if errors.As(err, &reqErr) {
+		args = append(args, "endpoint", reqErr.Endpoint)
+		if reqErr.StatusCode != 0 {
+			args = append(args, "status", reqErr.StatusCode)
+		}
+	}
+	var mexcErr *mexc.APIError
+	if errors.As(err, &mexcErr) {
+		args = append(args, "code", mexcErr.Code)
+	}
+	var binanceReqErr *binance.RequestError
+	if errors.As(err, &binanceReqErr) {
+		args = append(args, "endpoint", binanceReqErr.Endpoint)
+		if binanceReqErr.StatusCode != 0 {
+			args = append(args, "status", binanceReqErr.StatusCode)
+		}
+	}
+	var binanceErr *binance.APIError
+	if errors.As(err, &binanceErr) {
+		args = append(args, "code", binanceErr.Code)
+	}
+	var bybitReqErr *bybit.RequestError
+	if errors.As(err, &bybitReqErr) {
+		args = append(args, "endpoint", bybitReqErr.Endpoint)
+		if bybitReqErr.StatusCode != 0 {
+			args = append(args, "status", bybitReqErr.StatusCode)
+		}
+	}
+	var bybitErr *bybit.APIError
+	if errors.As(err, &bybitErr) {
+		args = append(args, "code", bybitErr.Code)
+	}
+	var apiErr *telegram.APIError
+	if errors.As(err, &apiErr) {
+		args = append(args, "status", apiErr.Code)
+	}
+	return args
+}
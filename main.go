@@ -1,163 +1,492 @@
 package main
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"log/slog"
 	"net/http"
-	"net/url"
 	"os"
-	"sort"
-	"strconv"
-	"strings"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
+
+	"github.com/killabayte/golang-telegram-bot/internal/config"
+	"github.com/killabayte/golang-telegram-bot/internal/ideas"
+	"github.com/killabayte/golang-telegram-bot/internal/storage"
+	"github.com/killabayte/golang-telegram-bot/pkg/alert"
+	"github.com/killabayte/golang-telegram-bot/pkg/binance"
+	"github.com/killabayte/golang-telegram-bot/pkg/bybit"
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+	"github.com/killabayte/golang-telegram-bot/pkg/rebalance"
+	"github.com/killabayte/golang-telegram-bot/pkg/telegram"
 )
 
-// urlEncode performs URL encoding similar to Java's URLEncoder.encode but replaces '+' with '%20'.
-func urlEncode(s string) string {
-	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+// divergenceLine describes how fairPrice differs from holdAvgPrice. It returns
+// an empty string when the two are equal.
+func divergenceLine(symbol string, fairPrice, holdAvgPrice float64) string {
+	// Direct comparison, since both are now float64
+	if fairPrice == holdAvgPrice {
+		return ""
+	}
+
+	difference := fairPrice - holdAvgPrice
+	percentageDifference := (difference / holdAvgPrice) * 100 // Calculate percentage difference
+
+	if difference > 0 {
+		return fmt.Sprintf("For %s, FairPrice (%f) is greater than HoldAvgPrice (%f) by: %f (%.2f%%)", symbol, fairPrice, holdAvgPrice, difference, percentageDifference)
+	}
+	// Note: difference is negative here, so we multiply by -1 to make percentage positive for printing.
+	return fmt.Sprintf("For %s, HoldAvgPrice (%f) is greater than FairPrice (%f) by: %f (%.2f%%)", symbol, holdAvgPrice, fairPrice, -difference, -percentageDifference)
 }
 
-// getRequestParamString constructs a sorted parameter string from the request parameters.
-func getRequestParamString(params map[string]string) string {
-	var keys []string
-	for k := range params {
-		keys = append(keys, k)
+func main() {
+	// Until the config is loaded, only the environment can set up logging.
+	if logger, err := newLogger(os.Stderr, os.Getenv("BOT_LOG_FORMAT"), os.Getenv("BOT_LOG_LEVEL")); err == nil {
+		slog.SetDefault(logger)
 	}
-	sort.Strings(keys)
 
-	var paramStrBuilder strings.Builder
-	for _, k := range keys {
-		paramStrBuilder.WriteString(fmt.Sprintf("%s=%s&", k, urlEncode(params[k])))
+	// Cancelling ctx stops the monitor and Telegram listener and aborts
+	// in-flight requests. After the first signal, a second one kills the
+	// process without waiting for the shutdown to finish.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	cmd, err := newRootCommand().ExecuteContextC(ctx)
+	stop()
+	if err != nil {
+		var exit exitError
+		if !errors.As(err, &exit) {
+			slog.Error(cmd.CommandPath(), errAttrs(err)...)
+			os.Exit(1)
+		}
+		os.Exit(int(exit))
 	}
-	paramStr := paramStrBuilder.String()
-	return strings.TrimSuffix(paramStr, "&")
 }
 
-// sign generates the signature for the request.
-func sign(accessKey, secretKey, reqTime, paramStr string) string {
-	toSign := accessKey + reqTime + paramStr
+// exitError ends the process with a status code when the command has
+// already reported what went wrong.
+type exitError int
 
-	mac := hmac.New(sha256.New, []byte(secretKey))
-	mac.Write([]byte(toSign))
-	return hex.EncodeToString(mac.Sum(nil))
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
 }
 
-type OpenPositionsResponse struct {
-	Data []struct {
-		Symbol       string  `json:"symbol"`
-		HoldAvgPrice float64 `json:"holdAvgPrice"` // Changed from string to float64
-	} `json:"data"`
-}
+// session holds what every command that talks to the exchange needs.
+type session struct {
+	cfg *config.Profile
+	api *mexc.Client // the top-level MEXC account; nil when only others are configured
+	// ex is every configured account as one exchange.Exchange.
+	ex                 exchange.Exchange
+	notifier           *telegram.Client // nil without Telegram settings
+	secretKey          []byte
+	secondarySecretKey []byte
+	// accountSecrets are the secret keys of the non-MEXC accounts.
+	accountSecrets [][]byte
 
-type FairPriceResponse struct {
-	Data struct {
-		FairPrice float64 `json:"fairPrice"`
-	} `json:"data"`
+	// notices tracks background Telegram messages, which close waits for.
+	notices sync.WaitGroup
 }
 
-func queryFairPriceForSymbol(client *http.Client, accessKey, secretKey, baseURL, symbol string, holdAvgPrice float64) {
-	reqTime := strconv.FormatInt(time.Now().Unix()*1000, 10)
-	endpoint := fmt.Sprintf("/api/v1/contract/fair_price/%s", symbol)
-	signature := sign(accessKey, secretKey, reqTime, "")
+// openSession loads the selected profile, switches logging to its settings
+// and sets up the API client. Call close when done.
+func openSession(ctx context.Context, flags *globalFlags) (*session, error) {
+	cfg, err := config.Load(flags.configPath, flags.profileName)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	logger, err := newLogger(os.Stderr, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		return nil, fmt.Errorf("configuring logging: %w", err)
+	}
+	slog.SetDefault(logger.With("profile", cfg.Name))
 
-	fullURL := baseURL + endpoint
+	accessKey := cfg.AccessKey
+	secretKey := []byte(cfg.SecretKey)
+	if flags.promptKeys {
+		accessKey, secretKey, err = promptKeys()
+		if err != nil {
+			return nil, fmt.Errorf("reading API keys: %w", err)
+		}
+	}
 
-	req, err := http.NewRequest("GET", fullURL, nil)
-	if err != nil {
-		fmt.Printf("Error creating request for symbol %s: %v\n", symbol, err)
-		return
+	s := &session{cfg: cfg, secretKey: secretKey}
+	if cfg.TelegramEnabled() {
+		s.notifier = telegram.NewClient(cfg.Telegram.Token, cfg.Telegram.ChatID)
+	}
+
+	egressCheckURL := cfg.EgressCheckURL
+	if egressCheckURL == "" {
+		egressCheckURL = defaultEgressCheckURL
 	}
+	checkEgressIP(ctx, &http.Client{}, egressCheckURL, cfg.ExpectedIPs)
 
-	req.Header.Add("ApiKey", accessKey)
-	req.Header.Add("Request-Time", reqTime)
-	req.Header.Add("Signature", signature)
-	req.Header.Add("Content-Type", "application/json")
+	var transport http.RoundTripper
+	if cfg.HTTP != (config.HTTP{}) {
+		transport = mexc.NewTransport(cfg.HTTP.TransportOptions())
+	}
+	onStreamError := func(err error) { slog.Error("price stream", errAttrs(err)...) }
 
-	response, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("Error sending request for symbol %s: %v\n", symbol, err)
-		return
+	// MEXC clients share one limiter, as MEXC limits public requests by IP.
+	limiter := mexc.NewRateLimiter(cfg.RateLimits.Limits())
+	var accounts []exchange.Account
+	// MEXC stays the primary account unless only other accounts are
+	// configured.
+	others := cfg.OtherAccounts()
+	if accessKey != "" || len(others) == 0 {
+		s.api = newMEXCClient(cfg, cfg.BaseURL, accessKey, secretKey, transport, limiter)
+		if cfg.SecondaryAccessKey != "" {
+			s.secondarySecretKey = []byte(cfg.SecondarySecretKey)
+			s.api.SetSecondaryKey(cfg.SecondaryAccessKey, s.secondarySecretKey)
+			s.api.OnKeyFailover = s.keyFailover
+		}
+		s.api.SpotBaseURL = cfg.Spot.BaseURL
+		ex := exchange.NewMEXC(s.api, cfg.Stream.URL)
+		ex.OnStreamError = onStreamError
+		accounts = append(accounts, exchange.Account{Label: cfg.Label, Exchange: ex})
 	}
-	defer response.Body.Close()
+	for _, a := range others {
+		secret := s.accountSecret(a)
+		var ex exchange.Exchange
+		switch a.Exchange {
+		case config.ExchangeMEXC:
+			m := exchange.NewMEXC(newMEXCClient(cfg, a.BaseURL, a.APIKey, secret, transport, limiter), a.StreamURL)
+			m.OnStreamError = onStreamError
+			ex = m
+		case config.ExchangeBinance:
+			client := binance.NewClient(a.APIKey, secret, a.BaseURL)
+			if transport != nil {
+				client.SetTransport(transport)
+			}
+			b := exchange.NewBinance(client, a.StreamURL)
+			b.OnStreamError = onStreamError
+			b.OnPositionError = func(symbol string, err error) {
+				slog.Error("skipping Binance position", errAttrs(err, "symbol", symbol)...)
+			}
+			ex = b
+		case config.ExchangeBybit:
+			client := bybit.NewClient(a.APIKey, secret, a.BaseURL)
+			if transport != nil {
+				client.SetTransport(transport)
+			}
+			b := exchange.NewBybit(client, a.StreamURL)
+			b.OnStreamError = onStreamError
+			ex = b
+		}
+		// The binance and bybit sections have no label: past the first
+		// account they are qualified with the exchange name.
+		label := a.Label
+		if label == "" && len(accounts) > 0 {
+			label = a.Exchange
+		}
+		accounts = append(accounts, exchange.Account{Label: label, Exchange: ex})
+	}
+	s.ex = accounts[0].Exchange
+	if len(accounts) > 1 {
+		s.ex = exchange.NewMultiAccount(accounts...)
+	}
+	return s, nil
+}
 
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		fmt.Printf("Error reading response body for symbol %s: %v\n", symbol, err)
-		return
+// newMEXCClient returns a client for one MEXC account with the profile's
+// retry settings.
+func newMEXCClient(cfg *config.Profile, baseURL, accessKey string, secretKey []byte, transport http.RoundTripper, limiter *mexc.RateLimiter) *mexc.Client {
+	client := mexc.NewClient(accessKey, secretKey, baseURL)
+	if transport != nil {
+		client.SetTransport(transport)
+	}
+	client.Retry = cfg.Retry.Policy()
+	client.Limiter = limiter
+	client.OnRetry = func(err error, delay time.Duration) {
+		slog.Warn("retrying MEXC request", errAttrs(err, "delay", delay)...)
 	}
+	return client
+}
 
-	var fairPriceResp FairPriceResponse
-	if err := json.Unmarshal(body, &fairPriceResp); err != nil {
-		fmt.Printf("Error decoding fair price response for %s: %v\n", symbol, err)
-		return
+func (s *session) close() {
+	s.notices.Wait()
+	zeroBytes(s.secretKey)
+	zeroBytes(s.secondarySecretKey)
+	for _, secret := range s.accountSecrets {
+		zeroBytes(secret)
 	}
+}
 
-	// Direct comparison, since both are now float64
-	if fairPriceResp.Data.FairPrice != holdAvgPrice {
-		difference := fairPriceResp.Data.FairPrice - holdAvgPrice
-		percentageDifference := (difference / holdAvgPrice) * 100 // Calculate percentage difference
-
-		if difference > 0 {
-			fmt.Printf("For %s, FairPrice (%f) is greater than HoldAvgPrice (%f) by: %f (%.2f%%)\n", symbol, fairPriceResp.Data.FairPrice, holdAvgPrice, difference, percentageDifference)
-		} else {
-			// Note: difference is negative here, so we multiply by -1 to make percentage positive for printing.
-			fmt.Printf("For %s, HoldAvgPrice (%f) is greater than FairPrice (%f) by: %f (%.2f%%)\n", symbol, holdAvgPrice, fairPriceResp.Data.FairPrice, -difference, -percentageDifference)
+// accountSecret copies the secret key of a, to be wiped by close.
+func (s *session) accountSecret(a config.Account) []byte {
+	secret := []byte(a.SecretKey)
+	s.accountSecrets = append(s.accountSecrets, secret)
+	return secret
+}
+
+// keyFailover reports that MEXC rejected the primary key pair and the
+// secondary is now in use. The Telegram message is sent in the background so
+// the request that failed over isn't held up.
+func (s *session) keyFailover(err error) {
+	slog.Warn("MEXC rejected the primary API key, switched to the secondary", errAttrs(err)...)
+	if s.notifier == nil {
+		return
+	}
+	s.notices.Add(1)
+	go func() {
+		defer s.notices.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+		msg := fmt.Sprintf("MEXC rejected the primary API key (%v); now using the secondary key. Replace the primary before the secondary is rotated too.", err)
+		if err := s.notifier.SendMessageContext(ctx, msg); err != nil {
+			slog.Error("sending Telegram message", errAttrs(err)...)
 		}
+	}()
+}
+
+// openHistory opens the profile's history database, or returns nil if none
+// is configured.
+func (s *session) openHistory() (*storage.DB, error) {
+	if s.cfg.Storage.Path == "" {
+		return nil, nil
+	}
+	history, err := storage.Open(s.cfg.Storage.Path, s.cfg.Storage.Retention)
+	if err != nil {
+		return nil, fmt.Errorf("opening history database %s: %w", s.cfg.Storage.Path, err)
 	}
+	return history, nil
 }
 
-func main() {
-	accessKey := os.Getenv("MEXC_ACCESS_KEY")
-	secretKey := os.Getenv("MEXC_SECRET_KEY")
+// runReport polls once and sends every position to Telegram.
+func runReport(ctx context.Context, s *session) error {
+	out := newReporter(s.notifier, nil)
+	defer out.flush(flushTimeout)
 
-	params := map[string]string{}
-	paramStr := getRequestParamString(params)
-	reqTime := strconv.FormatInt(time.Now().Unix()*1000, 10)
+	// A fresh monitor reports every position on its first poll.
+	res := monitor.NewForExchange(s.ex, monitorOptions(s.cfg)).Poll(ctx, out)
+	if res.Err != nil {
+		// Already logged by the reporter.
+		return exitError(1)
+	}
+	for _, sr := range res.Failed() {
+		out.send(missingLine(sr.Symbol, sr.Failures, sr.Err), ansiYellow)
+	}
+	return nil
+}
 
-	signature := sign(accessKey, secretKey, reqTime, paramStr)
+// writeReport polls once and writes the fair price comparison of every
+// position to w: in layout for the plain format, or as data for piping into
+// other tools. Nothing is sent to Telegram.
+func writeReport(ctx context.Context, s *session, w io.Writer, format, layout string) error {
+	opts := monitorOptions(s.cfg)
+	res := monitor.NewForExchange(s.ex, opts).Poll(ctx, nil)
+	for _, e := range res.Errors {
+		logMonitorError(e.Symbol, e.Err)
+	}
+	if res.Err != nil {
+		return exitError(1)
+	}
+	if format == outputPlain {
+		return renderReport(w, layout, res, opts.Threshold)
+	}
+	return comparisonOutput(comparisons(res)).write(w, format)
+}
 
-	baseURL := "https://contract.mexc.com"
-	endpoint := "/api/v1/private/position/open_positions"
+// runDaemon keeps running until ctx is canceled: watch polls positions and
+// reports changes, listen answers Telegram commands. Trade ideas are tracked
+// in either case.
+func runDaemon(ctx context.Context, s *session, watch, listen bool) error {
+	cfg, api := s.cfg, s.api
+	if listen && s.notifier == nil {
+		return errors.New("answering Telegram commands requires TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID")
+	}
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
 
-	fullURL := fmt.Sprintf("%s%s", baseURL, endpoint)
+	out := newReporter(s.notifier, newIncidents(cfg.Incidents.Alerts, cfg.Incidents.Window))
+	defer out.flush(flushTimeout)
 
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", fullURL, nil)
+	ideaStore, err := ideas.OpenStore(cfg.IdeasFile)
 	if err != nil {
-		fmt.Println("Error creating request:", err)
-		return
+		return fmt.Errorf("loading ideas from %s: %w", cfg.IdeasFile, err)
+	}
+	history, err := s.openHistory()
+	if err != nil {
+		return err
+	}
+	if history != nil {
+		defer history.Close()
 	}
 
-	req.Header.Add("ApiKey", accessKey)
-	req.Header.Add("Request-Time", reqTime)
-	req.Header.Add("Signature", signature)
-	req.Header.Add("Content-Type", "application/json")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		trackIdeas(ctx, s.ex, ideaStore, cfg.PollInterval, out)
+	}()
+	var dash *dashboard
+	if cfg.Dashboard.Listen != "" {
+		dash = newDashboard(s, history)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := dash.serve(ctx); err != nil {
+				slog.Error("serving dashboard", errAttrs(err)...)
+				stop()
+			}
+		}()
+	}
+	if watch {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.Info("watching positions", "interval", cfg.PollInterval, "stream", cfg.Stream.Enabled, "private", cfg.Stream.Private)
+			opts := monitorOptions(cfg)
+			opts.OnCycle = func(res monitor.CycleResult) {
+				logCycle(res, cfg.PollInterval)
+				if dash != nil {
+					dash.setCycle(res)
+				}
+			}
+			var h monitor.Handler = out
+			if history != nil {
+				opts.Alerts.OnError = func(err error) { slog.Error("saving alert state", errAttrs(err)...) }
+				if err := opts.Alerts.Persist(history); err != nil {
+					slog.Error("loading alert state", errAttrs(err)...)
+				}
+				opts.Recorder = history
+				h = &historyHandler{Handler: out, history: history}
+			}
+			if dash != nil {
+				h = &dashboardHandler{Handler: h, dashboard: dash}
+			}
+			if r := cfg.Hedges.Rebalance; r.Enabled {
+				slog.Info("hedge rebalancing enabled", "ratio", r.Ratio, "live", r.Live)
+				h = &rebalanceHandler{Handler: h, ctx: ctx, rebalancer: rebalance.NewForExchange(s.ex, rebalanceOptions(r)), out: out}
+			}
+			mon := monitor.NewForExchange(s.ex, opts)
+			if !cfg.Stream.Enabled {
+				mon.Run(ctx, h)
+				return
+			}
+			stream := s.ex.StreamPrices()
+			var private *mexc.PrivateStream
+			switch {
+			case !cfg.Stream.Private || api == nil:
+			case cfg.Label != "" && len(cfg.OtherAccounts()) > 0:
+				// Pushes carry bare MEXC symbols, which no longer match
+				// the labeled positions.
+				slog.Warn("stream.private is not supported for a labeled MEXC account, ignoring it")
+			default:
+				private = api.PrivateStream(cfg.Stream.URL)
+				private.OnError = func(err error) { slog.Error("private stream", errAttrs(err)...) }
+			}
+			mon.RunStream(ctx, h, stream, private)
+		}()
+	}
+	if listen {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.Info("listening for Telegram commands")
+			router := telegram.NewRouter(s.notifier)
+			var spot *mexc.Client
+			if cfg.Spot.Enabled {
+				spot = api
+			}
+			registerCommands(router, s.ex, spot, history)
+			registerIdeaCommands(router, s.ex, ideaStore)
+			if err := router.Listen(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("listening for Telegram updates", errAttrs(err)...)
+				stop()
+			}
+		}()
+	}
+	wg.Wait()
+	slog.Info("shutting down")
+	return nil
+}
 
-	response, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request:", err)
+// logCycle logs how a watch mode poll went, and warns when polls take
+// longer than the interval between them.
+func logCycle(res monitor.CycleResult, interval time.Duration) {
+	attrs := []any{
+		"duration", res.Duration.Round(time.Millisecond),
+		"positions", len(res.Positions),
+		"symbols", len(res.Symbols),
+		"missing", len(res.Failed()),
+		"events", len(res.Events),
+		"errors", len(res.Errors),
+	}
+	if res.Duration > interval {
+		slog.Warn("poll took longer than the poll interval", append(attrs, "interval", interval)...)
 		return
 	}
-	defer response.Body.Close()
+	slog.Debug("poll finished", attrs...)
+}
 
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return
+func rebalanceOptions(r config.Rebalance) rebalance.Options {
+	return rebalance.Options{
+		Ratio:           r.Ratio,
+		MaxOrderValue:   r.MaxOrderValue,
+		MaxOrdersPerDay: r.MaxOrdersPerDay,
+		Cooldown:        r.Cooldown,
+		DryRun:          !r.Live,
 	}
+}
 
-	var resp OpenPositionsResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		fmt.Println("Error decoding response JSON:", err)
-		return
+func monitorOptions(cfg *config.Profile) monitor.Options {
+	return monitor.Options{
+		Interval:    cfg.PollInterval,
+		Include:     cfg.WatchesSymbol,
+		Concurrency: cfg.Concurrency,
+		Threshold: func(symbol string) monitor.Threshold {
+			t := cfg.Thresholds.For(symbol)
+			return monitor.Threshold{Percent: t.Percent, Absolute: t.Absolute}
+		},
+		Alerts: alert.NewManager(alert.Policy{
+			Cooldown:   cfg.Alerts.Cooldown,
+			Repeat:     cfg.Alerts.Repeat,
+			Hysteresis: cfg.Alerts.Hysteresis,
+		}),
+
+		ImbalanceLevels:    cfg.Imbalance.Levels,
+		ImbalanceInReports: cfg.Imbalance.InReports,
+		ImbalanceThreshold: cfg.Imbalance.Threshold,
+
+		FundingInReports: cfg.Funding.InReports,
+		FundingThreshold: cfg.Funding.Percent / 100,
+
+		StaleAfter:  cfg.StalePositions.After,
+		StaleRepeat: cfg.StalePositions.Repeat,
+
+		HedgeTolerance: cfg.Hedges.Tolerance,
+
+		MissingDataAfter: cfg.MissingData.After,
+
+		EquityDropPercent: cfg.EquityDrop.Percent,
+		EquityDropWindow:  cfg.EquityDrop.Window,
 	}
+}
 
-	for _, pos := range resp.Data {
-		queryFairPriceForSymbol(client, accessKey, secretKey, baseURL, pos.Symbol, pos.HoldAvgPrice) // pos.HoldAvgPrice is now a float64
+// validateConfig checks every profile in the config file, then loads the
+// selected one with environment overrides, and prints a summary.
+func validateConfig(path, profile string) error {
+	if path != "" {
+		if err := config.ValidateFile(path); err != nil {
+			return err
+		}
+	}
+	cfg, err := config.Load(path, profile)
+	if err != nil {
+		return err
+	}
+	source := path
+	if source == "" {
+		source = "environment"
 	}
+	fmt.Printf("%s: OK (profile %q)\n", source, cfg.Name)
+	return nil
 }
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/killabayte/golang-telegram-bot/internal/storage"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+)
+
+// Output formats for commands that print data.
+const (
+	outputPlain = "plain"
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+func addOutputFlag(cmd *cobra.Command, output *string) {
+	cmd.Flags().StringVarP(output, "output", "o", outputPlain, "output format: plain, table, json or csv")
+}
+
+func checkOutput(output string) error {
+	switch output {
+	case outputPlain, outputTable, outputJSON, outputCSV:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q (want plain, table, json or csv)", output)
+}
+
+// output is data that can be written in every output format. Columns are
+// named in snake_case; the table header shows them in upper case.
+type output struct {
+	columns []string
+	rows    [][]string
+	// json is encoded for the json format.
+	json any
+	// plain is the text for the plain format.
+	plain string
+}
+
+func (o output) write(w io.Writer, format string) error {
+	switch format {
+	case outputJSON:
+		return writeJSON(w, o.json)
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(o.columns)
+		cw.WriteAll(o.rows)
+		return cw.Error()
+	case outputTable:
+		var b bytes.Buffer
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		header := make([]string, len(o.columns))
+		for i, c := range o.columns {
+			header[i] = strings.ToUpper(strings.ReplaceAll(c, "_", " "))
+		}
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range o.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		// Rows ending in empty cells would otherwise end in the padding of
+		// the cells before them.
+		for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+			if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	_, err := fmt.Fprintln(w, o.plain)
+	return err
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// formatNumber renders a number for table and CSV cells without losing
+// precision.
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// optionalNumber is formatNumber for values that may be missing.
+func optionalNumber(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatNumber(*v)
+}
+
+// optionalPercent renders a percentage that may be missing, to two decimals.
+func optionalPercent(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', 2, 64)
+}
+
+// comparison is one position in the fair price report. The fair price
+// fields are nil when the symbol couldn't be priced.
+type comparison struct {
+	Symbol            string   `json:"symbol"`
+	Side              string   `json:"side"`
+	Leverage          int      `json:"leverage"`
+	Contracts         float64  `json:"contracts"`
+	EntryPrice        float64  `json:"entry_price"`
+	FairPrice         *float64 `json:"fair_price"`
+	Difference        *float64 `json:"difference"`
+	DifferencePercent *float64 `json:"difference_percent"`
+	Error             string   `json:"error,omitempty"`
+
+	positionType int
+}
+
+// comparisons lists every position of a poll with its fair price.
+func comparisons(res monitor.CycleResult) []comparison {
+	priced := make(map[string]monitor.SymbolResult, len(res.Symbols))
+	for _, sr := range res.Symbols {
+		priced[sr.Symbol] = sr
+	}
+	rows := make([]comparison, 0, len(res.Positions))
+	for _, pos := range res.Positions {
+		c := comparison{
+			Symbol:     pos.Symbol,
+			Side:       pos.Side(),
+			Leverage:   pos.Leverage,
+			Contracts:  pos.HoldVol,
+			EntryPrice: pos.HoldAvgPrice,
+
+			positionType: pos.PositionType,
+		}
+		sr := priced[pos.Symbol]
+		if sr.Err != nil {
+			c.Error = sr.Err.Error()
+		} else {
+			fairPrice := sr.FairPrice
+			difference := fairPrice - pos.HoldAvgPrice
+			c.FairPrice, c.Difference = &fairPrice, &difference
+			if pos.HoldAvgPrice != 0 {
+				percent := difference / pos.HoldAvgPrice * 100
+				c.DifferencePercent = &percent
+			}
+		}
+		rows = append(rows, c)
+	}
+	return rows
+}
+
+func comparisonOutput(rows []comparison) output {
+	o := output{
+		columns: []string{"symbol", "side", "leverage", "contracts", "entry_price", "fair_price", "difference", "difference_percent", "error"},
+		json:    rows,
+	}
+	for _, c := range rows {
+		o.rows = append(o.rows, []string{
+			c.Symbol, c.Side, strconv.Itoa(c.Leverage), formatNumber(c.Contracts), formatNumber(c.EntryPrice),
+			optionalNumber(c.FairPrice), optionalNumber(c.Difference), optionalPercent(c.DifferencePercent), c.Error,
+		})
+	}
+	return o
+}
+
+func positionsOutput(positions []mexc.Position) output {
+	o := output{
+		columns: []string{"symbol", "side", "leverage", "contracts", "entry_price", "opened"},
+		json:    positions,
+		plain:   formatPositions(positions),
+	}
+	if positions == nil {
+		o.json = []mexc.Position{}
+	}
+	for _, pos := range positions {
+		opened := ""
+		if pos.CreateTime != 0 {
+			opened = pos.OpenedAt().Format(time.DateTime)
+		}
+		o.rows = append(o.rows, []string{
+			pos.Symbol, pos.Side(), strconv.Itoa(pos.Leverage), formatNumber(pos.HoldVol), formatNumber(pos.HoldAvgPrice), opened,
+		})
+	}
+	return o
+}
+
+func quoteOutput(q quote) output {
+	var change *float64
+	if q.DayAgo != nil && *q.DayAgo != 0 {
+		percent := (q.FairPrice - *q.DayAgo) / *q.DayAgo * 100
+		change = &percent
+	}
+	return output{
+		columns: []string{"symbol", "fair_price", "fair_price_24h_ago", "change_24h_percent"},
+		rows:    [][]string{{q.Symbol, formatNumber(q.FairPrice), optionalNumber(q.DayAgo), optionalPercent(change)}},
+		json:    q,
+		plain:   q.String(),
+	}
+}
+
+func alertsOutput(events []storage.AlertEvent) output {
+	o := output{
+		columns: []string{"time", "kind", "symbol", "position_id", "fair_price", "resolved_at", "resolution", "resolved_after_seconds"},
+		json:    events,
+	}
+	if events == nil {
+		o.json = []storage.AlertEvent{}
+	}
+	now := time.Now()
+	var plain strings.Builder
+	for _, e := range events {
+		fairPrice := ""
+		fmt.Fprintf(&plain, "%s %s %s", e.Time.Format(time.DateTime), e.Symbol, e.Kind)
+		if e.FairPrice != 0 {
+			fairPrice = formatNumber(e.FairPrice)
+			fmt.Fprintf(&plain, " @ %f", e.FairPrice)
+		}
+		if status := alertStatus(e, now); status != "" {
+			plain.WriteString(", " + status)
+		}
+		plain.WriteString("\n")
+		resolvedAt, resolvedAfter := "", ""
+		if e.ResolvedAt != nil {
+			resolvedAt = e.ResolvedAt.Format(time.RFC3339)
+			resolvedAfter = formatNumber(e.ResolvedAfterSeconds)
+		}
+		o.rows = append(o.rows, []string{
+			e.Time.Format(time.RFC3339), e.Kind, e.Symbol, strconv.FormatInt(e.PositionID, 10), fairPrice, resolvedAt, e.Resolution, resolvedAfter,
+		})
+	}
+	o.plain = strings.TrimSuffix(plain.String(), "\n")
+	if len(events) == 0 {
+		o.plain = "No alerts."
+	}
+	return o
+}
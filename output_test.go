@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/internal/storage"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+)
+
+// checkGolden writes o in every format and compares it with want, by format.
+func checkGolden(t *testing.T, o output, want map[string]string) {
+	t.Helper()
+	for _, format := range []string{outputPlain, outputTable, outputJSON, outputCSV} {
+		var b bytes.Buffer
+		if err := o.write(&b, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if got := b.String(); got != want[format] {
+			t.Errorf("%s output:\n%s\nwant:\n%s", format, got, want[format])
+		}
+	}
+}
+
+func TestComparisonOutput(t *testing.T) {
+	res := testCycle()
+	res.Positions = append(res.Positions, mexc.Position{Symbol: "SOL_USDT", PositionType: mexc.PositionTypeLong, HoldVol: 2.5, HoldAvgPrice: 150, Leverage: 5})
+	res.Symbols = append(res.Symbols, monitor.SymbolResult{Symbol: "SOL_USDT", Err: errors.New("timeout")})
+
+	o := comparisonOutput(comparisons(res))
+	o.plain = "see renderReport"
+	checkGolden(t, o, map[string]string{
+		outputPlain: "see renderReport\n",
+		outputTable: `SYMBOL    SIDE   LEVERAGE  CONTRACTS  ENTRY PRICE  FAIR PRICE  DIFFERENCE  DIFFERENCE PERCENT  ERROR
+BTC_USDT  long   10        10         60000        63000       3000        5.00
+ETH_USDT  short  20        5          3000         3030        30          1.00
+SOL_USDT  long   5         2.5        150                                                      timeout
+`,
+		outputJSON: `[
+  {
+    "symbol": "BTC_USDT",
+    "side": "long",
+    "leverage": 10,
+    "contracts": 10,
+    "entry_price": 60000,
+    "fair_price": 63000,
+    "difference": 3000,
+    "difference_percent": 5
+  },
+  {
+    "symbol": "ETH_USDT",
+    "side": "short",
+    "leverage": 20,
+    "contracts": 5,
+    "entry_price": 3000,
+    "fair_price": 3030,
+    "difference": 30,
+    "difference_percent": 1
+  },
+  {
+    "symbol": "SOL_USDT",
+    "side": "long",
+    "leverage": 5,
+    "contracts": 2.5,
+    "entry_price": 150,
+    "fair_price": null,
+    "difference": null,
+    "difference_percent": null,
+    "error": "timeout"
+  }
+]
+`,
+		outputCSV: `symbol,side,leverage,contracts,entry_price,fair_price,difference,difference_percent,error
+BTC_USDT,long,10,10,60000,63000,3000,5.00,
+ETH_USDT,short,20,5,3000,3030,30,1.00,
+SOL_USDT,long,5,2.5,150,,,,timeout
+`,
+	})
+}
+
+func TestPositionsOutput(t *testing.T) {
+	positions := []mexc.Position{
+		{PositionID: 1, Symbol: "BTC_USDT", PositionType: mexc.PositionTypeLong, State: mexc.PositionStateHolding, HoldVol: 10, HoldAvgPrice: 60000.5, Leverage: 10, OpenType: mexc.OpenTypeCross},
+	}
+	checkGolden(t, positionsOutput(positions), map[string]string{
+		outputPlain: "BTC_USDT long 10x: 10 contracts @ 60000.500000\n",
+		outputTable: `SYMBOL    SIDE  LEVERAGE  CONTRACTS  ENTRY PRICE  OPENED
+BTC_USDT  long  10        10         60000.5
+`,
+		outputJSON: `[
+  {
+    "positionId": 1,
+    "symbol": "BTC_USDT",
+    "positionType": 1,
+    "state": 1,
+    "holdVol": 10,
+    "holdAvgPrice": 60000.5,
+    "realised": 0,
+    "leverage": 10,
+    "openType": 2,
+    "createTime": 0
+  }
+]
+`,
+		outputCSV: `symbol,side,leverage,contracts,entry_price,opened
+BTC_USDT,long,10,10,60000.5,
+`,
+	})
+
+	checkGolden(t, positionsOutput(nil), map[string]string{
+		outputPlain: "No open positions.\n",
+		outputTable: "SYMBOL  SIDE  LEVERAGE  CONTRACTS  ENTRY PRICE  OPENED\n",
+		outputJSON:  "[]\n",
+		outputCSV:   "symbol,side,leverage,contracts,entry_price,opened\n",
+	})
+}
+
+func TestQuoteOutput(t *testing.T) {
+	dayAgo := 60000.0
+	checkGolden(t, quoteOutput(quote{Symbol: "BTC_USDT", FairPrice: 61500, DayAgo: &dayAgo}), map[string]string{
+		outputPlain: "BTC_USDT fair price: 61500.000000\n24h ago: 60000.000000 (+2.50%)\n",
+		outputTable: `SYMBOL    FAIR PRICE  FAIR PRICE 24H AGO  CHANGE 24H PERCENT
+BTC_USDT  61500       60000               2.50
+`,
+		outputJSON: `{
+  "symbol": "BTC_USDT",
+  "fair_price": 61500,
+  "fair_price_24h_ago": 60000
+}
+`,
+		outputCSV: `symbol,fair_price,fair_price_24h_ago,change_24h_percent
+BTC_USDT,61500,60000,2.50
+`,
+	})
+}
+
+func TestAlertsOutput(t *testing.T) {
+	fired := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	resolved := fired.Add(90 * time.Second)
+	events := []storage.AlertEvent{
+		{Time: fired, Kind: "updated", Symbol: "BTC_USDT", PositionID: 7, FairPrice: 63000, AlertKey: "divergence:BTC_USDT/7", ResolvedAt: &resolved, Resolution: storage.ResolutionCleared, ResolvedAfterSeconds: 90},
+		{Time: fired.Add(time.Hour), Kind: "stale", Symbol: "ETH_USDT, short", PositionID: 8},
+	}
+	checkGolden(t, alertsOutput(events), map[string]string{
+		outputPlain: `2026-03-01 12:00:00 BTC_USDT updated @ 63000.000000, resolved after 1m
+2026-03-01 13:00:00 ETH_USDT, short stale
+`,
+		outputTable: `TIME                  KIND     SYMBOL           POSITION ID  FAIR PRICE  RESOLVED AT           RESOLUTION  RESOLVED AFTER SECONDS
+2026-03-01T12:00:00Z  updated  BTC_USDT         7            63000       2026-03-01T12:01:30Z  cleared     90
+2026-03-01T13:00:00Z  stale    ETH_USDT, short  8
+`,
+		outputJSON: `[
+  {
+    "time": "2026-03-01T12:00:00Z",
+    "kind": "updated",
+    "symbol": "BTC_USDT",
+    "position_id": 7,
+    "fair_price": 63000,
+    "alert_key": "divergence:BTC_USDT/7",
+    "resolved_at": "2026-03-01T12:01:30Z",
+    "resolution": "cleared",
+    "resolved_after_seconds": 90
+  },
+  {
+    "time": "2026-03-01T13:00:00Z",
+    "kind": "stale",
+    "symbol": "ETH_USDT, short",
+    "position_id": 8,
+    "fair_price": 0
+  }
+]
+`,
+		// Cells with commas are quoted.
+		outputCSV: `time,kind,symbol,position_id,fair_price,resolved_at,resolution,resolved_after_seconds
+2026-03-01T12:00:00Z,updated,BTC_USDT,7,63000,2026-03-01T12:01:30Z,cleared,90
+2026-03-01T13:00:00Z,stale,"ETH_USDT, short",8,,,,
+`,
+	})
+
+	checkGolden(t, alertsOutput(nil), map[string]string{
+		outputPlain: "No alerts.\n",
+		outputTable: "TIME  KIND  SYMBOL  POSITION ID  FAIR PRICE  RESOLVED AT  RESOLUTION  RESOLVED AFTER SECONDS\n",
+		outputJSON:  "[]\n",
+		outputCSV:   "time,kind,symbol,position_id,fair_price,resolved_at,resolution,resolved_after_seconds\n",
+	})
+}
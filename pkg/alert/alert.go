@@ -0,0 +1,192 @@
+// Package alert decides when a recurring condition is worth notifying about
+// again, so a condition that stays true doesn't produce a message every poll.
+package alert
+
+import (
+	"sync"
+	"time"
+)
+
+// Policy controls how often an alert may repeat.
+type Policy struct {
+	// Cooldown is the minimum time between two alerts for the same key, even
+	// if the condition cleared and re-triggered in between.
+	Cooldown time.Duration
+	// Repeat re-sends an alert while its condition stays active. Zero sends
+	// one alert per episode.
+	Repeat time.Duration
+	// Hysteresis is the fraction (0 to 1) by which a condition must fall back
+	// below its threshold before the alert re-arms. Callers apply it when
+	// computing the cleared argument to Check; see Rearm.
+	Hysteresis float64
+}
+
+// Rearm scales a threshold down to the level at which an alert re-arms.
+func (p Policy) Rearm(threshold float64) float64 {
+	return threshold * (1 - p.Hysteresis)
+}
+
+// State is what a Manager remembers about one key.
+type State struct {
+	// Armed means the condition cleared since the last alert.
+	Armed     bool
+	LastFired time.Time
+}
+
+// Store keeps alert state across restarts, so a running condition isn't
+// announced again just because the bot was restarted.
+type Store interface {
+	LoadAlertStates() (map[string]State, error)
+	SaveAlertState(key string, s State) error
+	DeleteAlertState(key string) error
+}
+
+// Manager tracks the last-fired state of each alert key. It is safe for
+// concurrent use.
+type Manager struct {
+	policy Policy
+
+	// OnError, if set, receives errors writing to the store given to Persist.
+	// The alert decision itself never fails.
+	OnError func(error)
+
+	mu      sync.Mutex
+	entries map[string]*State
+	store   Store
+}
+
+// NewManager returns a Manager applying policy to every key.
+func NewManager(policy Policy) *Manager {
+	return &Manager{policy: policy, entries: make(map[string]*State)}
+}
+
+// Persist loads the state saved in store and writes every later change back
+// to it.
+func (m *Manager) Persist(store Store) error {
+	states, err := store.LoadAlertStates()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, s := range states {
+		s := s
+		m.entries[key] = &s
+	}
+	m.store = store
+	return nil
+}
+
+// Policy returns the policy the manager was created with.
+func (m *Manager) Policy() Policy {
+	return m.policy
+}
+
+// Check reports whether an alert for key should be sent at now. active says
+// the condition currently holds; cleared says it has fallen back past the
+// hysteresis band, which re-arms the key. A condition inside the band is
+// neither.
+func (m *Manager) Check(key string, active, cleared bool, now time.Time) bool {
+	return m.Update(key, active, cleared, now) == Fired
+}
+
+// Transition is what an Update did to a key.
+type Transition int
+
+const (
+	// Unchanged means no alert is due and the key kept its state.
+	Unchanged Transition = iota
+	// Fired means an alert should be sent.
+	Fired
+	// Rearmed means the condition of a fired alert cleared.
+	Rearmed
+)
+
+// Update is Check, but also reports when a fired key re-arms, so callers
+// can tell that the condition behind an alert has gone away.
+func (m *Manager) Update(key string, active, cleared bool, now time.Time) Transition {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if cleared {
+		if ok && !e.Armed {
+			e.Armed = true
+			m.save(key, e)
+			return Rearmed
+		}
+		return Unchanged
+	}
+	if !active {
+		return Unchanged
+	}
+
+	switch {
+	case !ok:
+		e = &State{}
+		m.entries[key] = e
+	case e.Armed && now.Sub(e.LastFired) >= m.policy.Cooldown:
+	case !e.Armed && m.policy.Repeat > 0 && now.Sub(e.LastFired) >= m.policy.Repeat:
+	default:
+		return Unchanged
+	}
+	e.Armed = false
+	e.LastFired = now
+	m.save(key, e)
+	return Fired
+}
+
+// State returns what the manager remembers about key, if anything.
+func (m *Manager) State(key string) (State, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return State{}, false
+	}
+	return *e, true
+}
+
+// Reset forgets key so its next active check fires immediately, e.g. when
+// the underlying position changed.
+func (m *Manager) Reset(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.forget(key)
+}
+
+// Retain forgets every key for which keep returns false, e.g. keys of
+// positions that closed while the bot wasn't running.
+func (m *Manager) Retain(keep func(key string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.entries {
+		if !keep(key) {
+			m.forget(key)
+		}
+	}
+}
+
+// forget and save must be called with m.mu held.
+func (m *Manager) forget(key string) {
+	if _, ok := m.entries[key]; !ok {
+		return
+	}
+	delete(m.entries, key)
+	if m.store != nil {
+		m.report(m.store.DeleteAlertState(key))
+	}
+}
+
+func (m *Manager) save(key string, e *State) {
+	if m.store != nil {
+		m.report(m.store.SaveAlertState(key, *e))
+	}
+}
+
+func (m *Manager) report(err error) {
+	if err != nil && m.OnError != nil {
+		m.OnError(err)
+	}
+}
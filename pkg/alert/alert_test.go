@@ -0,0 +1,111 @@
+package alert
+
+import (
+	"testing"
+	"time"
+)
+
+// memStore is a Store in memory.
+type memStore struct {
+	states map[string]State
+}
+
+func (s *memStore) LoadAlertStates() (map[string]State, error) { return s.states, nil }
+
+func (s *memStore) SaveAlertState(key string, st State) error {
+	s.states[key] = st
+	return nil
+}
+
+func (s *memStore) DeleteAlertState(key string) error {
+	delete(s.states, key)
+	return nil
+}
+
+func TestPersist(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	saved := map[string]State{
+		"divergence:BTC_USDT/1": {LastFired: t0},
+		"divergence:ETH_USDT/2": {Armed: true, LastFired: t0.Add(time.Minute)},
+		"imbalance:SOL_USDT":    {LastFired: t0.Add(2 * time.Minute)},
+	}
+	store := &memStore{states: make(map[string]State)}
+	for key, s := range saved {
+		store.states[key] = s
+	}
+
+	m := NewManager(Policy{})
+	if err := m.Persist(store); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range saved {
+		if got, ok := m.State(key); !ok || got != want {
+			t.Errorf("State(%s) = %+v, %v, want %+v", key, got, ok, want)
+		}
+	}
+
+	// Restored alerts don't fire again, and changes are written back.
+	if m.Check("divergence:BTC_USDT/1", true, false, t0.Add(time.Hour)) {
+		t.Error("restored alert fired again")
+	}
+	m.Update("divergence:BTC_USDT/1", false, true, t0.Add(time.Hour))
+	if !store.states["divergence:BTC_USDT/1"].Armed {
+		t.Error("re-arming wasn't saved")
+	}
+	m.Reset("imbalance:SOL_USDT")
+	if _, ok := store.states["imbalance:SOL_USDT"]; ok {
+		t.Error("reset key wasn't deleted from the store")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	type step struct {
+		at              time.Duration
+		active, cleared bool
+		want            Transition
+	}
+	tests := []struct {
+		name   string
+		policy Policy
+		steps  []step
+	}{
+		{"once per episode", Policy{}, []step{
+			{0, true, false, Fired},
+			{time.Hour, true, false, Unchanged},
+			{2 * time.Hour, false, true, Rearmed},
+			{3 * time.Hour, false, true, Unchanged},
+			{4 * time.Hour, true, false, Fired},
+		}},
+		{"inside the hysteresis band", Policy{}, []step{
+			{0, true, false, Fired},
+			{time.Minute, false, false, Unchanged},
+			{2 * time.Minute, true, false, Unchanged},
+		}},
+		{"cooldown", Policy{Cooldown: 10 * time.Minute}, []step{
+			{0, true, false, Fired},
+			{time.Minute, false, true, Rearmed},
+			{2 * time.Minute, true, false, Unchanged},
+			{10 * time.Minute, true, false, Fired},
+		}},
+		{"repeat", Policy{Repeat: 30 * time.Minute}, []step{
+			{0, true, false, Fired},
+			{29 * time.Minute, true, false, Unchanged},
+			{30 * time.Minute, true, false, Fired},
+		}},
+		{"cleared before firing", Policy{}, []step{
+			{0, false, true, Unchanged},
+			{time.Minute, true, false, Fired},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(tt.policy)
+			for i, s := range tt.steps {
+				if got := m.Update("key", s.active, s.cleared, t0.Add(s.at)); got != s.want {
+					t.Errorf("step %d: Update = %v, want %v", i+1, got, s.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package binance
+
+import "context"
+
+// Position sides. One-way mode accounts hold a single BOTH position per
+// symbol, whose sign gives the direction; hedge mode accounts hold LONG and
+// SHORT positions separately.
+const (
+	PositionSideBoth  = "BOTH"
+	PositionSideLong  = "LONG"
+	PositionSideShort = "SHORT"
+)
+
+// PositionRisk is one position as reported by /fapi/v2/positionRisk.
+type PositionRisk struct {
+	Symbol string `json:"symbol"`
+	// PositionAmt is the size in the base asset, negative for shorts in
+	// one-way mode.
+	PositionAmt      float64 `json:"positionAmt,string"`
+	EntryPrice       float64 `json:"entryPrice,string"`
+	MarkPrice        float64 `json:"markPrice,string"`
+	UnrealizedProfit float64 `json:"unRealizedProfit,string"`
+	LiquidationPrice float64 `json:"liquidationPrice,string"`
+	Leverage         int     `json:"leverage,string"`
+	MarginType       string  `json:"marginType"` // "isolated" or "cross"
+	PositionSide     string  `json:"positionSide"`
+	UpdateTime       int64   `json:"updateTime"` // milliseconds since the epoch
+}
+
+// PositionRisk returns every position on the account, including empty ones.
+func (c *Client) PositionRisk(ctx context.Context) ([]PositionRisk, error) {
+	var resp []PositionRisk
+	if err := c.signedGet(ctx, "/fapi/v2/positionRisk", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Balance is the futures wallet balance of one asset.
+type Balance struct {
+	Asset              string  `json:"asset"`
+	Balance            float64 `json:"balance,string"`
+	CrossWalletBalance float64 `json:"crossWalletBalance,string"`
+	CrossUnPnl         float64 `json:"crossUnPnl,string"`
+	AvailableBalance   float64 `json:"availableBalance,string"`
+}
+
+// Balances returns the futures wallet balance per asset.
+func (c *Client) Balances(ctx context.Context) ([]Balance, error) {
+	var resp []Balance
+	if err := c.signedGet(ctx, "/fapi/v2/balance", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// HedgeMode reports whether the account holds long and short positions
+// separately (Binance's "dual side position" mode).
+func (c *Client) HedgeMode(ctx context.Context) (bool, error) {
+	var resp struct {
+		DualSidePosition bool `json:"dualSidePosition"`
+	}
+	err := c.signedGet(ctx, "/fapi/v1/positionSide/dual", nil, &resp)
+	return resp.DualSidePosition, err
+}
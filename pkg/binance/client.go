@@ -0,0 +1,148 @@
+// Package binance is a client for the Binance USDT-M futures REST API and
+// its mark price stream.
+package binance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the production USDT-M futures API endpoint.
+const DefaultBaseURL = "https://fapi.binance.com"
+
+// DefaultRecvWindow is how long after its timestamp a signed request stays
+// valid.
+const DefaultRecvWindow = 5 * time.Second
+
+// Client signs and sends requests to the Binance futures API.
+type Client struct {
+	apiKey     string
+	secretKey  []byte
+	baseURL    string
+	httpClient *http.Client
+
+	// RecvWindow bounds the clock skew tolerated on signed requests.
+	RecvWindow time.Duration
+}
+
+// NewClient returns a Client for baseURL authenticated with the given key
+// pair. The secret is referenced rather than copied, so wiping the caller's
+// slice also wipes it from the Client.
+func NewClient(apiKey string, secretKey []byte, baseURL string) *Client {
+	return &Client{
+		apiKey:     apiKey,
+		secretKey:  secretKey,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		RecvWindow: DefaultRecvWindow,
+	}
+}
+
+// SetTransport replaces the transport the client sends requests through. It
+// must not be called while requests are in flight.
+func (c *Client) SetTransport(t http.RoundTripper) {
+	c.httpClient.Transport = t
+}
+
+// RequestError is returned when a request fails, and records which endpoint
+// it was for.
+type RequestError struct {
+	Endpoint string
+	// StatusCode is the HTTP status, or zero if no response was received.
+	StatusCode int
+	Err        error
+}
+
+func (e *RequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// sign returns the HMAC-SHA256 signature of query.
+func sign(secretKey []byte, query string) string {
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte(query))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// get sends a public GET request and decodes the JSON response into out.
+func (c *Client) get(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, endpoint, params, false, out)
+}
+
+// signedGet is get for endpoints that need the API key.
+func (c *Client) signedGet(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, endpoint, params, true, out)
+}
+
+// signedPost sends a signed POST request with params in the query string.
+func (c *Client) signedPost(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodPost, endpoint, params, true, out)
+}
+
+func (c *Client) do(ctx context.Context, method, endpoint string, params url.Values, signed bool, out interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	query := params.Encode()
+	if signed {
+		params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+		params.Set("recvWindow", strconv.FormatInt(c.RecvWindow.Milliseconds(), 10))
+		query = params.Encode()
+		query += "&signature=" + sign(c.secretKey, query)
+	}
+	fullURL := c.baseURL + endpoint
+	if query != "" {
+		fullURL += "?" + query
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if signed {
+		req.Header.Set("X-MBX-APIKEY", c.apiKey)
+	}
+
+	response, err := c.httpClient.Do(req)
+	if err != nil {
+		return &RequestError{Endpoint: endpoint, Err: fmt.Errorf("sending request: %w", err)}
+	}
+	defer response.Body.Close()
+
+	fail := func(err error) error {
+		return &RequestError{Endpoint: endpoint, StatusCode: response.StatusCode, Err: err}
+	}
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fail(fmt.Errorf("reading response body: %w", err))
+	}
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusTeapot {
+		// 418 means the IP was banned for ignoring earlier 429s.
+		return fail(ErrRateLimited)
+	}
+	if response.StatusCode >= 300 {
+		var apiErr APIError
+		if err := json.Unmarshal(respBody, &apiErr); err == nil && apiErr.Code != 0 {
+			return fail(&apiErr)
+		}
+		return fail(fmt.Errorf("unexpected HTTP status %s", response.Status))
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fail(fmt.Errorf("decoding response JSON: %w", err))
+	}
+	return nil
+}
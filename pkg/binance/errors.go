@@ -0,0 +1,46 @@
+package binance
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors that API responses are mapped to. Test for them with errors.Is:
+//
+//	if errors.Is(err, binance.ErrUnauthorized) { ... }
+var (
+	ErrUnauthorized     = errors.New("binance: API key invalid, not allowed from this IP or lacking permission")
+	ErrInvalidSignature = errors.New("binance: signature verification failed")
+	ErrRequestExpired   = errors.New("binance: request time outside the receive window; check the system clock")
+	ErrRateLimited      = errors.New("binance: rate limited")
+)
+
+// errorCodes maps Binance futures API error codes to the errors above.
+var errorCodes = map[int]error{
+	-1003: ErrRateLimited,
+	-1021: ErrRequestExpired,
+	-1022: ErrInvalidSignature,
+	-2014: ErrUnauthorized,
+	-2015: ErrUnauthorized,
+}
+
+// APIError is an error response from Binance, e.g. code -2015 for a rejected
+// API key.
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"msg"`
+}
+
+func (e *APIError) Error() string {
+	if known, ok := errorCodes[e.Code]; ok {
+		return fmt.Sprintf("%v (code %d: %s)", known, e.Code, e.Message)
+	}
+	return fmt.Sprintf("binance: error code %d: %s", e.Code, e.Message)
+}
+
+// Is reports whether the error's code maps to target, so callers can test
+// for the exported errors without knowing the codes.
+func (e *APIError) Is(target error) bool {
+	known, ok := errorCodes[e.Code]
+	return ok && known == target
+}
@@ -0,0 +1,148 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// PremiumIndex is a contract's mark price and funding.
+type PremiumIndex struct {
+	Symbol          string  `json:"symbol"`
+	MarkPrice       float64 `json:"markPrice,string"`
+	IndexPrice      float64 `json:"indexPrice,string"`
+	LastFundingRate float64 `json:"lastFundingRate,string"`
+	NextFundingTime int64   `json:"nextFundingTime"` // milliseconds since the epoch
+	Time            int64   `json:"time"`
+}
+
+// PremiumIndex returns the mark price and funding rate of symbol, e.g.
+// "BTCUSDT".
+func (c *Client) PremiumIndex(ctx context.Context, symbol string) (PremiumIndex, error) {
+	var resp PremiumIndex
+	err := c.get(ctx, "/fapi/v1/premiumIndex", url.Values{"symbol": {symbol}}, &resp)
+	return resp, err
+}
+
+// FundingSettlement is a settled funding rate.
+type FundingSettlement struct {
+	Symbol      string  `json:"symbol"`
+	FundingRate float64 `json:"fundingRate,string"`
+	FundingTime int64   `json:"fundingTime"` // milliseconds since the epoch
+}
+
+// LastFunding returns the latest settled funding rate of symbol, e.g.
+// "BTCUSDT".
+func (c *Client) LastFunding(ctx context.Context, symbol string) (FundingSettlement, error) {
+	var resp []FundingSettlement
+	if err := c.get(ctx, "/fapi/v1/fundingRate", url.Values{"symbol": {symbol}, "limit": {"1"}}, &resp); err != nil {
+		return FundingSettlement{}, err
+	}
+	if len(resp) == 0 {
+		return FundingSettlement{}, fmt.Errorf("no funding settlements for %s", symbol)
+	}
+	return resp[0], nil
+}
+
+// FundingInfo is a contract's funding interval. Binance only lists contracts
+// whose funding parameters were adjusted; the others settle every 8 hours.
+type FundingInfo struct {
+	Symbol               string `json:"symbol"`
+	FundingIntervalHours int    `json:"fundingIntervalHours"`
+}
+
+// FundingInfo returns the contracts with adjusted funding parameters.
+func (c *Client) FundingInfo(ctx context.Context) ([]FundingInfo, error) {
+	var resp []FundingInfo
+	err := c.get(ctx, "/fapi/v1/fundingInfo", nil, &resp)
+	return resp, err
+}
+
+// OrderBook is a depth snapshot, best prices first. Each level is a
+// [price, quantity] pair.
+type OrderBook struct {
+	Bids [][2]float64
+	Asks [][2]float64
+}
+
+type depthResponse struct {
+	Bids [][2]json64 `json:"bids"`
+	Asks [][2]json64 `json:"asks"`
+}
+
+// Depth returns up to limit levels per side of the order book for symbol.
+// Binance accepts limits of 5, 10, 20, 50, 100, 500 and 1000.
+func (c *Client) Depth(ctx context.Context, symbol string, limit int) (OrderBook, error) {
+	var resp depthResponse
+	params := url.Values{"symbol": {symbol}, "limit": {strconv.Itoa(limit)}}
+	if err := c.get(ctx, "/fapi/v1/depth", params, &resp); err != nil {
+		return OrderBook{}, err
+	}
+	return OrderBook{Bids: toLevels(resp.Bids), Asks: toLevels(resp.Asks)}, nil
+}
+
+func toLevels(raw [][2]json64) [][2]float64 {
+	levels := make([][2]float64, len(raw))
+	for i, l := range raw {
+		levels[i] = [2]float64{float64(l[0]), float64(l[1])}
+	}
+	return levels
+}
+
+// SymbolInfo describes a contract's trading parameters.
+type SymbolInfo struct {
+	Symbol       string `json:"symbol"`
+	BaseAsset    string `json:"baseAsset"`
+	QuoteAsset   string `json:"quoteAsset"`
+	ContractType string `json:"contractType"` // e.g. PERPETUAL
+	Filters      []struct {
+		FilterType string `json:"filterType"`
+		StepSize   string `json:"stepSize"`
+	} `json:"filters"`
+}
+
+// StepSize is the smallest quantity increment of market orders.
+func (s SymbolInfo) StepSize() float64 {
+	var step float64
+	for _, f := range s.Filters {
+		switch f.FilterType {
+		case "MARKET_LOT_SIZE":
+			if v, err := strconv.ParseFloat(f.StepSize, 64); err == nil && v > 0 {
+				return v
+			}
+		case "LOT_SIZE":
+			step, _ = strconv.ParseFloat(f.StepSize, 64)
+		}
+	}
+	return step
+}
+
+type exchangeInfoResponse struct {
+	Symbols []SymbolInfo `json:"symbols"`
+}
+
+// ExchangeInfo returns the trading parameters of every contract.
+func (c *Client) ExchangeInfo(ctx context.Context) ([]SymbolInfo, error) {
+	var resp exchangeInfoResponse
+	if err := c.get(ctx, "/fapi/v1/exchangeInfo", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Symbols, nil
+}
+
+// json64 decodes a float64 that Binance sends as a JSON string.
+type json64 float64
+
+func (f *json64) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if len(s) >= 2 && s[0] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*f = json64(v)
+	return nil
+}
@@ -0,0 +1,53 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// Order sides.
+const (
+	SideBuy  = "BUY"
+	SideSell = "SELL"
+)
+
+// OrderRequest is a new market order.
+type OrderRequest struct {
+	Symbol string
+	Side   string // SideBuy or SideSell
+	// PositionSide is PositionSideLong or PositionSideShort in hedge mode and
+	// PositionSideBoth (or empty) in one-way mode.
+	PositionSide string
+	Quantity     float64 // in the base asset
+	// ReduceOnly stops the order from opening or growing a position. Only
+	// valid in one-way mode; in hedge mode the side pair already says so.
+	ReduceOnly bool
+}
+
+// PlaceMarketOrder places req as a market order and returns its order ID.
+func (c *Client) PlaceMarketOrder(ctx context.Context, req OrderRequest) (int64, error) {
+	if req.Quantity <= 0 {
+		return 0, errors.New("order quantity must be positive")
+	}
+	params := url.Values{
+		"symbol":   {req.Symbol},
+		"side":     {req.Side},
+		"type":     {"MARKET"},
+		"quantity": {strconv.FormatFloat(req.Quantity, 'f', -1, 64)},
+	}
+	if req.PositionSide != "" {
+		params.Set("positionSide", req.PositionSide)
+	}
+	if req.ReduceOnly {
+		params.Set("reduceOnly", "true")
+	}
+	var resp struct {
+		OrderID int64 `json:"orderId"`
+	}
+	if err := c.signedPost(ctx, "/fapi/v1/order", params, &resp); err != nil {
+		return 0, err
+	}
+	return resp.OrderID, nil
+}
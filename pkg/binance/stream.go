@@ -0,0 +1,205 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultStreamURL is the USDT-M futures market stream endpoint.
+const DefaultStreamURL = "wss://fstream.binance.com/ws"
+
+const (
+	// Binance pings every three minutes and drops connections after 24 hours.
+	streamReadTimeout  = 5 * time.Minute
+	streamWriteTimeout = 10 * time.Second
+
+	streamMinBackoff = time.Second
+	streamMaxBackoff = 30 * time.Second
+)
+
+// MarkPriceUpdate is a mark price pushed by the stream.
+type MarkPriceUpdate struct {
+	Symbol string
+	Price  float64
+	Time   time.Time
+}
+
+// PriceStream keeps a WebSocket subscription to mark prices for a changing
+// set of symbols, reconnecting and resubscribing when the connection drops.
+type PriceStream struct {
+	url     string
+	updates chan MarkPriceUpdate
+
+	// OnError, if set, is called for connection errors before reconnecting.
+	OnError func(error)
+
+	mu      sync.Mutex
+	symbols map[string]bool
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	nextID  int
+}
+
+// NewPriceStream returns a stream for url. Call Run to connect.
+func NewPriceStream(url string) *PriceStream {
+	return &PriceStream{
+		url:     url,
+		updates: make(chan MarkPriceUpdate, 64),
+		symbols: make(map[string]bool),
+	}
+}
+
+// Updates delivers mark prices for the subscribed symbols. It is closed when
+// Run returns.
+func (s *PriceStream) Updates() <-chan MarkPriceUpdate {
+	return s.updates
+}
+
+// SetSymbols replaces the subscribed symbols, e.g. "BTCUSDT". Changes are
+// applied to the live connection immediately and remembered for reconnects.
+func (s *PriceStream) SetSymbols(symbols []string) {
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[symbol] = true
+	}
+
+	s.mu.Lock()
+	conn := s.conn
+	var added, removed []string
+	for symbol := range wanted {
+		if !s.symbols[symbol] {
+			added = append(added, symbol)
+		}
+	}
+	for symbol := range s.symbols {
+		if !wanted[symbol] {
+			removed = append(removed, symbol)
+		}
+	}
+	s.symbols = wanted
+	s.mu.Unlock()
+
+	if conn == nil {
+		return
+	}
+	// Write errors surface on the read side and trigger a reconnect, which
+	// resubscribes from s.symbols.
+	s.call(conn, "SUBSCRIBE", added)
+	s.call(conn, "UNSUBSCRIBE", removed)
+}
+
+// call sends a SUBSCRIBE or UNSUBSCRIBE request for the mark price streams
+// of symbols.
+func (s *PriceStream) call(conn *websocket.Conn, method string, symbols []string) error {
+	if len(symbols) == 0 {
+		return nil
+	}
+	streams := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		streams[i] = strings.ToLower(symbol) + "@markPrice@1s"
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.nextID++
+	conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return conn.WriteJSON(map[string]interface{}{"method": method, "params": streams, "id": s.nextID})
+}
+
+type markPriceEvent struct {
+	Event  string `json:"e"`
+	Time   int64  `json:"E"`
+	Symbol string `json:"s"`
+	Price  json64 `json:"p"`
+}
+
+// Run maintains the connection until ctx is canceled.
+func (s *PriceStream) Run(ctx context.Context) error {
+	defer close(s.updates)
+
+	backoff := streamMinBackoff
+	for {
+		started := time.Now()
+		err := s.session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if s.OnError != nil {
+			s.OnError(fmt.Errorf("mark price stream: %w", err))
+		}
+
+		// A connection that stayed up for a while earns a fresh backoff.
+		if time.Since(started) > time.Minute {
+			backoff = streamMinBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > streamMaxBackoff {
+			backoff = streamMaxBackoff
+		}
+	}
+}
+
+// session runs one connection until it fails or ctx is canceled.
+func (s *PriceStream) session(ctx context.Context) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, s.url, nil)
+	if err != nil {
+		return fmt.Errorf("dialing: %w", err)
+	}
+	defer conn.Close()
+	// Closing the connection unblocks the read below when ctx is canceled.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+		s.writeMu.Lock()
+		defer s.writeMu.Unlock()
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(streamWriteTimeout))
+	})
+
+	s.mu.Lock()
+	s.conn = conn
+	symbols := make([]string, 0, len(s.symbols))
+	for symbol := range s.symbols {
+		symbols = append(symbols, symbol)
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+	}()
+	if err := s.call(conn, "SUBSCRIBE", symbols); err != nil {
+		return fmt.Errorf("subscribing: %w", err)
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("reading: %w", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+
+		var ev markPriceEvent
+		if err := json.Unmarshal(data, &ev); err != nil || ev.Event != "markPriceUpdate" {
+			// Subscription acknowledgements and other messages.
+			continue
+		}
+		select {
+		case s.updates <- MarkPriceUpdate{Symbol: ev.Symbol, Price: float64(ev.Price), Time: time.UnixMilli(ev.Time)}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
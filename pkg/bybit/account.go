@@ -0,0 +1,101 @@
+package bybit
+
+import (
+	"context"
+	"net/url"
+)
+
+// Position sides. An empty side means the slot is flat.
+const (
+	SideBuy  = "Buy"
+	SideSell = "Sell"
+)
+
+// Position index values: one-way mode uses 0, hedge mode 1 for the long and
+// 2 for the short side.
+const (
+	PositionIdxOneWay    = 0
+	PositionIdxHedgeBuy  = 1
+	PositionIdxHedgeSell = 2
+)
+
+// Trade modes of a position.
+const (
+	TradeModeCross    = 0
+	TradeModeIsolated = 1
+)
+
+// Position is one position as reported by /v5/position/list.
+type Position struct {
+	Symbol      string `json:"symbol"`
+	Side        string `json:"side"` // SideBuy, SideSell or empty
+	PositionIdx int    `json:"positionIdx"`
+	// Size is in the base coin and always positive.
+	Size          number `json:"size"`
+	AvgPrice      number `json:"avgPrice"`
+	MarkPrice     number `json:"markPrice"`
+	Leverage      number `json:"leverage"`
+	TradeMode     int    `json:"tradeMode"`
+	UnrealisedPnl number `json:"unrealisedPnl"`
+	// CreatedTime is when a position was first created on the symbol, in
+	// milliseconds since the epoch. It isn't reset when the position is
+	// closed and reopened, so it isn't when the current position opened.
+	CreatedTime number `json:"createdTime"`
+}
+
+// Positions returns the linear positions settled in settleCoin, e.g. "USDT",
+// following the cursor through every page.
+func (c *Client) Positions(ctx context.Context, settleCoin string) ([]Position, error) {
+	var positions []Position
+	cursor := ""
+	for {
+		params := url.Values{"category": {CategoryLinear}, "settleCoin": {settleCoin}, "limit": {"200"}}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+		var resp struct {
+			List           []Position `json:"list"`
+			NextPageCursor string     `json:"nextPageCursor"`
+		}
+		if err := c.signedGet(ctx, "/v5/position/list", params, &resp); err != nil {
+			return nil, err
+		}
+		positions = append(positions, resp.List...)
+		if resp.NextPageCursor == "" || len(resp.List) == 0 {
+			return positions, nil
+		}
+		cursor = resp.NextPageCursor
+	}
+}
+
+// CoinBalance is the balance of one coin in a unified account.
+type CoinBalance struct {
+	Coin                string `json:"coin"`
+	Equity              number `json:"equity"`
+	WalletBalance       number `json:"walletBalance"`
+	UnrealisedPnl       number `json:"unrealisedPnl"`
+	AvailableToWithdraw number `json:"availableToWithdraw"`
+	// TotalOrderIM and TotalPositionIM are the initial margin held by open
+	// orders and positions.
+	TotalOrderIM    number `json:"totalOrderIM"`
+	TotalPositionIM number `json:"totalPositionIM"`
+}
+
+// WalletBalance returns the per-coin balances of the unified trading
+// account.
+func (c *Client) WalletBalance(ctx context.Context) ([]CoinBalance, error) {
+	var resp struct {
+		List []struct {
+			Coin []CoinBalance `json:"coin"`
+		} `json:"list"`
+	}
+	params := url.Values{"accountType": {"UNIFIED"}}
+	if err := c.signedGet(ctx, "/v5/account/wallet-balance", params, &resp); err != nil {
+		return nil, err
+	}
+	var coins []CoinBalance
+	for _, account := range resp.List {
+		coins = append(coins, account.Coin...)
+	}
+	return coins, nil
+}
@@ -0,0 +1,173 @@
+// Package bybit is a client for the Bybit V5 REST API (USDT perpetuals on a
+// unified trading account) and its public ticker stream.
+package bybit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the production V5 API endpoint.
+const DefaultBaseURL = "https://api.bybit.com"
+
+// DefaultRecvWindow is how long after its timestamp a signed request stays
+// valid.
+const DefaultRecvWindow = 5 * time.Second
+
+// Client signs and sends requests to the Bybit V5 API.
+type Client struct {
+	apiKey     string
+	secretKey  []byte
+	baseURL    string
+	httpClient *http.Client
+
+	// RecvWindow bounds the clock skew tolerated on signed requests.
+	RecvWindow time.Duration
+}
+
+// NewClient returns a Client for baseURL authenticated with the given key
+// pair. The secret is referenced rather than copied, so wiping the caller's
+// slice also wipes it from the Client.
+func NewClient(apiKey string, secretKey []byte, baseURL string) *Client {
+	return &Client{
+		apiKey:     apiKey,
+		secretKey:  secretKey,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		RecvWindow: DefaultRecvWindow,
+	}
+}
+
+// SetTransport replaces the transport the client sends requests through. It
+// must not be called while requests are in flight.
+func (c *Client) SetTransport(t http.RoundTripper) {
+	c.httpClient.Transport = t
+}
+
+// RequestError is returned when a request fails, and records which endpoint
+// it was for.
+type RequestError struct {
+	Endpoint string
+	// StatusCode is the HTTP status, or zero if no response was received.
+	StatusCode int
+	Err        error
+}
+
+func (e *RequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// sign returns the X-BAPI-SIGN signature: the hex HMAC-SHA256 of the
+// timestamp, API key, receive window and payload, which is the query string
+// for GET requests and the body for POST requests.
+func sign(secretKey []byte, timestamp, apiKey, recvWindow, payload string) string {
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte(timestamp + apiKey + recvWindow + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// envelope wraps every V5 response.
+type envelope struct {
+	RetCode int             `json:"retCode"`
+	RetMsg  string          `json:"retMsg"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// get sends a public GET request and decodes the response's result into out.
+func (c *Client) get(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, endpoint, params.Encode(), nil, false, out)
+}
+
+// signedGet is get for endpoints that need the API key.
+func (c *Client) signedGet(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, endpoint, params.Encode(), nil, true, out)
+}
+
+// signedPost sends payload as a signed JSON POST request and decodes the
+// result like get.
+func (c *Client) signedPost(ctx context.Context, endpoint string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+	return c.do(ctx, http.MethodPost, endpoint, "", body, true, out)
+}
+
+func (c *Client) do(ctx context.Context, method, endpoint, query string, body []byte, signed bool, out interface{}) error {
+	fullURL := c.baseURL + endpoint
+	if query != "" {
+		fullURL += "?" + query
+	}
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if signed {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		recvWindow := strconv.FormatInt(c.RecvWindow.Milliseconds(), 10)
+		payload := query
+		if body != nil {
+			payload = string(body)
+		}
+		req.Header.Set("X-BAPI-API-KEY", c.apiKey)
+		req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+		req.Header.Set("X-BAPI-RECV-WINDOW", recvWindow)
+		req.Header.Set("X-BAPI-SIGN", sign(c.secretKey, timestamp, c.apiKey, recvWindow, payload))
+	}
+
+	response, err := c.httpClient.Do(req)
+	if err != nil {
+		return &RequestError{Endpoint: endpoint, Err: fmt.Errorf("sending request: %w", err)}
+	}
+	defer response.Body.Close()
+
+	fail := func(err error) error {
+		return &RequestError{Endpoint: endpoint, StatusCode: response.StatusCode, Err: err}
+	}
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fail(fmt.Errorf("reading response body: %w", err))
+	}
+	// Bybit answers requests over the IP rate limit with a bare 403.
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusForbidden {
+		return fail(ErrRateLimited)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(respBody, &env); err != nil {
+		if response.StatusCode >= 300 {
+			return fail(fmt.Errorf("unexpected HTTP status %s", response.Status))
+		}
+		return fail(fmt.Errorf("decoding response JSON: %w", err))
+	}
+	if env.RetCode != 0 {
+		return fail(&APIError{Code: env.RetCode, Message: env.RetMsg})
+	}
+	if response.StatusCode >= 300 {
+		return fail(fmt.Errorf("unexpected HTTP status %s", response.Status))
+	}
+	if err := json.Unmarshal(env.Result, out); err != nil {
+		return fail(fmt.Errorf("decoding response JSON: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package bybit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	testAPIKey    = "bybitkey"
+	testSecretKey = "bybitsecret"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewClient(testAPIKey, []byte(testSecretKey), srv.URL)
+}
+
+// checkSigned reports a request whose X-BAPI-* headers don't sign payload.
+func checkSigned(t *testing.T, r *http.Request, payload string) {
+	t.Helper()
+	if got := r.Header.Get("X-BAPI-API-KEY"); got != testAPIKey {
+		t.Errorf("X-BAPI-API-KEY = %q, want %q", got, testAPIKey)
+	}
+	if got := r.Header.Get("X-BAPI-RECV-WINDOW"); got != "5000" {
+		t.Errorf("X-BAPI-RECV-WINDOW = %q, want 5000", got)
+	}
+	timestamp := r.Header.Get("X-BAPI-TIMESTAMP")
+	if timestamp == "" {
+		t.Error("X-BAPI-TIMESTAMP header missing")
+	}
+	want := sign([]byte(testSecretKey), timestamp, testAPIKey, "5000", payload)
+	if got := r.Header.Get("X-BAPI-SIGN"); got != want {
+		t.Errorf("X-BAPI-SIGN = %q, want %q", got, want)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Computed with Python's hmac module.
+	const want = "012c5566ac2025d1f9edd22b66be660f2ad137ce72086bcadf38cfbb4869d5a3"
+	if got := sign([]byte(testSecretKey), "1700000000000", testAPIKey, "5000", "category=linear&symbol=BTCUSDT"); got != want {
+		t.Errorf("sign = %s, want %s", got, want)
+	}
+}
+
+func TestSignedGet(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		checkSigned(t, r, r.URL.RawQuery)
+		w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":{"list":[{"coin":[{"coin":"USDT","equity":"1000.5","availableToWithdraw":"900"}]}]}}`))
+	})
+
+	coins, err := c.WalletBalance(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(coins) != 1 || coins[0].Coin != "USDT" || coins[0].Equity != 1000.5 || coins[0].AvailableToWithdraw != 900 {
+		t.Errorf("WalletBalance = %+v", coins)
+	}
+}
+
+func TestSignedPost(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		checkSigned(t, r, string(body))
+		var order map[string]interface{}
+		if err := json.Unmarshal(body, &order); err != nil {
+			t.Error(err)
+		}
+		if order["symbol"] != "BTCUSDT" || order["side"] != SideSell || order["qty"] != "0.002" || order["reduceOnly"] != true {
+			t.Errorf("order = %v", order)
+		}
+		w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":{"orderId":"abc"}}`))
+	})
+
+	id, err := c.PlaceMarketOrder(context.Background(), OrderRequest{Symbol: "BTCUSDT", Side: SideSell, Quantity: 0.002, ReduceOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "abc" {
+		t.Errorf("order ID = %q, want abc", id)
+	}
+}
+
+func TestPublicGetUnsigned(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-BAPI-SIGN") != "" {
+			t.Error("public request was signed")
+		}
+		w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":{"list":[{"symbol":"BTCUSDT","markPrice":"60000.5","fundingRate":""}]}}`))
+	})
+
+	ticker, err := c.Ticker(context.Background(), "BTCUSDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticker.MarkPrice != 60000.5 || ticker.FundingRate != 0 {
+		t.Errorf("Ticker = %+v", ticker)
+	}
+}
+
+func TestPositionsPagination(t *testing.T) {
+	pages := map[string]string{
+		"":   `{"list":[{"symbol":"BTCUSDT","side":"Buy","size":"0.01"},{"symbol":"ETHUSDT","side":"Sell","size":"0.5"}],"nextPageCursor":"p2"}`,
+		"p2": `{"list":[{"symbol":"SOLUSDT","side":"Buy","size":"3"}],"nextPageCursor":"p3"}`,
+		"p3": `{"list":[],"nextPageCursor":"p4"}`,
+	}
+	var cursors []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		checkSigned(t, r, r.URL.RawQuery)
+		q := r.URL.Query()
+		if q.Get("category") != CategoryLinear || q.Get("settleCoin") != "USDT" {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		cursor := q.Get("cursor")
+		cursors = append(cursors, cursor)
+		page, ok := pages[cursor]
+		if !ok {
+			t.Errorf("unexpected cursor %q", cursor)
+			page = `{"list":[]}`
+		}
+		w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":` + page + `}`))
+	})
+
+	positions, err := c.Positions(context.Background(), "USDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var symbols []string
+	for _, p := range positions {
+		symbols = append(symbols, p.Symbol)
+	}
+	if len(symbols) != 3 || symbols[0] != "BTCUSDT" || symbols[1] != "ETHUSDT" || symbols[2] != "SOLUSDT" {
+		t.Errorf("symbols = %v, want the positions of both non-empty pages", symbols)
+	}
+	// An empty page ends the walk even if it carries a cursor.
+	if len(cursors) != 3 || cursors[1] != "p2" || cursors[2] != "p3" {
+		t.Errorf("cursors requested = %q", cursors)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"invalid key", http.StatusOK, `{"retCode":10003,"retMsg":"API key is invalid."}`, ErrUnauthorized},
+		{"invalid signature", http.StatusOK, `{"retCode":10004,"retMsg":"error sign!"}`, ErrInvalidSignature},
+		{"ip not whitelisted", http.StatusOK, `{"retCode":10010,"retMsg":"Unmatched IP"}`, ErrIPNotWhitelisted},
+		{"ip rate limit", http.StatusForbidden, `access too frequent`, ErrRateLimited},
+		{"unknown code", http.StatusOK, `{"retCode":110001,"retMsg":"order not exists"}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := c.Positions(context.Background(), "USDT")
+			var reqErr *RequestError
+			if !errors.As(err, &reqErr) || reqErr.Endpoint != "/v5/position/list" || reqErr.StatusCode != tt.status {
+				t.Fatalf("err = %v, want a *RequestError for /v5/position/list with status %d", err, tt.status)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.want)
+			}
+			if tt.want == nil {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.Code != 110001 {
+					t.Errorf("err = %v, want an *APIError with code 110001", err)
+				}
+			}
+		})
+	}
+}
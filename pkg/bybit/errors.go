@@ -0,0 +1,50 @@
+package bybit
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors that API responses are mapped to. Test for them with errors.Is:
+//
+//	if errors.Is(err, bybit.ErrUnauthorized) { ... }
+var (
+	ErrUnauthorized     = errors.New("bybit: API key invalid or expired")
+	ErrInvalidSignature = errors.New("bybit: signature verification failed")
+	ErrIPNotWhitelisted = errors.New("bybit: request IP is not whitelisted for this API key")
+	ErrRequestExpired   = errors.New("bybit: request time outside the receive window; check the system clock")
+	ErrRateLimited      = errors.New("bybit: rate limited")
+	ErrPermission       = errors.New("bybit: API key lacks the required permission")
+)
+
+// errorCodes maps V5 retCode values to the errors above.
+var errorCodes = map[int]error{
+	10002: ErrRequestExpired,
+	10003: ErrUnauthorized,
+	10004: ErrInvalidSignature,
+	10005: ErrPermission,
+	10006: ErrRateLimited,
+	10010: ErrIPNotWhitelisted,
+	33004: ErrUnauthorized,
+}
+
+// APIError is a response with a non-zero retCode, e.g. 10003 for an invalid
+// API key.
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if known, ok := errorCodes[e.Code]; ok {
+		return fmt.Sprintf("%v (code %d: %s)", known, e.Code, e.Message)
+	}
+	return fmt.Sprintf("bybit: error code %d: %s", e.Code, e.Message)
+}
+
+// Is reports whether the error's code maps to target, so callers can test
+// for the exported errors without knowing the codes.
+func (e *APIError) Is(target error) bool {
+	known, ok := errorCodes[e.Code]
+	return ok && known == target
+}
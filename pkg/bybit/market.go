@@ -0,0 +1,135 @@
+package bybit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// CategoryLinear selects USDT and USDC perpetuals and futures.
+const CategoryLinear = "linear"
+
+// Ticker is a contract's latest prices and funding.
+type Ticker struct {
+	Symbol          string `json:"symbol"`
+	LastPrice       number `json:"lastPrice"`
+	MarkPrice       number `json:"markPrice"`
+	IndexPrice      number `json:"indexPrice"`
+	FundingRate     number `json:"fundingRate"`
+	NextFundingTime number `json:"nextFundingTime"` // milliseconds since the epoch
+}
+
+// Ticker returns the latest prices of a linear contract, e.g. "BTCUSDT".
+func (c *Client) Ticker(ctx context.Context, symbol string) (Ticker, error) {
+	var resp struct {
+		List []Ticker `json:"list"`
+	}
+	params := url.Values{"category": {CategoryLinear}, "symbol": {symbol}}
+	if err := c.get(ctx, "/v5/market/tickers", params, &resp); err != nil {
+		return Ticker{}, err
+	}
+	if len(resp.List) == 0 {
+		return Ticker{}, fmt.Errorf("no ticker for %s", symbol)
+	}
+	return resp.List[0], nil
+}
+
+// FundingSettlement is a settled funding rate.
+type FundingSettlement struct {
+	Symbol               string `json:"symbol"`
+	FundingRate          number `json:"fundingRate"`
+	FundingRateTimestamp number `json:"fundingRateTimestamp"` // milliseconds since the epoch
+}
+
+// LastFunding returns the latest settled funding rate of a linear contract.
+func (c *Client) LastFunding(ctx context.Context, symbol string) (FundingSettlement, error) {
+	var resp struct {
+		List []FundingSettlement `json:"list"`
+	}
+	params := url.Values{"category": {CategoryLinear}, "symbol": {symbol}, "limit": {"1"}}
+	if err := c.get(ctx, "/v5/market/funding/history", params, &resp); err != nil {
+		return FundingSettlement{}, err
+	}
+	if len(resp.List) == 0 {
+		return FundingSettlement{}, fmt.Errorf("no funding settlements for %s", symbol)
+	}
+	return resp.List[0], nil
+}
+
+// Instrument describes a linear contract's trading parameters.
+type Instrument struct {
+	Symbol    string `json:"symbol"`
+	BaseCoin  string `json:"baseCoin"`
+	QuoteCoin string `json:"quoteCoin"`
+	// FundingInterval is the time between funding settlements in minutes.
+	FundingInterval int `json:"fundingInterval"`
+	LotSizeFilter   struct {
+		QtyStep number `json:"qtyStep"`
+	} `json:"lotSizeFilter"`
+}
+
+// Instrument returns the trading parameters of a linear contract.
+func (c *Client) Instrument(ctx context.Context, symbol string) (Instrument, error) {
+	var resp struct {
+		List []Instrument `json:"list"`
+	}
+	params := url.Values{"category": {CategoryLinear}, "symbol": {symbol}}
+	if err := c.get(ctx, "/v5/market/instruments-info", params, &resp); err != nil {
+		return Instrument{}, err
+	}
+	if len(resp.List) == 0 {
+		return Instrument{}, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	return resp.List[0], nil
+}
+
+// OrderBook is a depth snapshot, best prices first. Each level is a
+// [price, size] pair.
+type OrderBook struct {
+	Bids [][2]float64
+	Asks [][2]float64
+}
+
+// OrderBook returns up to limit levels per side (at most 500) of the order
+// book of a linear contract.
+func (c *Client) OrderBook(ctx context.Context, symbol string, limit int) (OrderBook, error) {
+	var resp struct {
+		Bids [][2]number `json:"b"`
+		Asks [][2]number `json:"a"`
+	}
+	params := url.Values{"category": {CategoryLinear}, "symbol": {symbol}, "limit": {strconv.Itoa(limit)}}
+	if err := c.get(ctx, "/v5/market/orderbook", params, &resp); err != nil {
+		return OrderBook{}, err
+	}
+	return OrderBook{Bids: toLevels(resp.Bids), Asks: toLevels(resp.Asks)}, nil
+}
+
+func toLevels(raw [][2]number) [][2]float64 {
+	levels := make([][2]float64, len(raw))
+	for i, l := range raw {
+		levels[i] = [2]float64{float64(l[0]), float64(l[1])}
+	}
+	return levels
+}
+
+// number decodes a float64 that Bybit sends as a JSON string, which is empty
+// for values that don't apply.
+type number float64
+
+func (n *number) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if len(s) >= 2 && s[0] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*n = number(v)
+	return nil
+}
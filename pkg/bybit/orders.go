@@ -0,0 +1,41 @@
+package bybit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// OrderRequest is a new market order on a linear contract.
+type OrderRequest struct {
+	Symbol string
+	Side   string // SideBuy or SideSell
+	// PositionIdx selects the hedge mode side; zero in one-way mode.
+	PositionIdx int
+	Quantity    float64 // in the base coin
+	// ReduceOnly stops the order from opening or growing a position.
+	ReduceOnly bool
+}
+
+// PlaceMarketOrder places req as a market order and returns its order ID.
+func (c *Client) PlaceMarketOrder(ctx context.Context, req OrderRequest) (string, error) {
+	if req.Quantity <= 0 {
+		return "", errors.New("order quantity must be positive")
+	}
+	payload := map[string]interface{}{
+		"category":    CategoryLinear,
+		"symbol":      req.Symbol,
+		"side":        req.Side,
+		"orderType":   "Market",
+		"qty":         strconv.FormatFloat(req.Quantity, 'f', -1, 64),
+		"positionIdx": req.PositionIdx,
+		"reduceOnly":  req.ReduceOnly,
+	}
+	var resp struct {
+		OrderID string `json:"orderId"`
+	}
+	if err := c.signedPost(ctx, "/v5/order/create", payload, &resp); err != nil {
+		return "", err
+	}
+	return resp.OrderID, nil
+}
@@ -0,0 +1,233 @@
+package bybit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultStreamURL is the public stream endpoint for linear contracts.
+const DefaultStreamURL = "wss://stream.bybit.com/v5/public/linear"
+
+const (
+	streamReadTimeout  = time.Minute
+	streamWriteTimeout = 10 * time.Second
+	streamMaxBackoff   = 30 * time.Second
+
+	// subscribeBatch caps the topics per subscribe request.
+	subscribeBatch = 10
+)
+
+// Variables so tests can shorten them.
+var (
+	// Bybit drops connections that stay silent for longer than about 30
+	// seconds, so the client pings well within that.
+	streamPingInterval = 20 * time.Second
+	streamMinBackoff   = time.Second
+)
+
+// MarkPriceUpdate is a mark price pushed by the stream.
+type MarkPriceUpdate struct {
+	Symbol string
+	Price  float64
+	Time   time.Time
+}
+
+// PriceStream keeps a WebSocket subscription to the tickers of a changing set
+// of symbols, reconnecting and resubscribing when the connection drops.
+type PriceStream struct {
+	url     string
+	updates chan MarkPriceUpdate
+
+	// OnError, if set, is called for connection errors before reconnecting.
+	OnError func(error)
+
+	mu      sync.Mutex
+	symbols map[string]bool
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// NewPriceStream returns a stream for url. Call Run to connect.
+func NewPriceStream(url string) *PriceStream {
+	return &PriceStream{
+		url:     url,
+		updates: make(chan MarkPriceUpdate, 64),
+		symbols: make(map[string]bool),
+	}
+}
+
+// Updates delivers mark prices for the subscribed symbols. It is closed when
+// Run returns.
+func (s *PriceStream) Updates() <-chan MarkPriceUpdate {
+	return s.updates
+}
+
+// SetSymbols replaces the subscribed symbols, e.g. "BTCUSDT". Changes are
+// applied to the live connection immediately and remembered for reconnects.
+func (s *PriceStream) SetSymbols(symbols []string) {
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[symbol] = true
+	}
+
+	s.mu.Lock()
+	conn := s.conn
+	var added, removed []string
+	for symbol := range wanted {
+		if !s.symbols[symbol] {
+			added = append(added, symbol)
+		}
+	}
+	for symbol := range s.symbols {
+		if !wanted[symbol] {
+			removed = append(removed, symbol)
+		}
+	}
+	s.symbols = wanted
+	s.mu.Unlock()
+
+	if conn == nil {
+		return
+	}
+	// Write errors surface on the read side and trigger a reconnect, which
+	// resubscribes from s.symbols.
+	s.call(conn, "subscribe", added)
+	s.call(conn, "unsubscribe", removed)
+}
+
+// call sends subscribe or unsubscribe requests for the ticker topics of
+// symbols.
+func (s *PriceStream) call(conn *websocket.Conn, op string, symbols []string) error {
+	for len(symbols) > 0 {
+		n := min(len(symbols), subscribeBatch)
+		topics := make([]string, n)
+		for i, symbol := range symbols[:n] {
+			topics[i] = "tickers." + symbol
+		}
+		symbols = symbols[n:]
+		if err := s.write(conn, map[string]interface{}{"op": op, "args": topics}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PriceStream) write(conn *websocket.Conn, msg interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return conn.WriteJSON(msg)
+}
+
+type tickerMessage struct {
+	Topic string `json:"topic"`
+	Time  int64  `json:"ts"`
+	Data  struct {
+		Symbol    string `json:"symbol"`
+		MarkPrice number `json:"markPrice"`
+	} `json:"data"`
+}
+
+// Run maintains the connection until ctx is canceled.
+func (s *PriceStream) Run(ctx context.Context) error {
+	defer close(s.updates)
+
+	backoff := streamMinBackoff
+	for {
+		started := time.Now()
+		err := s.session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if s.OnError != nil {
+			s.OnError(fmt.Errorf("ticker stream: %w", err))
+		}
+
+		// A connection that stayed up for a while earns a fresh backoff.
+		if time.Since(started) > time.Minute {
+			backoff = streamMinBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > streamMaxBackoff {
+			backoff = streamMaxBackoff
+		}
+	}
+}
+
+// session runs one connection until it fails or ctx is canceled.
+func (s *PriceStream) session(ctx context.Context) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, s.url, nil)
+	if err != nil {
+		return fmt.Errorf("dialing: %w", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Closing the connection unblocks the read below when ctx is canceled.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	s.mu.Lock()
+	s.conn = conn
+	symbols := make([]string, 0, len(s.symbols))
+	for symbol := range s.symbols {
+		symbols = append(symbols, symbol)
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+	}()
+	if err := s.call(conn, "subscribe", symbols); err != nil {
+		return fmt.Errorf("subscribing: %w", err)
+	}
+
+	ticker := time.NewTicker(streamPingInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.write(conn, map[string]string{"op": "ping"}); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("reading: %w", err)
+		}
+
+		var msg tickerMessage
+		if err := json.Unmarshal(data, &msg); err != nil || !strings.HasPrefix(msg.Topic, "tickers.") {
+			// Pongs and subscription acknowledgements.
+			continue
+		}
+		// Deltas only carry the fields that changed.
+		if msg.Data.MarkPrice == 0 {
+			continue
+		}
+		select {
+		case s.updates <- MarkPriceUpdate{Symbol: msg.Data.Symbol, Price: float64(msg.Data.MarkPrice), Time: time.UnixMilli(msg.Time)}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package bybit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newFakeWS starts a WebSocket server handing each connection to the test
+// through the returned channel, and returns its URL.
+func newFakeWS(t *testing.T) (<-chan *websocket.Conn, string) {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 4)
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(srv.Close)
+	return conns, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func accept(t *testing.T, conns <-chan *websocket.Conn) *websocket.Conn {
+	t.Helper()
+	select {
+	case conn := <-conns:
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("client didn't connect")
+		return nil
+	}
+}
+
+type wsRequest struct {
+	Op   string   `json:"op"`
+	Args []string `json:"args"`
+}
+
+// readRequest returns the next request the client sends on conn.
+func readRequest(t *testing.T, conn *websocket.Conn) wsRequest {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var req wsRequest
+	if err := conn.ReadJSON(&req); err != nil {
+		t.Fatalf("reading client request: %v", err)
+	}
+	return req
+}
+
+// expectRequest fails unless the client's next request is op for topics, in
+// any order.
+func expectRequest(t *testing.T, conn *websocket.Conn, op string, topics ...string) {
+	t.Helper()
+	req := readRequest(t, conn)
+	sort.Strings(req.Args)
+	sort.Strings(topics)
+	if req.Op != op || strings.Join(req.Args, ",") != strings.Join(topics, ",") {
+		t.Fatalf("request = %+v, want %s %v", req, op, topics)
+	}
+}
+
+// startStream runs s with the ping interval set to ping and a millisecond
+// reconnect backoff until the test ends.
+func startStream(t *testing.T, s *PriceStream, ping time.Duration) {
+	t.Helper()
+	oldPing, oldBackoff := streamPingInterval, streamMinBackoff
+	streamPingInterval, streamMinBackoff = ping, time.Millisecond
+	t.Cleanup(func() { streamPingInterval, streamMinBackoff = oldPing, oldBackoff })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("Run = %v, want context.Canceled", err)
+		}
+	})
+}
+
+func TestPriceStreamResubscribes(t *testing.T) {
+	conns, url := newFakeWS(t)
+	s := NewPriceStream(url)
+	errs := make(chan error, 16)
+	s.OnError = func(err error) { errs <- err }
+	s.SetSymbols([]string{"BTCUSDT"})
+	startStream(t, s, time.Hour)
+
+	conn := accept(t, conns)
+	expectRequest(t, conn, "subscribe", "tickers.BTCUSDT")
+	conn.WriteJSON(map[string]interface{}{"success": true, "op": "subscribe"})
+	conn.WriteJSON(map[string]interface{}{"topic": "tickers.BTCUSDT", "type": "delta", "ts": 1700000000000, "data": map[string]string{"symbol": "BTCUSDT", "fundingRate": "0.0001"}})
+	conn.WriteJSON(map[string]interface{}{"topic": "tickers.BTCUSDT", "type": "delta", "ts": 1700000000000, "data": map[string]string{"symbol": "BTCUSDT", "markPrice": "60000.5"}})
+	select {
+	case u := <-s.Updates():
+		// The delta without a mark price was skipped.
+		if u.Symbol != "BTCUSDT" || u.Price != 60000.5 || !u.Time.Equal(time.UnixMilli(1700000000000)) {
+			t.Errorf("update = %+v", u)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no price update")
+	}
+
+	// Changes apply to the live connection, in batches of subscribeBatch.
+	symbols := make([]string, subscribeBatch+1)
+	topics := make([]string, len(symbols))
+	for i := range symbols {
+		symbols[i] = fmt.Sprintf("COIN%dUSDT", i)
+		topics[i] = "tickers." + symbols[i]
+	}
+	s.SetSymbols(symbols)
+	first := readRequest(t, conn)
+	if first.Op != "subscribe" || len(first.Args) != subscribeBatch {
+		t.Fatalf("request = %+v, want a full batch of subscriptions", first)
+	}
+	second := readRequest(t, conn)
+	got := append(first.Args, second.Args...)
+	sort.Strings(got)
+	sort.Strings(topics)
+	if second.Op != "subscribe" || strings.Join(got, ",") != strings.Join(topics, ",") {
+		t.Errorf("subscribed to %v, want %v", got, topics)
+	}
+	expectRequest(t, conn, "unsubscribe", "tickers.BTCUSDT")
+
+	s.SetSymbols([]string{"ETHUSDT"})
+	expectRequest(t, conn, "subscribe", "tickers.ETHUSDT")
+	for i := 0; i < 2; i++ {
+		if req := readRequest(t, conn); req.Op != "unsubscribe" {
+			t.Fatalf("request = %+v, want an unsubscribe", req)
+		}
+	}
+
+	// A dropped connection is redialed and subscribed to the current set.
+	conn.Close()
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "ticker stream: reading") {
+			t.Errorf("error = %v, want a read error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error reported")
+	}
+	conn = accept(t, conns)
+	expectRequest(t, conn, "subscribe", "tickers.ETHUSDT")
+}
+
+func TestStreamPings(t *testing.T) {
+	conns, url := newFakeWS(t)
+	s := NewPriceStream(url)
+	startStream(t, s, 10*time.Millisecond)
+
+	conn := accept(t, conns)
+	for i := 0; i < 2; i++ {
+		if req := readRequest(t, conn); req.Op != "ping" {
+			t.Fatalf("request = %+v, want a ping", req)
+		}
+		// Pongs are read and dropped without closing the connection.
+		conn.WriteJSON(map[string]interface{}{"success": true, "ret_msg": "pong", "op": "ping"})
+	}
+	select {
+	case u := <-s.Updates():
+		t.Errorf("update %+v from a pong", u)
+	default:
+	}
+}
@@ -0,0 +1,373 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/binance"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// Binance is the Exchange for Binance USDT-M futures.
+//
+// Symbols are translated to the MEXC form, "BTCUSDT" becoming "BTC_USDT", and
+// sizes to contracts of one quantity step each, so positions compare and
+// alert the same way on both exchanges.
+type Binance struct {
+	*binance.Client
+	streamURL string
+
+	// OnStreamError, if set, is passed to price streams as their OnError.
+	OnStreamError func(error)
+	// OnPositionError, if set, is called for each position OpenPositions
+	// skips because it can't be converted, with the native symbol.
+	OnPositionError func(symbol string, err error)
+
+	mu      sync.Mutex
+	symbols map[string]binance.SymbolInfo // by native symbol
+	fetched time.Time                     // when symbols was last fetched
+
+	fundingMu      sync.Mutex
+	intervals      map[string]int // funding interval in hours by native symbol
+	fundingFetched time.Time      // when intervals was last fetched
+}
+
+// exchangeInfoRefetch is how long after fetching the contract list an
+// unknown symbol may fetch it again, so that a symbol that really doesn't
+// exist doesn't fetch it on every call.
+const exchangeInfoRefetch = time.Minute
+
+// fundingInfoRefetch is how long funding intervals are cached. Binance
+// shortens a contract's interval while its funding rate is at the cap.
+const fundingInfoRefetch = time.Hour
+
+// defaultFundingInterval is the funding interval in hours of contracts
+// fundingInfo doesn't list.
+const defaultFundingInterval = 8
+
+// NewBinance returns an Exchange backed by client, streaming prices from
+// streamURL (binance.DefaultStreamURL when empty).
+func NewBinance(client *binance.Client, streamURL string) *Binance {
+	if streamURL == "" {
+		streamURL = binance.DefaultStreamURL
+	}
+	return &Binance{Client: client, streamURL: streamURL}
+}
+
+func (b *Binance) Name() string { return "Binance" }
+
+// symbolInfo returns the trading parameters of a native symbol. The contract
+// list is cached, and fetched again when it doesn't have the symbol, which
+// may have been listed since.
+func (b *Binance) symbolInfo(ctx context.Context, native string) (binance.SymbolInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if info, ok := b.symbols[native]; ok {
+		return info, nil
+	}
+	if b.symbols == nil || time.Since(b.fetched) >= exchangeInfoRefetch {
+		infos, err := b.ExchangeInfo(ctx)
+		if err != nil {
+			return binance.SymbolInfo{}, fmt.Errorf("fetching exchange info: %w", err)
+		}
+		b.symbols = make(map[string]binance.SymbolInfo, len(infos))
+		for _, info := range infos {
+			b.symbols[info.Symbol] = info
+		}
+		b.fetched = time.Now()
+	}
+	info, ok := b.symbols[native]
+	if !ok {
+		return binance.SymbolInfo{}, fmt.Errorf("unknown symbol %s", native)
+	}
+	return info, nil
+}
+
+// symbol converts a native symbol to the MEXC form.
+func (b *Binance) symbol(ctx context.Context, native string) string {
+	info, err := b.symbolInfo(ctx, native)
+	if err != nil || info.BaseAsset == "" || info.QuoteAsset == "" {
+		return native
+	}
+	return info.BaseAsset + "_" + info.QuoteAsset
+}
+
+// native converts a symbol in the MEXC form to Binance's.
+func native(symbol string) string {
+	return strings.ReplaceAll(symbol, "_", "")
+}
+
+// ContractSize implements ContractSizer. A contract is one quantity step of
+// the symbol.
+func (b *Binance) ContractSize(ctx context.Context, symbol string) (float64, error) {
+	info, err := b.symbolInfo(ctx, native(symbol))
+	if err != nil {
+		return 0, err
+	}
+	step := info.StepSize()
+	if step <= 0 {
+		return 1, nil
+	}
+	return step, nil
+}
+
+// OpenPositions implements Exchange. A position whose symbol can't be
+// looked up is left out and passed to OnPositionError, rather than failing
+// the others.
+func (b *Binance) OpenPositions(ctx context.Context) ([]Position, error) {
+	risks, err := b.PositionRisk(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var positions []Position
+	for _, r := range risks {
+		if r.PositionAmt == 0 {
+			continue
+		}
+		symbol := b.symbol(ctx, r.Symbol)
+		size, err := b.ContractSize(ctx, symbol)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			if b.OnPositionError != nil {
+				b.OnPositionError(r.Symbol, fmt.Errorf("fetching contract size: %w", err))
+			}
+			continue
+		}
+
+		p := Position{
+			Symbol:       symbol,
+			PositionType: mexc.PositionTypeLong,
+			State:        mexc.PositionStateHolding,
+			HoldVol:      math.Round(math.Abs(r.PositionAmt) / size),
+			HoldAvgPrice: r.EntryPrice,
+			Leverage:     r.Leverage,
+			OpenType:     mexc.OpenTypeCross,
+			// Binance doesn't report when a position was opened, and its
+			// updateTime moves with every fill and funding payment, so
+			// CreateTime stays unset and stale position nudges skip it.
+		}
+		if r.PositionSide == binance.PositionSideShort || (r.PositionSide != binance.PositionSideLong && r.PositionAmt < 0) {
+			p.PositionType = mexc.PositionTypeShort
+		}
+		if r.MarginType == "isolated" {
+			p.OpenType = mexc.OpenTypeIsolated
+		}
+		positions = append(positions, p)
+	}
+	return positions, nil
+}
+
+// FairPrice implements Exchange. It returns the mark price, which is what
+// Binance liquidates at.
+func (b *Binance) FairPrice(ctx context.Context, symbol string) (float64, error) {
+	index, err := b.PremiumIndex(ctx, native(symbol))
+	if err != nil {
+		return 0, err
+	}
+	return index.MarkPrice, nil
+}
+
+// Balances implements Exchange.
+func (b *Binance) Balances(ctx context.Context) ([]Balance, error) {
+	wallet, err := b.Client.Balances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]Balance, 0, len(wallet))
+	for _, w := range wallet {
+		balances = append(balances, Balance{
+			Currency:   w.Asset,
+			Equity:     w.Balance + w.CrossUnPnl,
+			Available:  w.AvailableBalance,
+			Unrealized: w.CrossUnPnl,
+		})
+	}
+	return balances, nil
+}
+
+// PlaceOrder implements Exchange. Only market orders are supported; the
+// position side is sent in hedge mode and the close sides become reduce-only
+// orders in one-way mode.
+func (b *Binance) PlaceOrder(ctx context.Context, req OrderRequest) (string, error) {
+	if req.Type != mexc.OrderTypeMarket {
+		return "", errors.New("binance: only market orders are supported")
+	}
+	size, err := b.ContractSize(ctx, req.Symbol)
+	if err != nil {
+		return "", fmt.Errorf("fetching contract size: %w", err)
+	}
+	hedge, err := b.HedgeMode(ctx)
+	if err != nil {
+		return "", fmt.Errorf("fetching position mode: %w", err)
+	}
+
+	order := binance.OrderRequest{Symbol: native(req.Symbol), Quantity: req.Vol * size}
+	var positionSide string
+	switch req.Side {
+	case mexc.OrderSideOpenLong:
+		order.Side, positionSide = binance.SideBuy, binance.PositionSideLong
+	case mexc.OrderSideCloseLong:
+		order.Side, positionSide = binance.SideSell, binance.PositionSideLong
+		order.ReduceOnly = !hedge
+	case mexc.OrderSideOpenShort:
+		order.Side, positionSide = binance.SideSell, binance.PositionSideShort
+	case mexc.OrderSideCloseShort:
+		order.Side, positionSide = binance.SideBuy, binance.PositionSideShort
+		order.ReduceOnly = !hedge
+	default:
+		return "", fmt.Errorf("binance: unknown order side %d", req.Side)
+	}
+	if hedge {
+		order.PositionSide = positionSide
+	}
+
+	id, err := b.PlaceMarketOrder(ctx, order)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+// depthLimits are the order book sizes Binance serves.
+var depthLimits = []int{5, 10, 20, 50, 100, 500, 1000}
+
+// Depth implements DepthSource. Volumes are in contracts, like MEXC's.
+func (b *Binance) Depth(ctx context.Context, symbol string, limit int) (OrderBook, error) {
+	size, err := b.ContractSize(ctx, symbol)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("fetching contract size: %w", err)
+	}
+	allowed := depthLimits[len(depthLimits)-1]
+	for _, l := range depthLimits {
+		if l >= limit {
+			allowed = l
+			break
+		}
+	}
+	book, err := b.Client.Depth(ctx, native(symbol), allowed)
+	if err != nil {
+		return OrderBook{}, err
+	}
+	toLevels := func(raw [][2]float64) []mexc.Level {
+		if len(raw) > limit {
+			raw = raw[:limit]
+		}
+		levels := make([]mexc.Level, len(raw))
+		for i, l := range raw {
+			levels[i] = mexc.Level{Price: l[0], Volume: l[1] / size}
+		}
+		return levels
+	}
+	return OrderBook{Bids: toLevels(book.Bids), Asks: toLevels(book.Asks)}, nil
+}
+
+// FundingRate implements FundingSource.
+func (b *Binance) FundingRate(ctx context.Context, symbol string) (FundingRate, error) {
+	index, err := b.PremiumIndex(ctx, native(symbol))
+	if err != nil {
+		return FundingRate{}, err
+	}
+	cycle, err := b.fundingInterval(ctx, native(symbol))
+	if err != nil {
+		return FundingRate{}, err
+	}
+	return FundingRate{
+		Symbol:         symbol,
+		Rate:           index.LastFundingRate,
+		CollectCycle:   cycle,
+		NextSettleTime: index.NextFundingTime,
+	}, nil
+}
+
+// fundingInterval returns the funding interval in hours of a native symbol.
+func (b *Binance) fundingInterval(ctx context.Context, native string) (int, error) {
+	b.fundingMu.Lock()
+	defer b.fundingMu.Unlock()
+	if b.intervals == nil || time.Since(b.fundingFetched) >= fundingInfoRefetch {
+		infos, err := b.FundingInfo(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("fetching funding info: %w", err)
+		}
+		b.intervals = make(map[string]int, len(infos))
+		for _, info := range infos {
+			if info.FundingIntervalHours > 0 {
+				b.intervals[info.Symbol] = info.FundingIntervalHours
+			}
+		}
+		b.fundingFetched = time.Now()
+	}
+	if hours, ok := b.intervals[native]; ok {
+		return hours, nil
+	}
+	return defaultFundingInterval, nil
+}
+
+// LastFunding implements FundingHistorySource.
+func (b *Binance) LastFunding(ctx context.Context, symbol string) (FundingSettlement, error) {
+	s, err := b.Client.LastFunding(ctx, native(symbol))
+	if err != nil {
+		return FundingSettlement{}, err
+	}
+	return FundingSettlement{Symbol: symbol, Rate: s.FundingRate, SettleTime: s.FundingTime}, nil
+}
+
+// StreamPrices implements Exchange.
+func (b *Binance) StreamPrices() PriceStream {
+	stream := binance.NewPriceStream(b.streamURL)
+	stream.OnError = b.OnStreamError
+	return &binanceStream{
+		stream:  stream,
+		updates: make(chan PriceUpdate, 64),
+		symbols: make(map[string]string),
+	}
+}
+
+// binanceStream translates symbols between the bot and a binance.PriceStream.
+type binanceStream struct {
+	stream  *binance.PriceStream
+	updates chan PriceUpdate
+
+	mu      sync.Mutex
+	symbols map[string]string // native to MEXC form
+}
+
+func (s *binanceStream) Updates() <-chan PriceUpdate { return s.updates }
+
+func (s *binanceStream) SetSymbols(symbols []string) {
+	natives := make([]string, len(symbols))
+	s.mu.Lock()
+	for i, symbol := range symbols {
+		natives[i] = native(symbol)
+		s.symbols[natives[i]] = symbol
+	}
+	s.mu.Unlock()
+	s.stream.SetSymbols(natives)
+}
+
+func (s *binanceStream) Run(ctx context.Context) error {
+	defer close(s.updates)
+	done := make(chan error, 1)
+	go func() { done <- s.stream.Run(ctx) }()
+
+	for u := range s.stream.Updates() {
+		s.mu.Lock()
+		symbol, ok := s.symbols[u.Symbol]
+		s.mu.Unlock()
+		if !ok {
+			continue
+		}
+		select {
+		case s.updates <- PriceUpdate{Symbol: symbol, Price: u.Price, Time: u.Time}:
+		case <-ctx.Done():
+		}
+	}
+	return <-done
+}
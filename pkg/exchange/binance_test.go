@@ -0,0 +1,190 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/binance"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// fakeBinance serves the Binance endpoints the adapter reads from.
+type fakeBinance struct {
+	mu        sync.Mutex
+	listed    []string // base assets quoted in USDT
+	positions string   // positionRisk response
+	funding   string   // fundingInfo response
+	infoCalls int
+
+	fundingCalls int
+}
+
+func (f *fakeBinance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/fapi/v1/exchangeInfo":
+		f.infoCalls++
+		var symbols []string
+		for _, base := range f.listed {
+			symbols = append(symbols, fmt.Sprintf(`{"symbol":"%sUSDT","baseAsset":%q,"quoteAsset":"USDT","filters":[{"filterType":"LOT_SIZE","stepSize":"0.001"}]}`, base, base))
+		}
+		fmt.Fprintf(w, `{"symbols":[%s]}`, strings.Join(symbols, ","))
+	case "/fapi/v2/positionRisk":
+		w.Write([]byte(f.positions))
+	case "/fapi/v1/fundingInfo":
+		f.fundingCalls++
+		w.Write([]byte(f.funding))
+	case "/fapi/v1/premiumIndex":
+		fmt.Fprintf(w, `{"symbol":%q,"markPrice":"60000","lastFundingRate":"0.0001","nextFundingTime":1700000000000}`, r.URL.Query().Get("symbol"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":-1,"msg":"not found"}`))
+	}
+}
+
+func (f *fakeBinance) list(base string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listed = append(f.listed, base)
+}
+
+func newTestBinance(t *testing.T, f *fakeBinance) *Binance {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return NewBinance(binance.NewClient("key", []byte("secret"), srv.URL), "")
+}
+
+func TestBinanceOpenPositionsNewSymbol(t *testing.T) {
+	f := &fakeBinance{
+		listed: []string{"BTC"},
+		positions: `[
+			{"symbol":"BTCUSDT","positionAmt":"0.010","entryPrice":"60000","leverage":"10","marginType":"cross","positionSide":"BOTH","updateTime":1700000000000},
+			{"symbol":"NEWUSDT","positionAmt":"-2.000","entryPrice":"1.5","leverage":"5","marginType":"isolated","positionSide":"BOTH","updateTime":1700000000000}
+		]`,
+	}
+	b := newTestBinance(t, f)
+	var skipped []string
+	b.OnPositionError = func(symbol string, err error) { skipped = append(skipped, symbol) }
+
+	positions, err := b.OpenPositions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 1 || positions[0].Symbol != "BTC_USDT" || positions[0].HoldVol != 10 {
+		t.Fatalf("positions = %+v, want only BTC_USDT with 10 contracts", positions)
+	}
+	if positions[0].CreateTime != 0 {
+		t.Errorf("CreateTime = %d, want 0 as Binance doesn't report the open time", positions[0].CreateTime)
+	}
+	if len(skipped) != 1 || skipped[0] != "NEWUSDT" {
+		t.Errorf("skipped = %v, want [NEWUSDT]", skipped)
+	}
+	if f.infoCalls != 1 {
+		t.Errorf("exchange info fetched %d times, want once within the refetch interval", f.infoCalls)
+	}
+
+	// NEWUSDT is listed and the refetch interval has passed.
+	f.list("NEW")
+	b.mu.Lock()
+	b.fetched = b.fetched.Add(-exchangeInfoRefetch)
+	b.mu.Unlock()
+	skipped = nil
+
+	positions, err = b.OpenPositions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 2 || len(skipped) != 0 {
+		t.Fatalf("positions = %+v, skipped = %v, want both positions", positions, skipped)
+	}
+	short := positions[1]
+	if short.Symbol != "NEW_USDT" || short.PositionType != mexc.PositionTypeShort || short.HoldVol != 2000 || short.OpenType != mexc.OpenTypeIsolated {
+		t.Errorf("positions[1] = %+v, want an isolated NEW_USDT short of 2000 contracts", short)
+	}
+	if f.infoCalls != 2 {
+		t.Errorf("exchange info fetched %d times, want twice", f.infoCalls)
+	}
+
+	// Known symbols are served from the cache.
+	if _, err := b.ContractSize(context.Background(), "NEW_USDT"); err != nil {
+		t.Fatal(err)
+	}
+	if f.infoCalls != 2 {
+		t.Errorf("exchange info fetched %d times for a cached symbol", f.infoCalls)
+	}
+}
+
+func TestBinanceUnknownSymbolRefetch(t *testing.T) {
+	f := &fakeBinance{listed: []string{"BTC"}}
+	b := newTestBinance(t, f)
+
+	for i := 0; i < 3; i++ {
+		if _, err := b.ContractSize(context.Background(), "NOPE_USDT"); err == nil {
+			t.Fatal("ContractSize succeeded for an unlisted symbol")
+		}
+	}
+	if f.infoCalls != 1 {
+		t.Errorf("exchange info fetched %d times, want once", f.infoCalls)
+	}
+
+	b.mu.Lock()
+	b.fetched = time.Now().Add(-exchangeInfoRefetch)
+	b.mu.Unlock()
+	b.ContractSize(context.Background(), "NOPE_USDT")
+	if f.infoCalls != 2 {
+		t.Errorf("exchange info fetched %d times, want a refetch after the interval", f.infoCalls)
+	}
+}
+
+func TestBinanceFundingInterval(t *testing.T) {
+	f := &fakeBinance{funding: `[
+		{"symbol":"BTCUSDT","adjustedFundingRateCap":"0.02","adjustedFundingRateFloor":"-0.02","fundingIntervalHours":4},
+		{"symbol":"NEWUSDT","adjustedFundingRateCap":"0.03","adjustedFundingRateFloor":"-0.03","fundingIntervalHours":1}
+	]`}
+	b := newTestBinance(t, f)
+
+	tests := []struct {
+		symbol string
+		want   int
+	}{
+		{"BTC_USDT", 4},
+		{"NEW_USDT", 1},
+		{"ETH_USDT", defaultFundingInterval},
+	}
+	for _, tt := range tests {
+		rate, err := b.FundingRate(context.Background(), tt.symbol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rate.Symbol != tt.symbol || rate.CollectCycle != tt.want || rate.Rate != 0.0001 {
+			t.Errorf("FundingRate(%s) = %+v, want a %d hour cycle", tt.symbol, rate, tt.want)
+		}
+	}
+	if f.fundingCalls != 1 {
+		t.Errorf("funding info fetched %d times, want once", f.fundingCalls)
+	}
+
+	// The interval went back to 8 hours and the cache expired.
+	f.mu.Lock()
+	f.funding = `[]`
+	f.mu.Unlock()
+	b.fundingMu.Lock()
+	b.fundingFetched = b.fundingFetched.Add(-fundingInfoRefetch)
+	b.fundingMu.Unlock()
+
+	rate, err := b.FundingRate(context.Background(), "BTC_USDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate.CollectCycle != defaultFundingInterval || f.fundingCalls != 2 {
+		t.Errorf("CollectCycle = %d after %d fetches, want %d after a refetch", rate.CollectCycle, f.fundingCalls, defaultFundingInterval)
+	}
+}
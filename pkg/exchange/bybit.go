@@ -0,0 +1,323 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/bybit"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// bybitSettleCoins are the settlement coins whose linear positions are read.
+var bybitSettleCoins = []string{"USDT", "USDC"}
+
+// Bybit is the Exchange for linear contracts on a Bybit unified trading
+// account.
+//
+// As with Binance, symbols are translated to the MEXC form, "BTCUSDT"
+// becoming "BTC_USDT", and sizes to contracts of one quantity step each.
+type Bybit struct {
+	*bybit.Client
+	streamURL string
+
+	// OnStreamError, if set, is passed to price streams as their OnError.
+	OnStreamError func(error)
+
+	mu          sync.Mutex
+	instruments map[string]bybit.Instrument // by native symbol
+	natives     map[string]string           // native symbols by MEXC form
+}
+
+// NewBybit returns an Exchange backed by client, streaming prices from
+// streamURL (bybit.DefaultStreamURL when empty).
+func NewBybit(client *bybit.Client, streamURL string) *Bybit {
+	if streamURL == "" {
+		streamURL = bybit.DefaultStreamURL
+	}
+	return &Bybit{Client: client, streamURL: streamURL, instruments: make(map[string]bybit.Instrument), natives: make(map[string]string)}
+}
+
+func (b *Bybit) Name() string { return "Bybit" }
+
+// instrument returns the trading parameters of a native symbol, caching
+// them.
+func (b *Bybit) instrument(ctx context.Context, native string) (bybit.Instrument, error) {
+	b.mu.Lock()
+	info, ok := b.instruments[native]
+	b.mu.Unlock()
+	if ok {
+		return info, nil
+	}
+	info, err := b.Instrument(ctx, native)
+	if err != nil {
+		return bybit.Instrument{}, fmt.Errorf("fetching instrument info: %w", err)
+	}
+	b.mu.Lock()
+	b.instruments[native] = info
+	b.mu.Unlock()
+	return info, nil
+}
+
+// native converts a symbol in the MEXC form to Bybit's. USDC contracts don't
+// follow the BASEQUOTE pattern ("BTCPERP"), so symbols seen in positions are
+// looked up first.
+func (b *Bybit) native(symbol string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n, ok := b.natives[symbol]; ok {
+		return n
+	}
+	return native(symbol)
+}
+
+// ContractSize implements ContractSizer. A contract is one quantity step of
+// the symbol.
+func (b *Bybit) ContractSize(ctx context.Context, symbol string) (float64, error) {
+	info, err := b.instrument(ctx, b.native(symbol))
+	if err != nil {
+		return 0, err
+	}
+	if step := float64(info.LotSizeFilter.QtyStep); step > 0 {
+		return step, nil
+	}
+	return 1, nil
+}
+
+// positions returns the open positions in every settlement coin.
+func (b *Bybit) positions(ctx context.Context) ([]bybit.Position, error) {
+	var open []bybit.Position
+	for _, coin := range bybitSettleCoins {
+		positions, err := b.Positions(ctx, coin)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range positions {
+			if p.Side != "" && p.Size != 0 {
+				open = append(open, p)
+			}
+		}
+	}
+	return open, nil
+}
+
+// OpenPositions implements Exchange.
+func (b *Bybit) OpenPositions(ctx context.Context) ([]Position, error) {
+	open, err := b.positions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	positions := make([]Position, 0, len(open))
+	for _, r := range open {
+		info, err := b.instrument(ctx, r.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		symbol := r.Symbol
+		if info.BaseCoin != "" && info.QuoteCoin != "" {
+			symbol = info.BaseCoin + "_" + info.QuoteCoin
+			b.mu.Lock()
+			b.natives[symbol] = r.Symbol
+			b.mu.Unlock()
+		}
+		size := float64(info.LotSizeFilter.QtyStep)
+		if size <= 0 {
+			size = 1
+		}
+
+		p := Position{
+			Symbol:       symbol,
+			PositionType: mexc.PositionTypeLong,
+			State:        mexc.PositionStateHolding,
+			HoldVol:      math.Round(float64(r.Size) / size),
+			HoldAvgPrice: float64(r.AvgPrice),
+			Leverage:     int(r.Leverage),
+			OpenType:     mexc.OpenTypeCross,
+			// Bybit's createdTime is when the symbol's position slot was
+			// first created and survives closing and reopening, so as with
+			// Binance CreateTime stays unset and stale position nudges skip
+			// it.
+		}
+		if r.Side == bybit.SideSell {
+			p.PositionType = mexc.PositionTypeShort
+		}
+		if r.TradeMode == bybit.TradeModeIsolated {
+			p.OpenType = mexc.OpenTypeIsolated
+		}
+		positions = append(positions, p)
+	}
+	return positions, nil
+}
+
+// FairPrice implements Exchange. It returns the mark price.
+func (b *Bybit) FairPrice(ctx context.Context, symbol string) (float64, error) {
+	ticker, err := b.Ticker(ctx, b.native(symbol))
+	if err != nil {
+		return 0, err
+	}
+	return float64(ticker.MarkPrice), nil
+}
+
+// Balances implements Exchange.
+func (b *Bybit) Balances(ctx context.Context) ([]Balance, error) {
+	coins, err := b.WalletBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]Balance, 0, len(coins))
+	for _, c := range coins {
+		balances = append(balances, Balance{
+			Currency:   c.Coin,
+			Equity:     float64(c.Equity),
+			Available:  float64(c.AvailableToWithdraw),
+			Unrealized: float64(c.UnrealisedPnl),
+			Frozen:     float64(c.TotalOrderIM),
+			Margin:     float64(c.TotalPositionIM),
+		})
+	}
+	return balances, nil
+}
+
+// PlaceOrder implements Exchange. Only market orders are supported. Closing
+// orders are sent reduce-only against the position being closed, whose index
+// tells whether the account is in hedge mode.
+func (b *Bybit) PlaceOrder(ctx context.Context, req OrderRequest) (string, error) {
+	if req.Type != mexc.OrderTypeMarket {
+		return "", errors.New("bybit: only market orders are supported")
+	}
+	size, err := b.ContractSize(ctx, req.Symbol)
+	if err != nil {
+		return "", fmt.Errorf("fetching contract size: %w", err)
+	}
+
+	order := bybit.OrderRequest{Symbol: b.native(req.Symbol), Quantity: req.Vol * size}
+	var held string // side of the position the order applies to
+	switch req.Side {
+	case mexc.OrderSideOpenLong:
+		order.Side, held = bybit.SideBuy, bybit.SideBuy
+	case mexc.OrderSideCloseLong:
+		order.Side, held, order.ReduceOnly = bybit.SideSell, bybit.SideBuy, true
+	case mexc.OrderSideOpenShort:
+		order.Side, held = bybit.SideSell, bybit.SideSell
+	case mexc.OrderSideCloseShort:
+		order.Side, held, order.ReduceOnly = bybit.SideBuy, bybit.SideSell, true
+	default:
+		return "", fmt.Errorf("bybit: unknown order side %d", req.Side)
+	}
+
+	open, err := b.positions(ctx)
+	if err != nil {
+		return "", fmt.Errorf("fetching positions: %w", err)
+	}
+	for _, p := range open {
+		if p.Symbol == order.Symbol && p.Side == held {
+			order.PositionIdx = p.PositionIdx
+			break
+		}
+	}
+	return b.PlaceMarketOrder(ctx, order)
+}
+
+// Depth implements DepthSource. Volumes are in contracts, like MEXC's.
+func (b *Bybit) Depth(ctx context.Context, symbol string, limit int) (OrderBook, error) {
+	size, err := b.ContractSize(ctx, symbol)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("fetching contract size: %w", err)
+	}
+	book, err := b.OrderBook(ctx, b.native(symbol), min(limit, 500))
+	if err != nil {
+		return OrderBook{}, err
+	}
+	toLevels := func(raw [][2]float64) []mexc.Level {
+		levels := make([]mexc.Level, len(raw))
+		for i, l := range raw {
+			levels[i] = mexc.Level{Price: l[0], Volume: l[1] / size}
+		}
+		return levels
+	}
+	return OrderBook{Bids: toLevels(book.Bids), Asks: toLevels(book.Asks)}, nil
+}
+
+// FundingRate implements FundingSource.
+func (b *Bybit) FundingRate(ctx context.Context, symbol string) (FundingRate, error) {
+	ticker, err := b.Ticker(ctx, b.native(symbol))
+	if err != nil {
+		return FundingRate{}, err
+	}
+	cycle := 8
+	if info, err := b.instrument(ctx, b.native(symbol)); err == nil && info.FundingInterval > 0 {
+		cycle = info.FundingInterval / 60
+	}
+	return FundingRate{
+		Symbol:         symbol,
+		Rate:           float64(ticker.FundingRate),
+		CollectCycle:   cycle,
+		NextSettleTime: int64(ticker.NextFundingTime),
+	}, nil
+}
+
+// LastFunding implements FundingHistorySource.
+func (b *Bybit) LastFunding(ctx context.Context, symbol string) (FundingSettlement, error) {
+	s, err := b.Client.LastFunding(ctx, b.native(symbol))
+	if err != nil {
+		return FundingSettlement{}, err
+	}
+	return FundingSettlement{Symbol: symbol, Rate: float64(s.FundingRate), SettleTime: int64(s.FundingRateTimestamp)}, nil
+}
+
+// StreamPrices implements Exchange.
+func (b *Bybit) StreamPrices() PriceStream {
+	stream := bybit.NewPriceStream(b.streamURL)
+	stream.OnError = b.OnStreamError
+	return &bybitStream{
+		ex:      b,
+		stream:  stream,
+		updates: make(chan PriceUpdate, 64),
+		symbols: make(map[string]string),
+	}
+}
+
+// bybitStream translates symbols between the bot and a bybit.PriceStream.
+type bybitStream struct {
+	ex      *Bybit
+	stream  *bybit.PriceStream
+	updates chan PriceUpdate
+
+	mu      sync.Mutex
+	symbols map[string]string // native to MEXC form
+}
+
+func (s *bybitStream) Updates() <-chan PriceUpdate { return s.updates }
+
+func (s *bybitStream) SetSymbols(symbols []string) {
+	natives := make([]string, len(symbols))
+	s.mu.Lock()
+	for i, symbol := range symbols {
+		natives[i] = s.ex.native(symbol)
+		s.symbols[natives[i]] = symbol
+	}
+	s.mu.Unlock()
+	s.stream.SetSymbols(natives)
+}
+
+func (s *bybitStream) Run(ctx context.Context) error {
+	defer close(s.updates)
+	done := make(chan error, 1)
+	go func() { done <- s.stream.Run(ctx) }()
+
+	for u := range s.stream.Updates() {
+		s.mu.Lock()
+		symbol, ok := s.symbols[u.Symbol]
+		s.mu.Unlock()
+		if !ok {
+			continue
+		}
+		select {
+		case s.updates <- PriceUpdate{Symbol: symbol, Price: u.Price, Time: u.Time}:
+		case <-ctx.Done():
+		}
+	}
+	return <-done
+}
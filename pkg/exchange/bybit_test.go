@@ -0,0 +1,126 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/bybit"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// fakeBybit serves the Bybit endpoints the adapter reads from.
+type fakeBybit struct {
+	mu          sync.Mutex
+	instruments map[string]string // instruments-info entries by symbol
+	positions   map[string]string // position/list entries by settle coin
+	orders      []map[string]interface{}
+}
+
+func (f *fakeBybit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := r.URL.Query()
+	var result string
+	switch r.URL.Path {
+	case "/v5/market/instruments-info":
+		result = fmt.Sprintf(`{"list":[%s]}`, f.instruments[q.Get("symbol")])
+	case "/v5/position/list":
+		result = fmt.Sprintf(`{"list":[%s]}`, f.positions[q.Get("settleCoin")])
+	case "/v5/market/tickers":
+		result = fmt.Sprintf(`{"list":[{"symbol":%q,"markPrice":"60000"}]}`, q.Get("symbol"))
+	case "/v5/order/create":
+		var order map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&order)
+		f.orders = append(f.orders, order)
+		result = `{"orderId":"1"}`
+	default:
+		w.Write([]byte(`{"retCode":10001,"retMsg":"not found"}`))
+		return
+	}
+	fmt.Fprintf(w, `{"retCode":0,"retMsg":"OK","result":%s}`, result)
+}
+
+func newTestBybit(t *testing.T, f *fakeBybit) *Bybit {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return NewBybit(bybit.NewClient("key", []byte("secret"), srv.URL), "")
+}
+
+func TestBybitOpenPositions(t *testing.T) {
+	f := &fakeBybit{
+		instruments: map[string]string{
+			"BTCUSDT": `{"symbol":"BTCUSDT","baseCoin":"BTC","quoteCoin":"USDT","lotSizeFilter":{"qtyStep":"0.001"}}`,
+			"ETHPERP": `{"symbol":"ETHPERP","baseCoin":"ETH","quoteCoin":"USDC","lotSizeFilter":{"qtyStep":"0.01"}}`,
+		},
+		positions: map[string]string{
+			"USDT": `{"symbol":"BTCUSDT","side":"Buy","positionIdx":0,"size":"0.015","avgPrice":"59000","leverage":"10","tradeMode":0,"createdTime":"1600000000000"},
+				{"symbol":"BTCUSDT","side":"","positionIdx":0,"size":"0"}`,
+			"USDC": `{"symbol":"ETHPERP","side":"Sell","positionIdx":2,"size":"1.5","avgPrice":"3100","leverage":"5","tradeMode":1}`,
+		},
+	}
+	b := newTestBybit(t, f)
+
+	positions, err := b.OpenPositions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Position{
+		{Symbol: "BTC_USDT", PositionType: mexc.PositionTypeLong, State: mexc.PositionStateHolding, HoldVol: 15, HoldAvgPrice: 59000, Leverage: 10, OpenType: mexc.OpenTypeCross},
+		{Symbol: "ETH_USDC", PositionType: mexc.PositionTypeShort, State: mexc.PositionStateHolding, HoldVol: 150, HoldAvgPrice: 3100, Leverage: 5, OpenType: mexc.OpenTypeIsolated},
+	}
+	if len(positions) != len(want) {
+		t.Fatalf("positions = %+v, want %d without the flat slot", positions, len(want))
+	}
+	for i := range want {
+		if positions[i] != want[i] {
+			t.Errorf("positions[%d] = %+v, want %+v", i, positions[i], want[i])
+		}
+	}
+
+	// Symbols seen in positions map back to their native form.
+	if got := b.native("ETH_USDC"); got != "ETHPERP" {
+		t.Errorf("native(ETH_USDC) = %q, want ETHPERP", got)
+	}
+	if got := b.native("SOL_USDT"); got != "SOLUSDT" {
+		t.Errorf("native(SOL_USDT) = %q, want SOLUSDT", got)
+	}
+	size, err := b.ContractSize(context.Background(), "ETH_USDC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 0.01 {
+		t.Errorf("ContractSize(ETH_USDC) = %v, want the 0.01 quantity step", size)
+	}
+}
+
+func TestBybitPlaceOrder(t *testing.T) {
+	f := &fakeBybit{
+		instruments: map[string]string{
+			"ETHPERP": `{"symbol":"ETHPERP","baseCoin":"ETH","quoteCoin":"USDC","lotSizeFilter":{"qtyStep":"0.01"}}`,
+		},
+		positions: map[string]string{
+			"USDC": `{"symbol":"ETHPERP","side":"Sell","positionIdx":2,"size":"1.5","avgPrice":"3100","leverage":"5"}`,
+		},
+	}
+	b := newTestBybit(t, f)
+	if _, err := b.OpenPositions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.PlaceOrder(context.Background(), OrderRequest{Symbol: "ETH_USDC", Side: mexc.OrderSideCloseShort, Type: mexc.OrderTypeMarket, Vol: 50}); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.orders) != 1 {
+		t.Fatalf("placed %d orders, want 1", len(f.orders))
+	}
+	order := f.orders[0]
+	if order["symbol"] != "ETHPERP" || order["side"] != bybit.SideBuy || order["qty"] != "0.5" || order["reduceOnly"] != true || order["positionIdx"] != float64(bybit.PositionIdxHedgeSell) {
+		t.Errorf("order = %v, want a reduce-only ETHPERP buy of 0.5 against the hedge mode short", order)
+	}
+}
@@ -0,0 +1,95 @@
+// Package exchange is the interface between the bot and a futures exchange.
+// The monitor, the rebalancer and the Telegram commands only talk to an
+// Exchange, so supporting another exchange means writing an adapter for it.
+//
+// Positions, orders and prices use the MEXC types, MEXC being the first
+// exchange supported; other adapters translate their API's model into them:
+//
+//	api := mexc.NewClient(accessKey, secretKey, mexc.DefaultBaseURL)
+//	var ex exchange.Exchange = exchange.NewMEXC(api, mexc.DefaultStreamURL)
+package exchange
+
+import (
+	"context"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+type (
+	Position     = mexc.Position
+	OrderRequest = mexc.OrderRequest
+	PriceUpdate  = mexc.PriceUpdate
+	OrderBook    = mexc.OrderBook
+	FundingRate  = mexc.FundingRate
+
+	FundingSettlement = mexc.FundingSettlement
+)
+
+// Balance is the margin account balance in one currency.
+type Balance struct {
+	Currency string
+	// Equity is the balance including unrealized PnL.
+	Equity float64
+	// Available is what can be used to open positions or withdrawn.
+	Available  float64
+	Unrealized float64
+	// Frozen is held by open orders, and Margin by open positions. Both
+	// are zero where the exchange doesn't break them out.
+	Frozen float64
+	Margin float64
+}
+
+// PriceStream pushes fair prices for a changing set of symbols. Updates is
+// closed when Run returns.
+type PriceStream interface {
+	Run(ctx context.Context) error
+	SetSymbols(symbols []string)
+	Updates() <-chan PriceUpdate
+}
+
+// Exchange is a futures account on an exchange. Implementations must be safe
+// for concurrent use.
+type Exchange interface {
+	// Name identifies the exchange in logs and messages, e.g. "MEXC".
+	Name() string
+	OpenPositions(ctx context.Context) ([]Position, error)
+	FairPrice(ctx context.Context, symbol string) (float64, error)
+	Balances(ctx context.Context) ([]Balance, error)
+	// PlaceOrder submits req and returns the new order's ID.
+	PlaceOrder(ctx context.Context, req OrderRequest) (string, error)
+	// StreamPrices returns a fair price stream, which connects when Run is
+	// called.
+	StreamPrices() PriceStream
+}
+
+// Optional capabilities. Callers check for them with a type assertion and
+// fall back or skip the feature when an exchange doesn't have them.
+type (
+	// ContractSizer is implemented by exchanges whose position sizes are
+	// counted in contracts rather than in the base asset.
+	ContractSizer interface {
+		ContractSize(ctx context.Context, symbol string) (float64, error)
+	}
+	// DepthSource is implemented by exchanges that serve order book depth.
+	DepthSource interface {
+		Depth(ctx context.Context, symbol string, limit int) (OrderBook, error)
+	}
+	// FundingSource is implemented by exchanges that report funding rates.
+	FundingSource interface {
+		FundingRate(ctx context.Context, symbol string) (FundingRate, error)
+	}
+	// FundingHistorySource is implemented by exchanges that report settled
+	// funding rates.
+	FundingHistorySource interface {
+		LastFunding(ctx context.Context, symbol string) (FundingSettlement, error)
+	}
+)
+
+// ContractSize returns the base asset amount per contract of symbol on ex,
+// which is 1 for exchanges without a ContractSizer.
+func ContractSize(ctx context.Context, ex Exchange, symbol string) (float64, error) {
+	if cs, ok := ex.(ContractSizer); ok {
+		return cs.ContractSize(ctx, symbol)
+	}
+	return 1, nil
+}
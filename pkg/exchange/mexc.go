@@ -0,0 +1,69 @@
+package exchange
+
+import (
+	"context"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// MEXC is the Exchange for MEXC futures. The embedded client stays available
+// for MEXC-only features such as the private stream.
+type MEXC struct {
+	*mexc.Client
+	streamURL string
+
+	// OnStreamError, if set, is passed to price streams as their OnError.
+	OnStreamError func(error)
+}
+
+// NewMEXC returns an Exchange backed by client, streaming prices from
+// streamURL (mexc.DefaultStreamURL when empty).
+func NewMEXC(client *mexc.Client, streamURL string) *MEXC {
+	if streamURL == "" {
+		streamURL = mexc.DefaultStreamURL
+	}
+	return &MEXC{Client: client, streamURL: streamURL}
+}
+
+func (m *MEXC) Name() string { return "MEXC" }
+
+// Balances implements Exchange.
+func (m *MEXC) Balances(ctx context.Context) ([]Balance, error) {
+	assets, err := m.Assets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]Balance, 0, len(assets))
+	for _, a := range assets {
+		balances = append(balances, Balance{
+			Currency:   a.Currency,
+			Equity:     a.Equity,
+			Available:  a.AvailableBalance,
+			Unrealized: a.Unrealized,
+			Frozen:     a.FrozenBalance,
+			Margin:     a.PositionMargin,
+		})
+	}
+	return balances, nil
+}
+
+// PlaceOrder implements Exchange.
+func (m *MEXC) PlaceOrder(ctx context.Context, req OrderRequest) (string, error) {
+	return m.SubmitOrder(ctx, req)
+}
+
+// StreamPrices implements Exchange.
+func (m *MEXC) StreamPrices() PriceStream {
+	stream := mexc.NewPriceStream(m.streamURL)
+	stream.OnError = m.OnStreamError
+	return stream
+}
+
+// ContractSize implements ContractSizer.
+func (m *MEXC) ContractSize(ctx context.Context, symbol string) (float64, error) {
+	detail, err := m.ContractDetail(ctx, symbol)
+	if err != nil {
+		return 0, err
+	}
+	return detail.ContractSize, nil
+}
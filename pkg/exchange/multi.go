@@ -0,0 +1,291 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Multi combines several exchange accounts into one Exchange, so the monitor
+// reports and alerts on all of them together.
+//
+// Symbols are qualified with their account's label, e.g. "binance:BTC_USDT",
+// and requests are routed by that prefix. One account may go without a label
+// and keep its symbols as they are.
+type Multi struct {
+	accounts []Account
+}
+
+// Account is one member of a Multi.
+type Account struct {
+	// Label qualifies the account's symbols. It must be unique, lowercase
+	// and free of ":"; empty leaves the symbols unqualified.
+	Label string
+	Exchange
+}
+
+// NewMulti returns an Exchange over exchanges, the first being the primary
+// whose symbols stay unqualified. The others are labeled with their
+// lowercased Name, so each exchange may appear only once; use
+// NewMultiAccount for several accounts on one exchange.
+func NewMulti(exchanges ...Exchange) *Multi {
+	accounts := make([]Account, len(exchanges))
+	for i, ex := range exchanges {
+		accounts[i] = Account{Exchange: ex}
+		if i > 0 {
+			accounts[i].Label = strings.ToLower(ex.Name())
+		}
+	}
+	return NewMultiAccount(accounts...)
+}
+
+// NewMultiAccount returns an Exchange over accounts.
+func NewMultiAccount(accounts ...Account) *Multi {
+	return &Multi{accounts: accounts}
+}
+
+func (m *Multi) Name() string {
+	names := make([]string, len(m.accounts))
+	for i, a := range m.accounts {
+		names[i] = a.describe()
+	}
+	return strings.Join(names, "+")
+}
+
+// describe names the account in errors: its label, or the exchange name.
+func (a Account) describe() string {
+	if a.Label != "" {
+		return a.Label
+	}
+	return a.Name()
+}
+
+// SplitSymbol splits a possibly qualified symbol into the lowercased account
+// label and the exchange's own symbol. The label is empty for unqualified
+// symbols.
+func SplitSymbol(symbol string) (label, bare string) {
+	if name, rest, ok := strings.Cut(symbol, ":"); ok {
+		return strings.ToLower(name), rest
+	}
+	return "", symbol
+}
+
+// BareSymbol returns symbol without its account qualifier.
+func BareSymbol(symbol string) string {
+	_, bare := SplitSymbol(symbol)
+	return bare
+}
+
+func (m *Multi) qualify(i int, symbol string) string {
+	if m.accounts[i].Label == "" {
+		return symbol
+	}
+	return m.accounts[i].Label + ":" + symbol
+}
+
+// route returns the account a qualified symbol belongs to and the symbol on
+// that account's exchange.
+func (m *Multi) route(symbol string) (int, string, error) {
+	label, bare := SplitSymbol(symbol)
+	for i, a := range m.accounts {
+		if a.Label == label {
+			return i, bare, nil
+		}
+	}
+	if label == "" {
+		return 0, "", fmt.Errorf("symbol %s names no account", symbol)
+	}
+	return 0, "", fmt.Errorf("no account %q for symbol %s", label, symbol)
+}
+
+// OpenPositions implements Exchange. It fails when any account does, as a
+// partial list would look like closed positions.
+func (m *Multi) OpenPositions(ctx context.Context) ([]Position, error) {
+	results := make([][]Position, len(m.accounts))
+	errs := make([]error, len(m.accounts))
+	var wg sync.WaitGroup
+	for i, a := range m.accounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			positions, err := a.OpenPositions(ctx)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", a.describe(), err)
+				return
+			}
+			for j := range positions {
+				positions[j].Symbol = m.qualify(i, positions[j].Symbol)
+			}
+			results[i] = positions
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var positions []Position
+	for _, r := range results {
+		positions = append(positions, r...)
+	}
+	return positions, nil
+}
+
+// FairPrice implements Exchange.
+func (m *Multi) FairPrice(ctx context.Context, symbol string) (float64, error) {
+	i, bare, err := m.route(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return m.accounts[i].FairPrice(ctx, bare)
+}
+
+// Balances implements Exchange. Currencies are qualified like symbols, e.g.
+// "binance:USDT".
+func (m *Multi) Balances(ctx context.Context) ([]Balance, error) {
+	var balances []Balance
+	for i, a := range m.accounts {
+		b, err := a.Balances(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.describe(), err)
+		}
+		for j := range b {
+			b[j].Currency = m.qualify(i, b[j].Currency)
+		}
+		balances = append(balances, b...)
+	}
+	return balances, nil
+}
+
+// PlaceOrder implements Exchange.
+func (m *Multi) PlaceOrder(ctx context.Context, req OrderRequest) (string, error) {
+	i, bare, err := m.route(req.Symbol)
+	if err != nil {
+		return "", err
+	}
+	req.Symbol = bare
+	return m.accounts[i].PlaceOrder(ctx, req)
+}
+
+// ContractSize implements ContractSizer.
+func (m *Multi) ContractSize(ctx context.Context, symbol string) (float64, error) {
+	i, bare, err := m.route(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return ContractSize(ctx, m.accounts[i].Exchange, bare)
+}
+
+// Depth implements DepthSource. It returns an error wrapping
+// errors.ErrUnsupported for accounts on exchanges without depth.
+func (m *Multi) Depth(ctx context.Context, symbol string, limit int) (OrderBook, error) {
+	i, bare, err := m.route(symbol)
+	if err != nil {
+		return OrderBook{}, err
+	}
+	ds, ok := m.accounts[i].Exchange.(DepthSource)
+	if !ok {
+		return OrderBook{}, fmt.Errorf("%s order book depth: %w", m.accounts[i].Name(), errors.ErrUnsupported)
+	}
+	return ds.Depth(ctx, bare, limit)
+}
+
+// FundingRate implements FundingSource. It returns an error wrapping
+// errors.ErrUnsupported for accounts on exchanges without funding rates.
+func (m *Multi) FundingRate(ctx context.Context, symbol string) (FundingRate, error) {
+	i, bare, err := m.route(symbol)
+	if err != nil {
+		return FundingRate{}, err
+	}
+	fs, ok := m.accounts[i].Exchange.(FundingSource)
+	if !ok {
+		return FundingRate{}, fmt.Errorf("%s funding rates: %w", m.accounts[i].Name(), errors.ErrUnsupported)
+	}
+	rate, err := fs.FundingRate(ctx, bare)
+	rate.Symbol = symbol
+	return rate, err
+}
+
+// LastFunding implements FundingHistorySource. It returns an error
+// wrapping errors.ErrUnsupported for accounts on exchanges without funding
+// history.
+func (m *Multi) LastFunding(ctx context.Context, symbol string) (FundingSettlement, error) {
+	i, bare, err := m.route(symbol)
+	if err != nil {
+		return FundingSettlement{}, err
+	}
+	fs, ok := m.accounts[i].Exchange.(FundingHistorySource)
+	if !ok {
+		return FundingSettlement{}, fmt.Errorf("%s funding history: %w", m.accounts[i].Name(), errors.ErrUnsupported)
+	}
+	s, err := fs.LastFunding(ctx, bare)
+	s.Symbol = symbol
+	return s, err
+}
+
+// StreamPrices implements Exchange. The returned stream runs one stream per
+// account and merges their updates.
+func (m *Multi) StreamPrices() PriceStream {
+	s := &multiStream{m: m, updates: make(chan PriceUpdate, 64)}
+	for _, a := range m.accounts {
+		s.streams = append(s.streams, a.StreamPrices())
+	}
+	return s
+}
+
+type multiStream struct {
+	m       *Multi
+	streams []PriceStream
+	updates chan PriceUpdate
+}
+
+func (s *multiStream) Updates() <-chan PriceUpdate { return s.updates }
+
+func (s *multiStream) SetSymbols(symbols []string) {
+	split := make([][]string, len(s.streams))
+	for _, symbol := range symbols {
+		if i, bare, err := s.m.route(symbol); err == nil {
+			split[i] = append(split[i], bare)
+		}
+	}
+	for i, stream := range s.streams {
+		stream.SetSymbols(split[i])
+	}
+}
+
+// Run runs every stream until ctx is canceled or one of them fails.
+func (s *multiStream) Run(ctx context.Context) error {
+	defer close(s.updates)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(s.streams))
+	var wg sync.WaitGroup
+	for i, stream := range s.streams {
+		go func() {
+			err := stream.Run(ctx)
+			cancel()
+			errs <- err
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range stream.Updates() {
+				u.Symbol = s.m.qualify(i, u.Symbol)
+				select {
+				case s.updates <- u:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	err := <-errs
+	for range len(s.streams) - 1 {
+		<-errs
+	}
+	return err
+}
@@ -0,0 +1,27 @@
+package mexc
+
+import "context"
+
+// Asset is the futures account balance in one currency.
+type Asset struct {
+	Currency         string  `json:"currency"`
+	PositionMargin   float64 `json:"positionMargin"`
+	FrozenBalance    float64 `json:"frozenBalance"`
+	AvailableBalance float64 `json:"availableBalance"`
+	CashBalance      float64 `json:"cashBalance"`
+	Equity           float64 `json:"equity"`
+	Unrealized       float64 `json:"unrealized"`
+}
+
+type assetsResponse struct {
+	Data []Asset `json:"data"`
+}
+
+// Assets returns the futures account balance per currency.
+func (c *Client) Assets(ctx context.Context) ([]Asset, error) {
+	var resp assetsResponse
+	if err := c.get(ctx, "/api/v1/private/account/assets", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
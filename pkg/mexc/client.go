@@ -0,0 +1,213 @@
+// Package mexc is a client for the MEXC contract (futures) REST API, with
+// read access to the spot account.
+package mexc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL is the production contract API endpoint.
+const DefaultBaseURL = "https://contract.mexc.com"
+
+// Client signs and sends requests to the MEXC contract API.
+type Client struct {
+	accessKey  string
+	secretKey  []byte
+	baseURL    string
+	httpClient *http.Client
+
+	// SpotBaseURL is the spot API endpoint used by the Spot methods;
+	// NewClient sets it to DefaultSpotBaseURL.
+	SpotBaseURL string
+	// Retry controls retries of failed GET requests; NewClient sets it to
+	// DefaultRetryPolicy.
+	Retry RetryPolicy
+	// OnRetry, if set, is called before each retry with the error and the
+	// wait before the next attempt.
+	OnRetry func(err error, delay time.Duration)
+	// Limiter holds requests back to stay within the exchange's rate limits;
+	// NewClient sets it to DefaultRateLimits. Nil disables limiting.
+	Limiter *RateLimiter
+	// OnKeyFailover, if set, is called once when the client switches to the
+	// secondary key pair, with the error that rejected the primary.
+	OnKeyFailover func(err error)
+
+	keyMu              sync.Mutex
+	secondaryAccessKey string
+	secondarySecretKey []byte
+	failedOver         bool
+
+	// sleep waits between retries; nil uses a timer. Tests replace it.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewClient returns a Client for baseURL authenticated with the given key
+// pair. The secret is referenced rather than copied, so wiping the caller's
+// slice also wipes it from the Client.
+func NewClient(accessKey string, secretKey []byte, baseURL string) *Client {
+	return &Client{
+		accessKey:   accessKey,
+		secretKey:   secretKey,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		httpClient:  &http.Client{Timeout: 30 * time.Second, Transport: sharedTransport},
+		SpotBaseURL: DefaultSpotBaseURL,
+		Retry:       DefaultRetryPolicy,
+		Limiter:     NewRateLimiter(DefaultRateLimits),
+	}
+}
+
+// urlEncode performs URL encoding similar to Java's URLEncoder.encode but replaces '+' with '%20'.
+func urlEncode(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// getRequestParamString constructs a sorted parameter string from the request parameters.
+func getRequestParamString(params map[string]string) string {
+	var keys []string
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var paramStrBuilder strings.Builder
+	for _, k := range keys {
+		paramStrBuilder.WriteString(fmt.Sprintf("%s=%s&", k, urlEncode(params[k])))
+	}
+	paramStr := paramStrBuilder.String()
+	return strings.TrimSuffix(paramStr, "&")
+}
+
+// sign generates the signature for the request.
+func sign(accessKey string, secretKey []byte, reqTime, paramStr string) string {
+	toSign := accessKey + reqTime + paramStr
+
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte(toSign))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RequestError is returned when a request fails, and records which endpoint
+// it was for.
+type RequestError struct {
+	Endpoint string
+	// StatusCode is the HTTP status, or zero if no response was received.
+	StatusCode int
+	Err        error
+}
+
+func (e *RequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// get sends a signed GET request and decodes the JSON response into out.
+// Responses with "success": false are returned as an *APIError wrapped in a
+// *RequestError.
+func (c *Client) get(ctx context.Context, endpoint string, params map[string]string, out interface{}) error {
+	paramStr := getRequestParamString(params)
+	fullURL := c.baseURL + endpoint
+	if paramStr != "" {
+		fullURL += "?" + paramStr
+	}
+	return c.do(ctx, "GET", endpoint, fullURL, paramStr, nil, out)
+}
+
+// post sends payload as a signed JSON POST request and decodes the response
+// like get.
+func (c *Client) post(ctx context.Context, endpoint string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+	return c.do(ctx, "POST", endpoint, c.baseURL+endpoint, string(body), body, out)
+}
+
+// do sends a request signed over signed, which is the query string for GET
+// requests and the body for POST requests, retrying as c.Retry allows and
+// failing over to the secondary key pair if the primary is rejected.
+func (c *Client) do(ctx context.Context, method, endpoint, fullURL, signed string, body []byte, out interface{}) error {
+	return c.doWithFailover(ctx, func() error {
+		return c.doWithRetry(ctx, method, func() (time.Duration, error) {
+			return c.attempt(ctx, method, endpoint, fullURL, signed, body, out)
+		})
+	})
+}
+
+// attempt sends the request once. The request is signed afresh each time so
+// that retries don't fall outside the allowed time window. It also returns
+// the server's Retry-After delay, if any.
+func (c *Client) attempt(ctx context.Context, method, endpoint, fullURL, signed string, body []byte, out interface{}) (time.Duration, error) {
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(ctx, EndpointGroup(endpoint)); err != nil {
+			return 0, err
+		}
+	}
+
+	accessKey, secretKey, _ := c.keys()
+	reqTime := strconv.FormatInt(time.Now().Unix()*1000, 10)
+	signature := sign(accessKey, secretKey, reqTime, signed)
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, reqBody)
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Add("ApiKey", accessKey)
+	req.Header.Add("Request-Time", reqTime)
+	req.Header.Add("Signature", signature)
+	req.Header.Add("Content-Type", "application/json")
+
+	response, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, &RequestError{Endpoint: endpoint, Err: fmt.Errorf("sending request: %w", err)}
+	}
+	defer response.Body.Close()
+
+	retryAfter := parseRetryAfter(response.Header.Get("Retry-After"))
+	fail := func(err error) (time.Duration, error) {
+		return retryAfter, &RequestError{Endpoint: endpoint, StatusCode: response.StatusCode, Err: err}
+	}
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fail(fmt.Errorf("reading response body: %w", err))
+	}
+	if response.StatusCode == http.StatusTooManyRequests {
+		return fail(ErrRateLimited)
+	}
+
+	// Error responses usually still come with a JSON envelope, which says
+	// more than the status line.
+	var env envelope
+	if err := json.Unmarshal(respBody, &env); err == nil && !env.Success && env.Code != 0 {
+		return fail(&APIError{Code: env.Code, Message: env.Message})
+	}
+	if response.StatusCode >= 300 {
+		return fail(fmt.Errorf("unexpected HTTP status %s", response.Status))
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fail(fmt.Errorf("decoding response JSON: %w", err))
+	}
+	return 0, nil
+}
@@ -0,0 +1,215 @@
+package mexc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "mx0access"
+	testSecretKey = "mx0secret"
+)
+
+// newTestClient returns a client for a test server running handler, without
+// rate limiting and with fast retries.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c := NewClient(testAccessKey, []byte(testSecretKey), srv.URL)
+	c.Limiter = nil
+	c.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	return c
+}
+
+func wantSignature(reqTime, signed string) string {
+	mac := hmac.New(sha256.New, []byte(testSecretKey))
+	mac.Write([]byte(testAccessKey + reqTime + signed))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGetRequestParamString(t *testing.T) {
+	tests := []struct {
+		params map[string]string
+		want   string
+	}{
+		{nil, ""},
+		{map[string]string{"symbol": "BTC_USDT"}, "symbol=BTC_USDT"},
+		{map[string]string{"page_size": "1", "symbol": "BTC_USDT", "page_num": "2"}, "page_num=2&page_size=1&symbol=BTC_USDT"},
+		{map[string]string{"note": "a b+c"}, "note=a%20b%2Bc"},
+	}
+	for _, tt := range tests {
+		if got := getRequestParamString(tt.params); got != tt.want {
+			t.Errorf("getRequestParamString(%v) = %q, want %q", tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestSignedGet(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("ApiKey"); got != testAccessKey {
+			t.Errorf("ApiKey = %q, want %q", got, testAccessKey)
+		}
+		reqTime := r.Header.Get("Request-Time")
+		if reqTime == "" {
+			t.Error("Request-Time header missing")
+		}
+		if got, want := r.Header.Get("Signature"), wantSignature(reqTime, r.URL.RawQuery); got != want {
+			t.Errorf("Signature = %q, want %q", got, want)
+		}
+		w.Write([]byte(`{"success":true,"code":0,"data":{"symbol":"BTC_USDT","contractSize":0.0001}}`))
+	})
+
+	detail, err := c.ContractDetail(context.Background(), "BTC_USDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.ContractSize != 0.0001 {
+		t.Errorf("ContractSize = %v, want 0.0001", detail.ContractSize)
+	}
+}
+
+func TestSignedPost(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if got, want := r.Header.Get("Signature"), wantSignature(r.Header.Get("Request-Time"), string(body)); got != want {
+			t.Errorf("Signature = %q, want %q", got, want)
+		}
+		w.Write([]byte(`{"success":true,"code":0,"data":"1"}`))
+	})
+
+	var out envelope
+	if err := c.post(context.Background(), "/api/v1/private/order/submit", map[string]string{"symbol": "BTC_USDT"}, &out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+		code   int
+	}{
+		{"invalid signature", http.StatusOK, `{"success":false,"code":602,"message":"Signature verification failed!"}`, ErrInvalidSignature, 602},
+		{"unauthorized", http.StatusUnauthorized, `{"success":false,"code":401,"message":"Not logged in"}`, ErrUnauthorized, 401},
+		{"ip not whitelisted", http.StatusOK, `{"success":false,"code":406,"message":"IP not whitelisted"}`, ErrIPNotWhitelisted, 406},
+		{"request expired", http.StatusOK, `{"success":false,"code":513,"message":"Request expired"}`, ErrRequestExpired, 513},
+		{"permission", http.StatusOK, `{"success":false,"code":701,"message":"No permission"}`, ErrPermission, 701},
+		{"invalid parameter", http.StatusBadRequest, `{"success":false,"code":600,"message":"Param error"}`, ErrInvalidParameter, 600},
+		{"unknown code", http.StatusOK, `{"success":false,"code":9999,"message":"Something else"}`, nil, 9999},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := c.FairPrice(context.Background(), "BTC_USDT")
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want an *APIError", err)
+			}
+			if apiErr.Code != tt.code {
+				t.Errorf("Code = %d, want %d", apiErr.Code, tt.code)
+			}
+			var reqErr *RequestError
+			if !errors.As(err, &reqErr) || reqErr.Endpoint != "/api/v1/contract/fair_price/BTC_USDT" {
+				t.Errorf("err = %v, want a *RequestError for the fair price endpoint", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.want)
+			}
+			for _, other := range []error{ErrUnauthorized, ErrInvalidSignature, ErrPermission, ErrRateLimited} {
+				if other != tt.want && errors.Is(err, other) {
+					t.Errorf("errors.Is(%v, %v) = true", err, other)
+				}
+			}
+		})
+	}
+}
+
+func TestUnexpectedStatus(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<html>forbidden</html>"))
+	})
+
+	_, err := c.FairPrice(context.Background(), "BTC_USDT")
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) || reqErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("err = %v, want a *RequestError with status 403", err)
+	}
+}
+
+func TestOpenPositions(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/private/position/open_positions" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"success":true,"code":0,"data":[
+			{"positionId":1,"symbol":"BTC_USDT","positionType":1,"state":1,"holdVol":10,"holdAvgPrice":59000.5,"leverage":10,"createTime":1700000000000},
+			{"positionId":2,"symbol":"ETH_USDT","positionType":2,"state":1,"holdVol":5,"holdAvgPrice":3100,"leverage":20}
+		]}`))
+	})
+
+	positions, err := c.OpenPositions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Position{
+		{PositionID: 1, Symbol: "BTC_USDT", PositionType: PositionTypeLong, State: PositionStateHolding, HoldVol: 10, HoldAvgPrice: 59000.5, Leverage: 10, CreateTime: 1700000000000},
+		{PositionID: 2, Symbol: "ETH_USDT", PositionType: PositionTypeShort, State: PositionStateHolding, HoldVol: 5, HoldAvgPrice: 3100, Leverage: 20},
+	}
+	if len(positions) != len(want) {
+		t.Fatalf("got %d positions, want %d", len(positions), len(want))
+	}
+	for i := range want {
+		if positions[i] != want[i] {
+			t.Errorf("positions[%d] = %+v, want %+v", i, positions[i], want[i])
+		}
+	}
+	if got := positions[1].Side(); got != "short" {
+		t.Errorf("Side() = %q, want short", got)
+	}
+}
+
+func TestFairPrice(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/contract/fair_price/BTC_USDT" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"success":true,"code":0,"data":{"symbol":"BTC_USDT","fairPrice":60123.4,"timestamp":1700000000000}}`))
+	})
+
+	price, err := c.FairPrice(context.Background(), "BTC_USDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != 60123.4 {
+		t.Errorf("FairPrice = %v, want 60123.4", price)
+	}
+}
+
+func TestFairPriceMalformed(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"code":0,"data":{"fairPrice":"oops"}}`))
+	})
+
+	if _, err := c.FairPrice(context.Background(), "BTC_USDT"); err == nil {
+		t.Fatal("FairPrice succeeded on a malformed response")
+	}
+}
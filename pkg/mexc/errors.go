@@ -0,0 +1,73 @@
+package mexc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors that API responses are mapped to. Test for them with errors.Is:
+//
+//	if errors.Is(err, mexc.ErrInvalidSignature) { ... }
+var (
+	ErrUnauthorized     = errors.New("mexc: API key missing, invalid or expired")
+	ErrInvalidSignature = errors.New("mexc: signature verification failed")
+	ErrIPNotWhitelisted = errors.New("mexc: request IP is not whitelisted for this API key")
+	ErrRequestExpired   = errors.New("mexc: request time outside the allowed window; check the system clock")
+	ErrRateLimited      = errors.New("mexc: rate limited")
+	ErrPermission       = errors.New("mexc: API key lacks the required permission")
+	ErrInvalidParameter = errors.New("mexc: invalid request parameter")
+	ErrUnavailable      = errors.New("mexc: service busy or unavailable")
+)
+
+// errorCodes maps MEXC contract and spot API error codes to the errors above.
+var errorCodes = map[int]error{
+	401: ErrUnauthorized,
+	402: ErrUnauthorized,
+	406: ErrIPNotWhitelisted,
+	500: ErrUnavailable,
+	501: ErrUnavailable,
+	510: ErrRateLimited,
+	513: ErrRequestExpired,
+	600: ErrInvalidParameter,
+	602: ErrInvalidSignature,
+	701: ErrPermission,
+	702: ErrPermission,
+	703: ErrPermission,
+	704: ErrPermission,
+
+	// Spot API codes.
+	10072:  ErrUnauthorized,
+	700001: ErrUnauthorized,
+	700002: ErrInvalidSignature,
+	700003: ErrRequestExpired,
+	700006: ErrIPNotWhitelisted,
+	700007: ErrPermission,
+}
+
+// APIError is an error reported in the body of an API response, i.e. one
+// with "success": false.
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if known, ok := errorCodes[e.Code]; ok {
+		return fmt.Sprintf("%v (code %d: %s)", known, e.Code, e.Message)
+	}
+	return fmt.Sprintf("mexc: error code %d: %s", e.Code, e.Message)
+}
+
+// Is reports whether the error's code maps to target, so callers can test
+// for the exported errors without knowing the codes.
+func (e *APIError) Is(target error) bool {
+	known, ok := errorCodes[e.Code]
+	return ok && known == target
+}
+
+// envelope is the part common to every API response.
+type envelope struct {
+	Success bool   `json:"success"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,83 @@
+package mexc
+
+import (
+	"context"
+	"errors"
+)
+
+// keyRejected reports whether err means the exchange refused the key pair
+// itself, as invalid, expired or not matching its signature, which a revoked
+// key during rotation produces. A key that is valid but lacks a permission
+// or isn't whitelisted for this IP is misconfigured, and switching keys
+// would hide that.
+func keyRejected(err error) bool {
+	return errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrInvalidSignature)
+}
+
+// SetSecondaryKey configures a standby key pair for zero-downtime rotation.
+// When the active key is rejected as invalid or expired, or its signature
+// fails, the client switches to the secondary for good, calls OnKeyFailover
+// and resends the request. Other errors, such as ErrPermission and
+// ErrIPNotWhitelisted, are returned as they are. Like NewClient, the
+// secret is referenced rather than copied. It must not be called while
+// requests are in flight.
+func (c *Client) SetSecondaryKey(accessKey string, secretKey []byte) {
+	c.keyMu.Lock()
+	defer c.keyMu.Unlock()
+	c.secondaryAccessKey, c.secondarySecretKey = accessKey, secretKey
+}
+
+// UsingSecondaryKey reports whether the client has failed over to the
+// secondary key pair.
+func (c *Client) UsingSecondaryKey() bool {
+	c.keyMu.Lock()
+	defer c.keyMu.Unlock()
+	return c.failedOver
+}
+
+// keys returns the active key pair and whether it is the secondary.
+func (c *Client) keys() (accessKey string, secretKey []byte, secondary bool) {
+	c.keyMu.Lock()
+	defer c.keyMu.Unlock()
+	if c.failedOver {
+		return c.secondaryAccessKey, c.secondarySecretKey, true
+	}
+	return c.accessKey, c.secretKey, false
+}
+
+// failover switches to the secondary key pair after the primary was rejected
+// with err. It reports whether the request should be resent, which is also
+// the case when another request already switched.
+func (c *Client) failover(err error) bool {
+	c.keyMu.Lock()
+	if c.secondaryAccessKey == "" {
+		c.keyMu.Unlock()
+		return false
+	}
+	if c.failedOver {
+		c.keyMu.Unlock()
+		return true
+	}
+	c.failedOver = true
+	c.keyMu.Unlock()
+
+	if c.OnKeyFailover != nil {
+		c.OnKeyFailover(err)
+	}
+	return true
+}
+
+// doWithFailover runs send, and runs it again on the secondary key pair if
+// the primary was rejected. A rejected request had no effect, so resending
+// is safe even for orders.
+func (c *Client) doWithFailover(ctx context.Context, send func() error) error {
+	_, _, secondary := c.keys()
+	err := send()
+	if err == nil || secondary || !keyRejected(err) || ctx.Err() != nil {
+		return err
+	}
+	if !c.failover(err) {
+		return err
+	}
+	return send()
+}
@@ -0,0 +1,62 @@
+package mexc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestKeyFailover(t *testing.T) {
+	const secondaryAccessKey = "mx0standby"
+
+	tests := []struct {
+		name      string
+		code      int // returned for the primary key
+		secondary bool
+		failover  bool
+		want      error
+	}{
+		{"invalid key", 401, true, true, nil},
+		{"invalid signature", 602, true, true, nil},
+		{"no permission", 701, true, false, ErrPermission},
+		{"ip not whitelisted", 406, true, false, ErrIPNotWhitelisted},
+		{"not a key problem", 600, true, false, ErrInvalidParameter},
+		{"no secondary", 602, false, false, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				key := r.Header.Get("ApiKey")
+				keys = append(keys, key)
+				if key == testAccessKey {
+					fmt.Fprintf(w, `{"success":false,"code":%d,"message":"rejected"}`, tt.code)
+					return
+				}
+				w.Write([]byte(`{"success":true,"code":0,"data":[]}`))
+			})
+			if tt.secondary {
+				c.SetSecondaryKey(secondaryAccessKey, []byte("mx0standbysecret"))
+			}
+			var failedOver []error
+			c.OnKeyFailover = func(err error) { failedOver = append(failedOver, err) }
+
+			_, err := c.OpenPositions(context.Background())
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			wantKeys, wantFailovers := []string{testAccessKey}, 0
+			if tt.failover {
+				wantKeys, wantFailovers = append(wantKeys, secondaryAccessKey), 1
+			}
+			if c.UsingSecondaryKey() != tt.failover || len(failedOver) != wantFailovers {
+				t.Errorf("using secondary = %v after %d failovers, want %v", c.UsingSecondaryKey(), len(failedOver), tt.failover)
+			}
+			if fmt.Sprint(keys) != fmt.Sprint(wantKeys) {
+				t.Errorf("requests used keys %v, want %v", keys, wantKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package mexc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+type fairPriceResponse struct {
+	Data struct {
+		FairPrice float64 `json:"fairPrice"`
+	} `json:"data"`
+}
+
+// FairPrice returns the current fair price for symbol.
+func (c *Client) FairPrice(ctx context.Context, symbol string) (float64, error) {
+	var resp fairPriceResponse
+	endpoint := fmt.Sprintf("/api/v1/contract/fair_price/%s", symbol)
+	if err := c.get(ctx, endpoint, nil, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Data.FairPrice, nil
+}
+
+// ContractDetail describes a contract's trading parameters.
+type ContractDetail struct {
+	Symbol       string  `json:"symbol"`
+	ContractSize float64 `json:"contractSize"`
+}
+
+type contractDetailResponse struct {
+	Data ContractDetail `json:"data"`
+}
+
+// ContractDetail returns the trading parameters of symbol.
+func (c *Client) ContractDetail(ctx context.Context, symbol string) (ContractDetail, error) {
+	var resp contractDetailResponse
+	params := map[string]string{"symbol": symbol}
+	if err := c.get(ctx, "/api/v1/contract/detail", params, &resp); err != nil {
+		return ContractDetail{}, err
+	}
+	return resp.Data, nil
+}
+
+// defaultFundingCycle is the funding interval in hours when MEXC doesn't
+// report one.
+const defaultFundingCycle = 8
+
+// FundingRate is a contract's current funding rate, which is what the next
+// settlement will charge if it doesn't move before then. Longs pay shorts
+// when Rate is positive.
+type FundingRate struct {
+	Symbol string  `json:"symbol"`
+	Rate   float64 `json:"fundingRate"`
+	// CollectCycle is the time between settlements in hours.
+	CollectCycle   int   `json:"collectCycle"`
+	NextSettleTime int64 `json:"nextSettleTime"` // milliseconds since the epoch
+}
+
+// SettlementsPerDay is how many times a day funding is exchanged.
+func (f FundingRate) SettlementsPerDay() float64 {
+	cycle := f.CollectCycle
+	if cycle <= 0 {
+		cycle = defaultFundingCycle
+	}
+	return 24 / float64(cycle)
+}
+
+type fundingRateResponse struct {
+	Data FundingRate `json:"data"`
+}
+
+// FundingRate returns the current funding rate of symbol.
+func (c *Client) FundingRate(ctx context.Context, symbol string) (FundingRate, error) {
+	var resp fundingRateResponse
+	endpoint := fmt.Sprintf("/api/v1/contract/funding_rate/%s", symbol)
+	if err := c.get(ctx, endpoint, nil, &resp); err != nil {
+		return FundingRate{}, err
+	}
+	return resp.Data, nil
+}
+
+// FundingSettlement is a funding rate that was settled.
+type FundingSettlement struct {
+	Symbol     string  `json:"symbol"`
+	Rate       float64 `json:"fundingRate"`
+	SettleTime int64   `json:"settleTime"` // milliseconds since the epoch
+}
+
+type fundingHistoryResponse struct {
+	Data struct {
+		ResultList []FundingSettlement `json:"resultList"`
+	} `json:"data"`
+}
+
+// LastFunding returns the latest settled funding rate of symbol.
+func (c *Client) LastFunding(ctx context.Context, symbol string) (FundingSettlement, error) {
+	var resp fundingHistoryResponse
+	params := map[string]string{"symbol": symbol, "page_num": "1", "page_size": "1"}
+	if err := c.get(ctx, "/api/v1/contract/funding_rate/history", params, &resp); err != nil {
+		return FundingSettlement{}, err
+	}
+	if len(resp.Data.ResultList) == 0 {
+		return FundingSettlement{}, fmt.Errorf("no funding settlements for %s", symbol)
+	}
+	return resp.Data.ResultList[0], nil
+}
+
+// Level is one price level of an order book.
+type Level struct {
+	Price  float64
+	Volume float64
+}
+
+// OrderBook is a depth snapshot, best prices first.
+type OrderBook struct {
+	Bids []Level
+	Asks []Level
+}
+
+type depthResponse struct {
+	Data struct {
+		// Each entry is [price, volume, order count].
+		Bids [][]float64 `json:"bids"`
+		Asks [][]float64 `json:"asks"`
+	} `json:"data"`
+}
+
+// Depth returns up to limit levels per side of the order book for symbol.
+func (c *Client) Depth(ctx context.Context, symbol string, limit int) (OrderBook, error) {
+	var resp depthResponse
+	endpoint := fmt.Sprintf("/api/v1/contract/depth/%s", symbol)
+	params := map[string]string{"limit": strconv.Itoa(limit)}
+	if err := c.get(ctx, endpoint, params, &resp); err != nil {
+		return OrderBook{}, err
+	}
+	return OrderBook{Bids: toLevels(resp.Data.Bids), Asks: toLevels(resp.Data.Asks)}, nil
+}
+
+func toLevels(raw [][]float64) []Level {
+	levels := make([]Level, 0, len(raw))
+	for _, entry := range raw {
+		if len(entry) < 2 {
+			continue
+		}
+		levels = append(levels, Level{Price: entry[0], Volume: entry[1]})
+	}
+	return levels
+}
+
+// Imbalance compares bid and ask volume over the top levels of each side.
+// The result ranges from -1 (all asks) to +1 (all bids); zero means balanced
+// or empty. levels <= 0 uses the whole snapshot.
+func (b OrderBook) Imbalance(levels int) float64 {
+	bidVol := sumVolume(b.Bids, levels)
+	askVol := sumVolume(b.Asks, levels)
+	if bidVol+askVol == 0 {
+		return 0
+	}
+	return (bidVol - askVol) / (bidVol + askVol)
+}
+
+func sumVolume(side []Level, levels int) float64 {
+	if levels > 0 && levels < len(side) {
+		side = side[:levels]
+	}
+	var total float64
+	for _, l := range side {
+		total += l.Volume
+	}
+	return total
+}
@@ -0,0 +1,62 @@
+package mexc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Order types accepted by SubmitOrder.
+const (
+	OrderTypeLimit  = 1
+	OrderTypeMarket = 5
+)
+
+// Margin modes of a position or order.
+const (
+	OpenTypeIsolated = 1
+	OpenTypeCross    = 2
+)
+
+// OrderRequest is a new futures order.
+type OrderRequest struct {
+	Symbol string  `json:"symbol"`
+	Price  float64 `json:"price,omitempty"` // ignored for market orders
+	Vol    float64 `json:"vol"`             // in contracts
+	// Side is one of OrderSide*; the close sides only reduce a position.
+	Side     int `json:"side"`
+	Type     int `json:"type"`
+	OpenType int `json:"openType"`
+	Leverage int `json:"leverage,omitempty"`
+	// PositionID targets a specific position when closing.
+	PositionID int64 `json:"positionId,omitempty"`
+}
+
+type submitOrderResponse struct {
+	Data json.RawMessage `json:"data"`
+}
+
+// orderID extracts the order ID, which MEXC returns either as the bare data
+// value or as data.orderId depending on the API version.
+func (r submitOrderResponse) orderID() string {
+	var wrapped struct {
+		OrderID json.Number `json:"orderId"`
+	}
+	if json.Unmarshal(r.Data, &wrapped) == nil && wrapped.OrderID != "" {
+		return wrapped.OrderID.String()
+	}
+	return strings.Trim(string(r.Data), `"`)
+}
+
+// SubmitOrder places req and returns the new order's ID.
+func (c *Client) SubmitOrder(ctx context.Context, req OrderRequest) (string, error) {
+	if req.Vol <= 0 {
+		return "", errors.New("order volume must be positive")
+	}
+	var resp submitOrderResponse
+	if err := c.post(ctx, "/api/v1/private/order/submit", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.orderID(), nil
+}
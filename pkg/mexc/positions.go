@@ -0,0 +1,115 @@
+package mexc
+
+import (
+	"context"
+	"time"
+)
+
+// Position types as reported by MEXC.
+const (
+	PositionTypeLong  = 1
+	PositionTypeShort = 2
+)
+
+// Position states as reported by MEXC.
+const (
+	PositionStateHolding       = 1
+	PositionStateSystemHolding = 2
+	PositionStateClosed        = 3
+)
+
+// Position is a single open futures position.
+type Position struct {
+	PositionID   int64   `json:"positionId"`
+	Symbol       string  `json:"symbol"`
+	PositionType int     `json:"positionType"`
+	State        int     `json:"state"`
+	HoldVol      float64 `json:"holdVol"`
+	HoldAvgPrice float64 `json:"holdAvgPrice"`
+	Realised     float64 `json:"realised"`
+	Leverage     int     `json:"leverage"`
+	OpenType     int     `json:"openType"`   // OpenTypeIsolated or OpenTypeCross
+	CreateTime   int64   `json:"createTime"` // milliseconds since the epoch
+}
+
+// OpenedAt returns when the position was opened.
+func (p Position) OpenedAt() time.Time {
+	return time.UnixMilli(p.CreateTime)
+}
+
+// Side returns "long" or "short".
+func (p Position) Side() string {
+	if p.PositionType == PositionTypeShort {
+		return "short"
+	}
+	return "long"
+}
+
+// UnrealizedPnL values the position at price, in the contract's settlement
+// currency. contractSize is the amount of base asset per contract.
+func (p Position) UnrealizedPnL(price, contractSize float64) float64 {
+	pnl := (price - p.HoldAvgPrice) * p.HoldVol * contractSize
+	if p.PositionType == PositionTypeShort {
+		pnl = -pnl
+	}
+	return pnl
+}
+
+// FundingStep is the funding rate change Sensitivity.PerFundingStep is
+// quoted for: 0.01%.
+const FundingStep = 0.0001
+
+// Sensitivity is how a position's PnL responds to the market, in the
+// contract's settlement currency.
+type Sensitivity struct {
+	// Notional is the position's value at the fair price.
+	Notional float64
+	// PerPercent is the PnL change for a 1% rise in price.
+	PerPercent float64
+	// PerFundingStep is the change in each funding payment received when the
+	// rate rises by FundingStep.
+	PerFundingStep float64
+	// FundingPerDay is the funding received per day at the current rate;
+	// negative means paid.
+	FundingPerDay float64
+}
+
+// Add returns the sum of two sensitivities, e.g. for a portfolio total.
+func (s Sensitivity) Add(other Sensitivity) Sensitivity {
+	return Sensitivity{
+		Notional:       s.Notional + other.Notional,
+		PerPercent:     s.PerPercent + other.PerPercent,
+		PerFundingStep: s.PerFundingStep + other.PerFundingStep,
+		FundingPerDay:  s.FundingPerDay + other.FundingPerDay,
+	}
+}
+
+// Sensitivity returns the position's exposure to price and funding at price,
+// the fair price. contractSize is the amount of base asset per contract.
+func (p Position) Sensitivity(price, contractSize float64, funding FundingRate) Sensitivity {
+	notional := p.HoldVol * contractSize * price
+	direction := 1.0
+	if p.PositionType == PositionTypeShort {
+		direction = -1
+	}
+	// Longs pay positive funding, so their funding moves against the rate.
+	return Sensitivity{
+		Notional:       notional,
+		PerPercent:     direction * notional * 0.01,
+		PerFundingStep: -direction * notional * FundingStep,
+		FundingPerDay:  -direction * notional * funding.Rate * funding.SettlementsPerDay(),
+	}
+}
+
+type openPositionsResponse struct {
+	Data []Position `json:"data"`
+}
+
+// OpenPositions returns all open futures positions on the account.
+func (c *Client) OpenPositions(ctx context.Context) ([]Position, error) {
+	var resp openPositionsResponse
+	if err := c.get(ctx, "/api/v1/private/position/open_positions", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
@@ -0,0 +1,180 @@
+package mexc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Order sides as reported by MEXC.
+const (
+	OrderSideOpenLong   = 1
+	OrderSideCloseShort = 2
+	OrderSideOpenShort  = 3
+	OrderSideCloseLong  = 4
+)
+
+// Order states as reported by MEXC.
+const (
+	OrderStateUninformed  = 1
+	OrderStateUncompleted = 2
+	OrderStateCompleted   = 3
+	OrderStateCancelled   = 4
+	OrderStateInvalid     = 5
+)
+
+// Order is a futures order as pushed on the private stream.
+type Order struct {
+	OrderID      string  `json:"orderId"`
+	Symbol       string  `json:"symbol"`
+	PositionID   int64   `json:"positionId"`
+	Price        float64 `json:"price"`
+	Vol          float64 `json:"vol"`
+	Side         int     `json:"side"`
+	State        int     `json:"state"`
+	DealVol      float64 `json:"dealVol"`
+	DealAvgPrice float64 `json:"dealAvgPrice"`
+	Profit       float64 `json:"profit"`
+	UpdateTime   int64   `json:"updateTime"`
+}
+
+// SideName describes the order side, e.g. "open long".
+func (o Order) SideName() string {
+	switch o.Side {
+	case OrderSideOpenLong:
+		return "open long"
+	case OrderSideCloseShort:
+		return "close short"
+	case OrderSideOpenShort:
+		return "open short"
+	case OrderSideCloseLong:
+		return "close long"
+	}
+	return "side " + strconv.Itoa(o.Side)
+}
+
+// Done reports whether the order can no longer fill.
+func (o Order) Done() bool {
+	return o.State == OrderStateCompleted || o.State == OrderStateCancelled || o.State == OrderStateInvalid
+}
+
+// ADLLevel is a position's auto-deleveraging rank, from 1 (lowest) to 5.
+type ADLLevel struct {
+	PositionID int64 `json:"positionId"`
+	Level      int   `json:"adlLevel"`
+}
+
+// PrivateEventKind identifies the payload of a PrivateEvent.
+type PrivateEventKind int
+
+const (
+	PositionChanged PrivateEventKind = iota
+	OrderChanged
+	ADLChanged
+)
+
+// PrivateEvent is an account update pushed on the private stream.
+type PrivateEvent struct {
+	Kind     PrivateEventKind
+	Position Position
+	Order    Order
+	ADL      ADLLevel
+}
+
+// PrivateStream is an authenticated WebSocket connection delivering
+// position, order and ADL updates for the account. It logs in again every
+// time it reconnects.
+type PrivateStream struct {
+	url    string
+	client *Client
+	events chan PrivateEvent
+
+	// OnError, if set, is called for connection and login errors before reconnecting.
+	OnError func(error)
+}
+
+// PrivateStream returns a stream for url authenticated with the client's
+// active key pair, so a reconnect after a key failover logs in with the
+// secondary.
+func (c *Client) PrivateStream(url string) *PrivateStream {
+	return &PrivateStream{
+		url:    url,
+		client: c,
+		events: make(chan PrivateEvent, 64),
+	}
+}
+
+// Events delivers account updates. It is closed when Run returns.
+func (s *PrivateStream) Events() <-chan PrivateEvent {
+	return s.events
+}
+
+// Run maintains the connection until ctx is canceled.
+func (s *PrivateStream) Run(ctx context.Context) error {
+	defer close(s.events)
+
+	return runWebSocket(ctx, s.url, wsHandlers{
+		connected: func(conn *wsConn) error {
+			accessKey, secretKey, _ := s.client.keys()
+			reqTime := strconv.FormatInt(time.Now().UnixMilli(), 10)
+			err := conn.call("login", map[string]string{
+				"apiKey":    accessKey,
+				"reqTime":   reqTime,
+				"signature": sign(accessKey, secretKey, reqTime, ""),
+			})
+			if err != nil {
+				return fmt.Errorf("sending login: %w", err)
+			}
+			return nil
+		},
+		message: func(msg streamMessage) error {
+			ev, ok, err := decodePrivate(msg)
+			if err != nil || !ok {
+				return err
+			}
+			select {
+			case s.events <- ev:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+		onError: func(err error) {
+			if s.OnError != nil {
+				s.OnError(fmt.Errorf("private stream: %w", err))
+			}
+		},
+	})
+}
+
+// decodePrivate turns a pushed message into an event. Login failures are
+// returned as errors so the connection is dropped and retried.
+func decodePrivate(msg streamMessage) (PrivateEvent, bool, error) {
+	var ev PrivateEvent
+	var target interface{}
+	switch msg.Channel {
+	case "rs.login":
+		var result string
+		if json.Unmarshal(msg.Data, &result) != nil || result != "success" {
+			return ev, false, fmt.Errorf("%w: login rejected: %s", ErrUnauthorized, msg.Data)
+		}
+		return ev, false, nil
+	case "rs.error":
+		return ev, false, fmt.Errorf("server error: %s", msg.Data)
+	case "push.personal.position":
+		ev.Kind, target = PositionChanged, &ev.Position
+	case "push.personal.order":
+		ev.Kind, target = OrderChanged, &ev.Order
+	case "push.personal.adl.level":
+		ev.Kind, target = ADLChanged, &ev.ADL
+	default:
+		return ev, false, nil
+	}
+
+	if err := json.Unmarshal(msg.Data, target); err != nil {
+		return ev, false, nil
+	}
+	return ev, true, nil
+}
@@ -0,0 +1,73 @@
+package mexc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// expectLogin fails unless the client's next request is a login signed with
+// the test key pair.
+func expectLogin(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	call := readCall(t, conn)
+	if call.Method != "login" || call.Param["apiKey"] != testAccessKey {
+		t.Fatalf("request = %+v, want a login with %s", call, testAccessKey)
+	}
+	if got, want := call.Param["signature"], wantSignature(call.Param["reqTime"], ""); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+}
+
+func nextEvent(t *testing.T, s *PrivateStream) PrivateEvent {
+	t.Helper()
+	select {
+	case ev := <-s.Events():
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+		return PrivateEvent{}
+	}
+}
+
+func TestPrivateStreamLogsInAgain(t *testing.T) {
+	fastStreams(t, time.Hour)
+	f, url := newFakeWS(t)
+	s := NewClient(testAccessKey, []byte(testSecretKey), "").PrivateStream(url)
+	var errs chan error
+	s.OnError, errs = collectErrors()
+	startStream(t, s.Run)
+
+	conn := f.accept(t)
+	expectLogin(t, conn)
+	conn.WriteJSON(map[string]interface{}{"channel": "rs.login", "data": "success"})
+	conn.WriteJSON(map[string]interface{}{"channel": "push.personal.position", "data": map[string]interface{}{"positionId": 7, "symbol": "BTC_USDT", "holdVol": 2, "state": PositionStateHolding}})
+	conn.WriteJSON(map[string]interface{}{"channel": "push.personal.order", "data": map[string]interface{}{"orderId": "9", "symbol": "BTC_USDT", "side": OrderSideCloseLong, "state": OrderStateCompleted}})
+	conn.WriteJSON(map[string]interface{}{"channel": "push.personal.adl.level", "data": map[string]interface{}{"positionId": 7, "adlLevel": 4}})
+
+	if ev := nextEvent(t, s); ev.Kind != PositionChanged || ev.Position.PositionID != 7 || ev.Position.HoldVol != 2 {
+		t.Errorf("event = %+v, want the position", ev)
+	}
+	if ev := nextEvent(t, s); ev.Kind != OrderChanged || ev.Order.OrderID != "9" || !ev.Order.Done() {
+		t.Errorf("event = %+v, want the completed order", ev)
+	}
+	if ev := nextEvent(t, s); ev.Kind != ADLChanged || ev.ADL.Level != 4 {
+		t.Errorf("event = %+v, want the ADL level", ev)
+	}
+
+	// Every reconnect logs in again.
+	conn.Close()
+	nextError(t, errs)
+	conn = f.accept(t)
+	expectLogin(t, conn)
+
+	// A rejected login drops the connection to retry.
+	conn.WriteJSON(map[string]interface{}{"channel": "rs.login", "data": "fail"})
+	if err := nextError(t, errs); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("error = %v, want ErrUnauthorized", err)
+	}
+	conn = f.accept(t)
+	expectLogin(t, conn)
+}
@@ -0,0 +1,123 @@
+package mexc
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint groups that rate limits apply to.
+const (
+	GroupMarket  = "market"  // public market data under /api/v1/contract/ and spot /api/v3/
+	GroupAccount = "account" // private account endpoints under /api/v1/private/ and the spot account
+	GroupOrder   = "order"   // order placement under /api/v1/private/order/
+)
+
+// RateLimit is a token bucket: requests are allowed at Rate per second on
+// average, with bursts of up to Burst.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultRateLimits stay under the MEXC contract API's published limit of 20
+// requests per 2 seconds per endpoint group.
+var DefaultRateLimits = map[string]RateLimit{
+	GroupMarket:  {Rate: 10, Burst: 20},
+	GroupAccount: {Rate: 10, Burst: 20},
+	GroupOrder:   {Rate: 10, Burst: 20},
+}
+
+// EndpointGroup returns the rate limit group of an API path.
+func EndpointGroup(endpoint string) string {
+	switch {
+	case strings.HasPrefix(endpoint, "/api/v1/private/order/"):
+		return GroupOrder
+	case strings.HasPrefix(endpoint, "/api/v1/private/"), endpoint == "/api/v3/account":
+		return GroupAccount
+	}
+	return GroupMarket
+}
+
+// RateLimiter keeps requests within a RateLimit per endpoint group. Requests
+// over the limit queue up and are let through in order. It is safe for
+// concurrent use.
+type RateLimiter struct {
+	mu      sync.Mutex
+	limits  map[string]RateLimit
+	buckets map[string]*bucket
+}
+
+// NewRateLimiter returns a limiter for the given limits by group. Groups
+// without a limit are not limited.
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{limits: limits, buckets: make(map[string]*bucket)}
+}
+
+// Wait blocks until a request to group may be sent, or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, group string) error {
+	l.mu.Lock()
+	limit, ok := l.limits[group]
+	if !ok || limit.Rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	b, ok := l.buckets[group]
+	if !ok {
+		b = newBucket(limit, time.Now())
+		l.buckets[group] = b
+	}
+	delay := b.reserve(time.Now())
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		b.cancel()
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// bucket is the state of one token bucket. Tokens go negative while requests
+// are queued; each queued request waits for its own token to refill.
+type bucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit RateLimit, now time.Time) *bucket {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+}
+
+// reserve takes a token and returns how long to wait until it is available.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	if burst := float64(b.limit.Burst); b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.limit.Rate * float64(time.Second))
+}
+
+// cancel returns a reserved token that won't be used.
+func (b *bucket) cancel() {
+	b.tokens++
+}
@@ -0,0 +1,105 @@
+package mexc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEndpointGroup(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"/api/v1/contract/fair_price/BTC_USDT", GroupMarket},
+		{"/api/v3/depth", GroupMarket},
+		{"/api/v1/private/position/open_positions", GroupAccount},
+		{"/api/v3/account", GroupAccount},
+		{"/api/v1/private/order/submit", GroupOrder},
+	}
+	for _, tt := range tests {
+		if got := EndpointGroup(tt.endpoint); got != tt.want {
+			t.Errorf("EndpointGroup(%q) = %q, want %q", tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestBucketReserve(t *testing.T) {
+	type step struct {
+		at     time.Duration // since the bucket was created
+		cancel bool          // return the token instead of taking one
+		want   time.Duration
+	}
+	tests := []struct {
+		name  string
+		limit RateLimit
+		steps []step
+	}{
+		{"burst then queue", RateLimit{Rate: 10, Burst: 3}, []step{
+			{0, false, 0}, {0, false, 0}, {0, false, 0},
+			{0, false, 100 * time.Millisecond},
+			{0, false, 200 * time.Millisecond},
+		}},
+		{"refill", RateLimit{Rate: 10, Burst: 2}, []step{
+			{0, false, 0}, {0, false, 0},
+			{50 * time.Millisecond, false, 50 * time.Millisecond},
+			{200 * time.Millisecond, false, 0},
+		}},
+		{"refill is capped at burst", RateLimit{Rate: 10, Burst: 2}, []step{
+			{time.Hour, false, 0}, {time.Hour, false, 0},
+			{time.Hour, false, 100 * time.Millisecond},
+		}},
+		{"canceled reservation", RateLimit{Rate: 1, Burst: 1}, []step{
+			{0, false, 0},
+			{0, false, time.Second},
+			{0, true, 0},
+			{0, false, time.Second},
+		}},
+		{"zero burst allows one", RateLimit{Rate: 2}, []step{
+			{0, false, 0},
+			{0, false, 500 * time.Millisecond},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			b := newBucket(tt.limit, start)
+			for i, s := range tt.steps {
+				if s.cancel {
+					b.cancel()
+					continue
+				}
+				got := b.reserve(start.Add(s.at))
+				if diff := got - s.want; diff < -time.Microsecond || diff > time.Microsecond {
+					t.Errorf("step %d: reserve at %v = %v, want %v", i, s.at, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(map[string]RateLimit{GroupOrder: {Rate: 0.001, Burst: 1}})
+
+	for i := 0; i < 5; i++ {
+		if err := l.Wait(context.Background(), GroupMarket); err != nil {
+			t.Fatalf("unlimited group: %v", err)
+		}
+	}
+	if err := l.Wait(context.Background(), GroupOrder); err != nil {
+		t.Fatalf("first order: %v", err)
+	}
+
+	// The next token is 1000 seconds away; a canceled wait gives it back.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, GroupOrder); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want the context's deadline", err)
+	}
+	l.mu.Lock()
+	tokens := l.buckets[GroupOrder].tokens
+	l.mu.Unlock()
+	if tokens < -0.01 {
+		t.Errorf("tokens = %v after a canceled wait, want the reservation returned", tokens)
+	}
+}
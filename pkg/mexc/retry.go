@@ -0,0 +1,130 @@
+package mexc
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried. Network errors, 5xx
+// responses, rate limiting and "service busy" API errors are retried; other
+// errors, such as a bad signature, are returned at once. Only GET requests
+// are retried, so an order is never submitted twice.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first; 1 or
+	// less disables retries.
+	MaxAttempts int
+	// BaseDelay is the upper bound of the wait before the first retry. It
+	// doubles on every further retry, and the actual wait is picked at
+	// random below it so that clients don't retry in lockstep.
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between attempts. A Retry-After longer than
+	// this ends the retries.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is the policy NewClient starts with.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// backoff returns the wait before retry number n (starting at 1).
+func (p RetryPolicy) backoff(n int) time.Duration {
+	ceiling := p.BaseDelay
+	for i := 1; i < n && ceiling < p.MaxDelay; i++ {
+		ceiling *= 2
+	}
+	if p.MaxDelay > 0 && ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// doWithRetry calls attempt until it succeeds, fails with an error that isn't
+// worth retrying, or the policy runs out. attempt returns any Retry-After
+// delay the server asked for.
+func (c *Client) doWithRetry(ctx context.Context, method string, attempt func() (time.Duration, error)) error {
+	attempts := c.Retry.MaxAttempts
+	if method != http.MethodGet || attempts < 1 {
+		attempts = 1
+	}
+	for n := 1; ; n++ {
+		retryAfter, err := attempt()
+		if err == nil || n >= attempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := c.Retry.backoff(n)
+		if retryAfter > 0 {
+			if c.Retry.MaxDelay > 0 && retryAfter > c.Retry.MaxDelay {
+				return err
+			}
+			delay = retryAfter
+		}
+		if c.OnRetry != nil {
+			c.OnRetry(err, delay)
+		}
+
+		if c.wait(ctx, delay) != nil {
+			return err
+		}
+	}
+}
+
+// wait blocks for d, or until ctx is done.
+func (c *Client) wait(ctx context.Context, d time.Duration) error {
+	if c.sleep != nil {
+		return c.sleep(ctx, d)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryable reports whether err is likely to go away on its own.
+func retryable(err error) bool {
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) {
+		return false
+	}
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return false
+	}
+	// No status means no response: a network error or timeout. A canceled
+	// context is caught before this is called.
+	return reqErr.StatusCode == 0 || reqErr.StatusCode >= 500
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date. It returns zero if the header is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
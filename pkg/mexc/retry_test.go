@@ -0,0 +1,238 @@
+package mexc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSleep replaces the retry timer for c and returns the waits it was
+// asked for.
+func fakeSleep(c *Client) *[]time.Duration {
+	var mu sync.Mutex
+	waits := new([]time.Duration)
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		*waits = append(*waits, d)
+		return ctx.Err()
+	}
+	return waits
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	ceilings := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, ceiling := range ceilings {
+		n := i + 1
+		seen := make(map[time.Duration]bool)
+		for j := 0; j < 200; j++ {
+			d := p.backoff(n)
+			if d < 0 || d >= ceiling {
+				t.Fatalf("backoff(%d) = %v, want in [0, %v)", n, d, ceiling)
+			}
+			seen[d] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("backoff(%d) returned the same wait 200 times; want jitter", n)
+		}
+	}
+
+	if d := (RetryPolicy{MaxAttempts: 3}).backoff(2); d != 0 {
+		t.Errorf("backoff without a base delay = %v, want 0", d)
+	}
+}
+
+func TestDoWithRetry(t *testing.T) {
+	var (
+		serverErr   = &RequestError{Endpoint: "/x", StatusCode: http.StatusBadGateway, Err: errors.New("bad gateway")}
+		networkErr  = &RequestError{Endpoint: "/x", Err: errors.New("connection reset")}
+		rateLimited = &RequestError{Endpoint: "/x", StatusCode: http.StatusTooManyRequests, Err: ErrRateLimited}
+		busy        = &RequestError{Endpoint: "/x", StatusCode: http.StatusOK, Err: &APIError{Code: 501, Message: "busy"}}
+		badSig      = &RequestError{Endpoint: "/x", StatusCode: http.StatusOK, Err: &APIError{Code: 602, Message: "bad signature"}}
+		badRequest  = &RequestError{Endpoint: "/x", StatusCode: http.StatusBadRequest, Err: errors.New("bad request")}
+		local       = errors.New("creating request")
+	)
+	type result struct {
+		retryAfter time.Duration
+		err        error
+	}
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		name    string
+		method  string
+		policy  RetryPolicy
+		results []result
+		// attempts is how many calls are made, and the error returned is
+		// that of the last one.
+		attempts int
+		// retryAfter, if set, is the wait expected before every retry;
+		// otherwise waits are checked against the backoff.
+		retryAfter time.Duration
+	}{
+		{"success", http.MethodGet, policy, []result{{0, nil}}, 1, 0},
+		{"server error then success", http.MethodGet, policy, []result{{0, serverErr}, {0, nil}}, 2, 0},
+		{"network error and rate limit", http.MethodGet, policy, []result{{0, networkErr}, {0, rateLimited}, {0, nil}}, 3, 0},
+		{"busy until attempts run out", http.MethodGet, policy, []result{{0, busy}, {0, busy}, {0, busy}, {0, nil}}, 3, 0},
+		{"post not retried", http.MethodPost, policy, []result{{0, serverErr}, {0, nil}}, 1, 0},
+		{"bad signature not retried", http.MethodGet, policy, []result{{0, badSig}, {0, nil}}, 1, 0},
+		{"client error not retried", http.MethodGet, policy, []result{{0, badRequest}, {0, nil}}, 1, 0},
+		{"local error not retried", http.MethodGet, policy, []result{{0, local}, {0, nil}}, 1, 0},
+		{"retries disabled", http.MethodGet, RetryPolicy{MaxAttempts: 1}, []result{{0, serverErr}, {0, nil}}, 1, 0},
+		{"retry after", http.MethodGet, policy, []result{{300 * time.Millisecond, rateLimited}, {300 * time.Millisecond, rateLimited}, {0, nil}}, 3, 300 * time.Millisecond},
+		{"retry after past max delay", http.MethodGet, policy, []result{{time.Minute, rateLimited}, {0, nil}}, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(testAccessKey, []byte(testSecretKey), "http://mexc.invalid")
+			c.Retry = tt.policy
+			waits := fakeSleep(c)
+			var retried []time.Duration
+			c.OnRetry = func(err error, delay time.Duration) { retried = append(retried, delay) }
+
+			calls := 0
+			err := c.doWithRetry(context.Background(), tt.method, func() (time.Duration, error) {
+				r := tt.results[calls]
+				calls++
+				return r.retryAfter, r.err
+			})
+
+			if calls != tt.attempts {
+				t.Errorf("made %d attempts, want %d", calls, tt.attempts)
+			}
+			if want := tt.results[calls-1].err; err != want {
+				t.Errorf("err = %v, want %v", err, want)
+			}
+			if len(*waits) != calls-1 || len(retried) != calls-1 {
+				t.Fatalf("waited %v and reported %v, want %d waits", *waits, retried, calls-1)
+			}
+			for i, wait := range *waits {
+				if retried[i] != wait {
+					t.Errorf("OnRetry got %v for a wait of %v", retried[i], wait)
+				}
+				if tt.retryAfter != 0 {
+					if wait != tt.retryAfter {
+						t.Errorf("wait %d = %v, want the Retry-After of %v", i+1, wait, tt.retryAfter)
+					}
+				} else if ceiling := tt.policy.BaseDelay << i; wait < 0 || wait >= ceiling {
+					t.Errorf("wait %d = %v, want in [0, %v)", i+1, wait, ceiling)
+				}
+			}
+		})
+	}
+}
+
+func TestDoWithRetryCanceled(t *testing.T) {
+	retryable := &RequestError{Endpoint: "/x", Err: errors.New("timeout")}
+
+	t.Run("during the wait", func(t *testing.T) {
+		c := NewClient(testAccessKey, []byte(testSecretKey), "http://mexc.invalid")
+		ctx, cancel := context.WithCancel(context.Background())
+		c.sleep = func(ctx context.Context, d time.Duration) error {
+			cancel()
+			return ctx.Err()
+		}
+		calls := 0
+		err := c.doWithRetry(ctx, http.MethodGet, func() (time.Duration, error) {
+			calls++
+			return 0, retryable
+		})
+		if calls != 1 || err != retryable {
+			t.Errorf("made %d attempts with err %v, want 1 with the request error", calls, err)
+		}
+	})
+
+	t.Run("before the wait", func(t *testing.T) {
+		c := NewClient(testAccessKey, []byte(testSecretKey), "http://mexc.invalid")
+		waits := fakeSleep(c)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		calls := 0
+		c.doWithRetry(ctx, http.MethodGet, func() (time.Duration, error) {
+			calls++
+			return 0, retryable
+		})
+		if calls != 1 || len(*waits) != 0 {
+			t.Errorf("made %d attempts and %d waits, want 1 and none", calls, len(*waits))
+		}
+	})
+}
+
+func TestRetryOverHTTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int // one per request, then 200
+		post     bool
+		requests int
+		wantErr  bool
+	}{
+		{"rate limited then unavailable", []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}, false, 3, false},
+		{"unavailable until attempts run out", []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}, false, 3, true},
+		{"post not retried", []int{http.StatusServiceUnavailable}, true, 1, true},
+		{"forbidden not retried", []int{http.StatusForbidden}, false, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := 0
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				n := requests
+				requests++
+				mu.Unlock()
+				if n < len(tt.statuses) {
+					if tt.statuses[n] == http.StatusTooManyRequests {
+						w.Header().Set("Retry-After", "1")
+					}
+					w.WriteHeader(tt.statuses[n])
+					w.Write([]byte("not json"))
+					return
+				}
+				w.Write([]byte(`{"success":true,"code":0,"data":{"symbol":"BTC_USDT","fairPrice":60000}}`))
+			})
+			c.Retry.MaxDelay = 2 * time.Second
+			waits := fakeSleep(c)
+
+			var err error
+			if tt.post {
+				err = c.post(context.Background(), "/api/v1/private/order/submit", map[string]string{"symbol": "BTC_USDT"}, &envelope{})
+			} else {
+				_, err = c.FairPrice(context.Background(), "BTC_USDT")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if requests != tt.requests {
+				t.Errorf("server got %d requests, want %d", requests, tt.requests)
+			}
+			if tt.statuses[0] == http.StatusTooManyRequests && (len(*waits) == 0 || (*waits)[0] != time.Second) {
+				t.Errorf("waits = %v, want the 1s Retry-After first", *waits)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"", 0, 0},
+		{"0", 0, 0},
+		{"-5", 0, 0},
+		{"soon", 0, 0},
+		{"3", 3 * time.Second, 3 * time.Second},
+		{time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), 59 * time.Minute, time.Hour},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got < tt.min || got > tt.max {
+			t.Errorf("parseRetryAfter(%q) = %v, want in [%v, %v]", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
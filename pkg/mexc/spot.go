@@ -0,0 +1,210 @@
+package mexc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSpotBaseURL is the production spot API endpoint.
+const DefaultSpotBaseURL = "https://api.mexc.com"
+
+// spotRecvWindow is how long after its timestamp a signed spot request stays
+// valid, in milliseconds.
+const spotRecvWindow = "5000"
+
+// spotQuote is the currency spot holdings are valued in.
+const spotQuote = "USDT"
+
+// SpotBalance is the spot account balance of one asset.
+type SpotBalance struct {
+	Asset  string
+	Free   float64
+	Locked float64 // held by open orders
+}
+
+// Total is the free and locked balance together.
+func (b SpotBalance) Total() float64 {
+	return b.Free + b.Locked
+}
+
+type spotAccountResponse struct {
+	Balances []struct {
+		Asset  string `json:"asset"`
+		Free   string `json:"free"`
+		Locked string `json:"locked"`
+	} `json:"balances"`
+}
+
+// SpotBalances returns every non-zero asset balance of the spot account.
+func (c *Client) SpotBalances(ctx context.Context) ([]SpotBalance, error) {
+	var resp spotAccountResponse
+	if err := c.spotGet(ctx, "/api/v3/account", true, &resp); err != nil {
+		return nil, err
+	}
+	var balances []SpotBalance
+	for _, b := range resp.Balances {
+		free, _ := strconv.ParseFloat(b.Free, 64)
+		locked, _ := strconv.ParseFloat(b.Locked, 64)
+		if free == 0 && locked == 0 {
+			continue
+		}
+		balances = append(balances, SpotBalance{Asset: b.Asset, Free: free, Locked: locked})
+	}
+	return balances, nil
+}
+
+// SpotPrices returns the last price of every spot pair, keyed by the spot
+// symbol, e.g. "BTCUSDT".
+func (c *Client) SpotPrices(ctx context.Context) (map[string]float64, error) {
+	var resp []struct {
+		Symbol string `json:"symbol"`
+		Price  string `json:"price"`
+	}
+	if err := c.spotGet(ctx, "/api/v3/ticker/price", false, &resp); err != nil {
+		return nil, err
+	}
+	prices := make(map[string]float64, len(resp))
+	for _, p := range resp {
+		if v, err := strconv.ParseFloat(p.Price, 64); err == nil {
+			prices[p.Symbol] = v
+		}
+	}
+	return prices, nil
+}
+
+// SpotHolding is a spot balance valued in USDT.
+type SpotHolding struct {
+	SpotBalance
+	// Price is the asset's price in USDT, or zero when it has no USDT pair.
+	Price float64
+}
+
+// Value is the holding's worth in USDT.
+func (h SpotHolding) Value() float64 {
+	return h.Total() * h.Price
+}
+
+// SpotHoldings returns the spot balances priced in USDT, most valuable
+// first.
+func (c *Client) SpotHoldings(ctx context.Context) ([]SpotHolding, error) {
+	balances, err := c.SpotBalances(ctx)
+	if err != nil || len(balances) == 0 {
+		return nil, err
+	}
+	prices, err := c.SpotPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ValueSpot(balances, prices), nil
+}
+
+// ValueSpot prices balances with prices as returned by SpotPrices, most
+// valuable first. Assets without a USDT pair come last with a zero price.
+func ValueSpot(balances []SpotBalance, prices map[string]float64) []SpotHolding {
+	holdings := make([]SpotHolding, len(balances))
+	for i, b := range balances {
+		holdings[i] = SpotHolding{SpotBalance: b, Price: SpotPrice(prices, b.Asset)}
+	}
+	sort.SliceStable(holdings, func(i, j int) bool { return holdings[i].Value() > holdings[j].Value() })
+	return holdings
+}
+
+// SpotPrice looks up the USDT price of asset in prices as returned by
+// SpotPrices. USDT is worth 1 and assets without a USDT pair 0.
+func SpotPrice(prices map[string]float64, asset string) float64 {
+	if asset == spotQuote {
+		return 1
+	}
+	return prices[asset+spotQuote]
+}
+
+// spotSign returns the signature of a spot request's query string.
+func spotSign(secretKey []byte, query string) string {
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte(query))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// spotGet sends a GET request to the spot API, which unlike the contract API
+// signs the query string alone and takes the key in X-MEXC-APIKEY. It is
+// retried and fails over between key pairs like contract requests.
+func (c *Client) spotGet(ctx context.Context, endpoint string, signed bool, out interface{}) error {
+	return c.doWithFailover(ctx, func() error {
+		return c.doWithRetry(ctx, http.MethodGet, func() (time.Duration, error) {
+			return c.spotAttempt(ctx, endpoint, signed, out)
+		})
+	})
+}
+
+// spotAttempt sends a spot request once, signed afresh like attempt.
+func (c *Client) spotAttempt(ctx context.Context, endpoint string, signed bool, out interface{}) (time.Duration, error) {
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(ctx, EndpointGroup(endpoint)); err != nil {
+			return 0, err
+		}
+	}
+
+	accessKey, secretKey, _ := c.keys()
+	fullURL := strings.TrimSuffix(c.SpotBaseURL, "/") + endpoint
+	if signed {
+		query := url.Values{
+			"timestamp":  {strconv.FormatInt(time.Now().UnixMilli(), 10)},
+			"recvWindow": {spotRecvWindow},
+		}.Encode()
+		fullURL += "?" + query + "&signature=" + spotSign(secretKey, query)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+	if signed {
+		req.Header.Set("X-MEXC-APIKEY", accessKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	response, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, &RequestError{Endpoint: endpoint, Err: fmt.Errorf("sending request: %w", err)}
+	}
+	defer response.Body.Close()
+
+	retryAfter := parseRetryAfter(response.Header.Get("Retry-After"))
+	fail := func(err error) (time.Duration, error) {
+		return retryAfter, &RequestError{Endpoint: endpoint, StatusCode: response.StatusCode, Err: err}
+	}
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fail(fmt.Errorf("reading response body: %w", err))
+	}
+	if response.StatusCode == http.StatusTooManyRequests {
+		return fail(ErrRateLimited)
+	}
+	if response.StatusCode >= 300 {
+		// Spot errors are {"code": ..., "msg": ...} with a 4xx or 5xx status.
+		var spotErr struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err := json.Unmarshal(respBody, &spotErr); err == nil && spotErr.Code != 0 {
+			return fail(&APIError{Code: spotErr.Code, Message: spotErr.Msg})
+		}
+		return fail(fmt.Errorf("unexpected HTTP status %s", response.Status))
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fail(fmt.Errorf("decoding response JSON: %w", err))
+	}
+	return 0, nil
+}
@@ -0,0 +1,70 @@
+package mexc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// newTestSpotClient is newTestClient with the spot API on the test server.
+func newTestSpotClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	c := newTestClient(t, handler)
+	c.SpotBaseURL = c.baseURL
+	return c
+}
+
+func TestSpotSign(t *testing.T) {
+	// Computed with Python's hmac module.
+	const want = "f9e86f5e9f7c55bc0e90191d43b310e854719861512fb0bae495f3863239342b"
+	if got := spotSign([]byte(testSecretKey), "recvWindow=5000&timestamp=1700000000000"); got != want {
+		t.Errorf("spotSign = %s, want %s", got, want)
+	}
+}
+
+func TestSpotSignedGet(t *testing.T) {
+	c := newTestSpotClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-MEXC-APIKEY"); got != testAccessKey {
+			t.Errorf("X-MEXC-APIKEY = %q, want %q", got, testAccessKey)
+		}
+		// The signature comes last and covers the query before it.
+		query, signature, ok := strings.Cut(r.URL.RawQuery, "&signature=")
+		if !ok {
+			t.Fatalf("query %q has no signature", r.URL.RawQuery)
+		}
+		if got, want := signature, spotSign([]byte(testSecretKey), query); got != want {
+			t.Errorf("signature = %s, want %s", got, want)
+		}
+		q := r.URL.Query()
+		if q.Get("recvWindow") != spotRecvWindow || q.Get("timestamp") == "" {
+			t.Errorf("query = %s, want a timestamp and recvWindow", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"balances":[{"asset":"BTC","free":"0.5","locked":"0.1"},{"asset":"DUST","free":"0","locked":"0"}]}`))
+	})
+
+	balances, err := c.SpotBalances(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balances) != 1 || balances[0] != (SpotBalance{Asset: "BTC", Free: 0.5, Locked: 0.1}) {
+		t.Errorf("SpotBalances = %+v, want BTC without the empty balance", balances)
+	}
+}
+
+func TestSpotPublicGetUnsigned(t *testing.T) {
+	c := newTestSpotClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-MEXC-APIKEY") != "" || r.URL.RawQuery != "" {
+			t.Errorf("public request sent key %q and query %q", r.Header.Get("X-MEXC-APIKEY"), r.URL.RawQuery)
+		}
+		w.Write([]byte(`[{"symbol":"BTCUSDT","price":"60000.5"},{"symbol":"BADUSDT","price":"n/a"}]`))
+	})
+
+	prices, err := c.SpotPrices(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 1 || prices["BTCUSDT"] != 60000.5 {
+		t.Errorf("SpotPrices = %v", prices)
+	}
+}
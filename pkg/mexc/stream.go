@@ -0,0 +1,140 @@
+package mexc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PriceUpdate is a fair price pushed by the stream.
+type PriceUpdate struct {
+	Symbol string
+	Price  float64
+	Time   time.Time
+}
+
+// PriceStream keeps a WebSocket subscription to fair prices for a changing
+// set of symbols, reconnecting and resubscribing when the connection drops.
+type PriceStream struct {
+	url     string
+	updates chan PriceUpdate
+
+	// OnError, if set, is called for connection errors before reconnecting.
+	OnError func(error)
+
+	mu      sync.Mutex
+	symbols map[string]bool
+	conn    *wsConn
+}
+
+// NewPriceStream returns a stream for url. Call Run to connect.
+func NewPriceStream(url string) *PriceStream {
+	return &PriceStream{
+		url:     url,
+		updates: make(chan PriceUpdate, 64),
+		symbols: make(map[string]bool),
+	}
+}
+
+// Updates delivers fair prices for the subscribed symbols. It is closed when Run returns.
+func (s *PriceStream) Updates() <-chan PriceUpdate {
+	return s.updates
+}
+
+// SetSymbols replaces the subscribed symbols. Changes are applied to the live
+// connection immediately and remembered for reconnects.
+func (s *PriceStream) SetSymbols(symbols []string) {
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[symbol] = true
+	}
+
+	s.mu.Lock()
+	conn := s.conn
+	var added, removed []string
+	for symbol := range wanted {
+		if !s.symbols[symbol] {
+			added = append(added, symbol)
+		}
+	}
+	for symbol := range s.symbols {
+		if !wanted[symbol] {
+			removed = append(removed, symbol)
+		}
+	}
+	s.symbols = wanted
+	s.mu.Unlock()
+
+	if conn == nil {
+		return
+	}
+	// Write errors surface on the read side and trigger a reconnect, which
+	// resubscribes from s.symbols.
+	for _, symbol := range added {
+		conn.call("sub.fair.price", map[string]string{"symbol": symbol})
+	}
+	for _, symbol := range removed {
+		conn.call("unsub.fair.price", map[string]string{"symbol": symbol})
+	}
+}
+
+type fairPricePush struct {
+	Symbol string  `json:"symbol"`
+	Price  float64 `json:"price"`
+}
+
+// Run maintains the connection until ctx is canceled.
+func (s *PriceStream) Run(ctx context.Context) error {
+	defer close(s.updates)
+
+	return runWebSocket(ctx, s.url, wsHandlers{
+		connected: func(conn *wsConn) error {
+			s.mu.Lock()
+			s.conn = conn
+			symbols := make([]string, 0, len(s.symbols))
+			for symbol := range s.symbols {
+				symbols = append(symbols, symbol)
+			}
+			s.mu.Unlock()
+
+			for _, symbol := range symbols {
+				if err := conn.call("sub.fair.price", map[string]string{"symbol": symbol}); err != nil {
+					return fmt.Errorf("subscribing to %s: %w", symbol, err)
+				}
+			}
+			return nil
+		},
+		disconnected: func() {
+			s.mu.Lock()
+			s.conn = nil
+			s.mu.Unlock()
+		},
+		message: func(msg streamMessage) error {
+			if msg.Channel != "push.fair.price" {
+				return nil
+			}
+
+			var push fairPricePush
+			if err := json.Unmarshal(msg.Data, &push); err != nil {
+				return nil
+			}
+			if push.Symbol == "" {
+				push.Symbol = msg.Symbol
+			}
+
+			select {
+			case s.updates <- PriceUpdate{Symbol: push.Symbol, Price: push.Price, Time: time.UnixMilli(msg.TS)}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+		onError: func(err error) {
+			if s.OnError != nil {
+				s.OnError(fmt.Errorf("fair price stream: %w", err))
+			}
+		},
+	})
+}
@@ -0,0 +1,173 @@
+package mexc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeWS is a WebSocket server handing each connection to the test.
+type fakeWS struct {
+	conns chan *websocket.Conn
+}
+
+// newFakeWS starts a fakeWS and returns it with its URL.
+func newFakeWS(t *testing.T) (*fakeWS, string) {
+	t.Helper()
+	f := &fakeWS{conns: make(chan *websocket.Conn, 4)}
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		f.conns <- conn
+	}))
+	t.Cleanup(srv.Close)
+	return f, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// accept waits for the client's next connection.
+func (f *fakeWS) accept(t *testing.T) *websocket.Conn {
+	t.Helper()
+	select {
+	case conn := <-f.conns:
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("client didn't connect")
+		return nil
+	}
+}
+
+type wsCall struct {
+	Method string            `json:"method"`
+	Param  map[string]string `json:"param"`
+}
+
+// readCall returns the next request the client sends on conn.
+func readCall(t *testing.T, conn *websocket.Conn) wsCall {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var call wsCall
+	if err := conn.ReadJSON(&call); err != nil {
+		t.Fatalf("reading client request: %v", err)
+	}
+	return call
+}
+
+// expectCall fails unless the client's next request is method for symbol.
+func expectCall(t *testing.T, conn *websocket.Conn, method, symbol string) {
+	t.Helper()
+	if call := readCall(t, conn); call.Method != method || call.Param["symbol"] != symbol {
+		t.Fatalf("request = %+v, want %s for %s", call, method, symbol)
+	}
+}
+
+// fastStreams shortens the ping interval to ping and the reconnect backoff to
+// a millisecond for the test.
+func fastStreams(t *testing.T, ping time.Duration) {
+	t.Helper()
+	oldPing, oldBackoff := streamPingInterval, streamMinBackoff
+	streamPingInterval, streamMinBackoff = ping, time.Millisecond
+	t.Cleanup(func() { streamPingInterval, streamMinBackoff = oldPing, oldBackoff })
+}
+
+// startStream calls run in the background until the test ends.
+func startStream(t *testing.T, run func(context.Context) error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("Run = %v, want context.Canceled", err)
+		}
+	})
+}
+
+// collectErrors returns an OnError hook and the channel it reports to.
+func collectErrors() (func(error), chan error) {
+	errs := make(chan error, 16)
+	return func(err error) { errs <- err }, errs
+}
+
+func nextError(t *testing.T, errs <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-errs:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error reported")
+		return nil
+	}
+}
+
+func TestPriceStreamResubscribes(t *testing.T) {
+	fastStreams(t, time.Hour)
+	f, url := newFakeWS(t)
+	s := NewPriceStream(url)
+	var errs chan error
+	s.OnError, errs = collectErrors()
+	s.SetSymbols([]string{"BTC_USDT"})
+	startStream(t, s.Run)
+
+	conn := f.accept(t)
+	expectCall(t, conn, "sub.fair.price", "BTC_USDT")
+	conn.WriteJSON(map[string]interface{}{"channel": "pong", "data": 1700000000000})
+	conn.WriteJSON(map[string]interface{}{
+		"channel": "push.fair.price",
+		"data":    map[string]interface{}{"symbol": "BTC_USDT", "price": 60000.5},
+		"symbol":  "BTC_USDT",
+		"ts":      1700000000000,
+	})
+	select {
+	case u := <-s.Updates():
+		if u.Symbol != "BTC_USDT" || u.Price != 60000.5 || !u.Time.Equal(time.UnixMilli(1700000000000)) {
+			t.Errorf("update = %+v", u)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no price update")
+	}
+
+	// Changes apply to the live connection.
+	s.SetSymbols([]string{"ETH_USDT"})
+	expectCall(t, conn, "sub.fair.price", "ETH_USDT")
+	expectCall(t, conn, "unsub.fair.price", "BTC_USDT")
+
+	// A dropped connection is redialed and subscribed to the current set.
+	conn.Close()
+	if err := nextError(t, errs); !strings.Contains(err.Error(), "fair price stream: reading") {
+		t.Errorf("error = %v, want a read error", err)
+	}
+	conn = f.accept(t)
+	expectCall(t, conn, "sub.fair.price", "ETH_USDT")
+}
+
+func TestStreamPings(t *testing.T) {
+	fastStreams(t, 10*time.Millisecond)
+	f, url := newFakeWS(t)
+	s := NewPriceStream(url)
+	startStream(t, s.Run)
+
+	conn := f.accept(t)
+	for i := 0; i < 2; i++ {
+		if call := readCall(t, conn); call.Method != "ping" {
+			t.Fatalf("request = %+v, want a ping", call)
+		}
+		// Pongs are read and dropped without closing the connection.
+		conn.WriteJSON(map[string]interface{}{"channel": "pong", "data": 1700000000000})
+	}
+	select {
+	case u := <-s.Updates():
+		t.Errorf("update %+v from a pong", u)
+	default:
+	}
+}
@@ -0,0 +1,62 @@
+package mexc
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportOptions tunes the HTTP connections to the API.
+type TransportOptions struct {
+	// MaxIdleConnsPerHost is how many idle connections to the API are kept
+	// for reuse. Below the number of requests in flight at once, the extra
+	// connections are closed after every poll and the next poll dials them,
+	// and negotiates TLS, again.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes connections idle for longer. With a poll
+	// interval longer than this, every poll needs new connections.
+	IdleConnTimeout time.Duration
+	// KeepAlive is the TCP keep-alive interval; negative disables probes.
+	KeepAlive time.Duration
+	// DisableHTTP2 restricts requests to HTTP/1.1.
+	DisableHTTP2 bool
+}
+
+// DefaultTransportOptions keep enough idle connections for a poll at the
+// monitor's default concurrency, plus the streams' and commands' requests.
+var DefaultTransportOptions = TransportOptions{
+	MaxIdleConnsPerHost: 16,
+	IdleConnTimeout:     90 * time.Second,
+	KeepAlive:           30 * time.Second,
+}
+
+// NewTransport returns an HTTP transport with opts applied to the settings
+// of http.DefaultTransport. HTTP/2 is negotiated when the server offers it,
+// so concurrent requests share a connection.
+func NewTransport(opts TransportOptions) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	if t.MaxIdleConns < opts.MaxIdleConnsPerHost {
+		t.MaxIdleConns = opts.MaxIdleConnsPerHost
+	}
+	t.IdleConnTimeout = opts.IdleConnTimeout
+	t.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: opts.KeepAlive}).DialContext
+	t.ForceAttemptHTTP2 = !opts.DisableHTTP2
+	if opts.DisableHTTP2 {
+		// A non-nil empty map stops the transport from upgrading to HTTP/2.
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return t
+}
+
+// sharedTransport is used by every Client until SetTransport is called, so
+// clients created for the same host share its connections.
+var sharedTransport = NewTransport(DefaultTransportOptions)
+
+// SetTransport replaces the transport the client sends requests through,
+// e.g. with one from NewTransport. It must not be called while requests are
+// in flight.
+func (c *Client) SetTransport(t http.RoundTripper) {
+	c.httpClient.Transport = t
+}
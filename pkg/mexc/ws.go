@@ -0,0 +1,157 @@
+package mexc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultStreamURL is the contract WebSocket endpoint for public and private channels.
+const DefaultStreamURL = "wss://contract.mexc.com/ws"
+
+const (
+	streamReadTimeout  = time.Minute
+	streamWriteTimeout = 10 * time.Second
+	streamMaxBackoff   = 30 * time.Second
+)
+
+// Variables so tests can shorten them.
+var (
+	// The server drops connections that haven't pinged within a minute.
+	streamPingInterval = 15 * time.Second
+	streamMinBackoff   = time.Second
+)
+
+type streamMessage struct {
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+	Symbol  string          `json:"symbol"`
+	TS      int64           `json:"ts"`
+}
+
+// wsConn serializes writes; gorilla/websocket allows only one concurrent writer.
+type wsConn struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+func (c *wsConn) write(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return c.conn.WriteJSON(v)
+}
+
+func (c *wsConn) call(method string, param interface{}) error {
+	msg := map[string]interface{}{"method": method}
+	if param != nil {
+		msg["param"] = param
+	}
+	return c.write(msg)
+}
+
+// wsHandlers customizes a reconnecting WebSocket loop.
+type wsHandlers struct {
+	// connected runs after each dial, before messages are read; use it to
+	// log in and subscribe. An error drops the connection.
+	connected func(*wsConn) error
+	// message handles every decoded message. An error drops the connection.
+	message func(streamMessage) error
+	// disconnected runs after the connection closes.
+	disconnected func()
+	// onError reports connection errors before reconnecting.
+	onError func(error)
+}
+
+// runWebSocket keeps a connection to url open until ctx is canceled,
+// reconnecting with exponential backoff.
+func runWebSocket(ctx context.Context, url string, h wsHandlers) error {
+	backoff := streamMinBackoff
+	for {
+		started := time.Now()
+		err := wsSession(ctx, url, h)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if h.onError != nil {
+			h.onError(err)
+		}
+
+		// A connection that stayed up for a while earns a fresh backoff.
+		if time.Since(started) > streamReadTimeout {
+			backoff = streamMinBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > streamMaxBackoff {
+			backoff = streamMaxBackoff
+		}
+	}
+}
+
+// wsSession runs one connection until it fails or ctx is canceled.
+func wsSession(ctx context.Context, url string, h wsHandlers) error {
+	raw, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return fmt.Errorf("dialing: %w", err)
+	}
+	defer raw.Close()
+	conn := &wsConn{conn: raw}
+	if h.disconnected != nil {
+		defer h.disconnected()
+	}
+
+	if h.connected != nil {
+		if err := h.connected(conn); err != nil {
+			return err
+		}
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	ticker := time.NewTicker(streamPingInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// Unblocks ReadMessage below.
+				raw.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := conn.call("ping", nil); err != nil {
+					raw.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		raw.SetReadDeadline(time.Now().Add(streamReadTimeout))
+		_, data, err := raw.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("reading: %w", err)
+		}
+
+		var msg streamMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		if err := h.message(msg); err != nil {
+			return err
+		}
+	}
+}
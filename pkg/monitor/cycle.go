@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"context"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// CycleResult is what one poll observed. Events and errors are also passed
+// to the Handler as they happen; the result collects them for callers that
+// render, count or time a whole cycle.
+type CycleResult struct {
+	Started  time.Time
+	Duration time.Duration
+
+	// Err is set when open positions couldn't be fetched, in which case
+	// nothing else was checked.
+	Err error
+
+	// Positions are the tracked open positions.
+	Positions []mexc.Position
+	// Symbols has one entry per distinct symbol of Positions, in the same order.
+	Symbols []SymbolResult
+
+	// Events and Errors are everything passed to the Handler during the
+	// cycle, in order.
+	Events []Event
+	Errors []CycleError
+}
+
+// SymbolResult is the outcome of fetching one symbol's fair price.
+type SymbolResult struct {
+	Symbol    string
+	FairPrice float64
+	// Err is the last error when no fair price could be fetched. The
+	// symbol's positions were not checked in that case.
+	Err error
+	// Attempts is how many requests were made, including the retry at the
+	// end of the poll, and Duration how long they took together.
+	Attempts int
+	Duration time.Duration
+	// Failures is the number of polls in a row without a fair price,
+	// including this one.
+	Failures int
+}
+
+// CycleError is an error passed to Handler.HandleError. Symbol is empty for
+// account-wide errors.
+type CycleError struct {
+	Symbol string
+	Err    error
+}
+
+// Failed returns the symbols without a fair price this cycle.
+func (r CycleResult) Failed() []SymbolResult {
+	var failed []SymbolResult
+	for _, s := range r.Symbols {
+		if s.Err != nil {
+			failed = append(failed, s)
+		}
+	}
+	return failed
+}
+
+// collector records what a cycle passes to its Handler. A nil Handler only
+// records.
+type collector struct {
+	h   Handler
+	res *CycleResult
+}
+
+func (c *collector) HandleEvent(ev Event) {
+	c.res.Events = append(c.res.Events, ev)
+	if c.h != nil {
+		c.h.HandleEvent(ev)
+	}
+}
+
+func (c *collector) HandleError(symbol string, err error) {
+	c.res.Errors = append(c.res.Errors, CycleError{Symbol: symbol, Err: err})
+	if c.h != nil {
+		c.h.HandleError(symbol, err)
+	}
+}
+
+// fetchFairPrice makes one fair price request for symbol.
+func (m *Monitor) fetchFairPrice(ctx context.Context, symbol string) (SymbolResult, error) {
+	start := time.Now()
+	price, err := m.api.FairPrice(ctx, symbol)
+	return SymbolResult{Symbol: symbol, FairPrice: price, Err: err, Attempts: 1, Duration: time.Since(start)}, err
+}
@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DefaultEquityDropWindow is the window used when Options.EquityDropPercent
+// is set without Options.EquityDropWindow.
+const DefaultEquityDropWindow = time.Hour
+
+// Drawdown is how far a currency's equity has fallen from its highest value
+// within a window.
+type Drawdown struct {
+	Currency string
+	// Peak is the highest equity seen within Window, and Equity the latest.
+	Peak   float64
+	Equity float64
+	Window time.Duration
+}
+
+// Percent is the fall from the peak in percent of the peak.
+func (d Drawdown) Percent() float64 {
+	if d.Peak <= 0 || d.Equity >= d.Peak {
+		return 0
+	}
+	return (d.Peak - d.Equity) / d.Peak * 100
+}
+
+// equitySample is the equity of one currency at one refresh.
+type equitySample struct {
+	at     time.Time
+	equity float64
+}
+
+// checkEquity samples the account equity per currency and emits an
+// EquityDrop event for each currency that fell EquityDropPercent below its
+// peak within EquityDropWindow, as far as the alert policy allows.
+func (m *Monitor) checkEquity(ctx context.Context, h Handler) {
+	if m.opts.EquityDropPercent <= 0 {
+		return
+	}
+	balances, err := m.api.Balances(ctx)
+	if err != nil {
+		h.HandleError("", fmt.Errorf("fetching balances: %w", err))
+		return
+	}
+
+	now := time.Now()
+	window := m.opts.EquityDropWindow
+	seen := make(map[string]bool, len(balances))
+	for _, b := range balances {
+		seen[b.Currency] = true
+		samples := append(m.equity[b.Currency], equitySample{at: now, equity: b.Equity})
+		expired := 0
+		for expired < len(samples)-1 && now.Sub(samples[expired].at) > window {
+			expired++
+		}
+		samples = samples[expired:]
+		m.equity[b.Currency] = samples
+
+		d := Drawdown{Currency: b.Currency, Equity: b.Equity, Window: window}
+		for _, s := range samples {
+			d.Peak = math.Max(d.Peak, s.equity)
+		}
+		drop := d.Percent()
+		active := drop >= m.opts.EquityDropPercent
+		cleared := drop < m.opts.Alerts.Policy().Rearm(m.opts.EquityDropPercent)
+		m.alert(Event{Kind: EquityDrop, Drawdown: d}, equityAlertKey(b.Currency), active, cleared, h)
+	}
+	for currency := range m.equity {
+		if !seen[currency] {
+			delete(m.equity, currency)
+		}
+	}
+}
+
+func equityAlertKey(currency string) string {
+	return "equity:" + currency
+}
@@ -0,0 +1,96 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/alert"
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+)
+
+func TestDrawdownPercent(t *testing.T) {
+	tests := []struct {
+		peak, equity, want float64
+	}{
+		{1000, 900, 10},
+		{1000, 1000, 0},
+		{1000, 1100, 0},
+		{0, -50, 0},
+	}
+	for _, tt := range tests {
+		if got := (Drawdown{Peak: tt.peak, Equity: tt.equity}).Percent(); got != tt.want {
+			t.Errorf("drawdown from %v to %v = %v%%, want %v%%", tt.peak, tt.equity, got, tt.want)
+		}
+	}
+}
+
+func TestEquityDrop(t *testing.T) {
+	ex := &fakeExchange{prices: map[string]float64{}}
+	m := NewForExchange(ex, Options{
+		Threshold:         quiet,
+		Alerts:            alert.NewManager(alert.Policy{Hysteresis: 0.5}),
+		EquityDropPercent: 10,
+	})
+
+	// With a hysteresis of 0.5 the alert re-arms below a 5% drop.
+	steps := []struct {
+		name   string
+		equity float64
+		want   []string
+	}{
+		{"first sample", 1000, nil},
+		{"new peak", 1200, nil},
+		{"small drop", 1100, nil},
+		{"crossed", 1080, []string{"equity_drop"}},
+		{"deeper", 900, nil},
+		{"recovering above the re-arm level", 1130, nil},
+		{"re-armed", 1150, []string{"resolved(equity_drop)"}},
+		{"crossed again", 1000, []string{"equity_drop"}},
+	}
+	for _, s := range steps {
+		ex.balances = []exchange.Balance{{Currency: "USDT", Equity: s.equity}}
+		var r recorder
+		m.Poll(context.Background(), &r)
+		if got := r.only(EquityDrop); fmt.Sprint(got) != fmt.Sprint(s.want) {
+			t.Fatalf("%s: events %v, want %v", s.name, got, s.want)
+		}
+		for _, ev := range r.events {
+			if ev.AlertKey != "equity:USDT" || ev.Drawdown.Peak != 1200 || ev.Drawdown.Equity != s.equity || ev.Drawdown.Window != DefaultEquityDropWindow {
+				t.Errorf("%s: event %+v, want the drawdown from the 1200 peak", s.name, ev)
+			}
+		}
+	}
+}
+
+func TestEquityDropWindow(t *testing.T) {
+	ex := &fakeExchange{prices: map[string]float64{}}
+	m := NewForExchange(ex, Options{
+		Threshold:         quiet,
+		EquityDropPercent: 10,
+		EquityDropWindow:  time.Hour,
+	})
+	// A peak older than the window no longer counts.
+	m.equity["USDT"] = []equitySample{
+		{at: time.Now().Add(-2 * time.Hour), equity: 2000},
+		{at: time.Now().Add(-30 * time.Minute), equity: 1050},
+	}
+	ex.balances = []exchange.Balance{{Currency: "USDT", Equity: 1000}}
+
+	var r recorder
+	m.Poll(context.Background(), &r)
+	if got := r.only(EquityDrop); len(got) != 0 {
+		t.Errorf("events %v for a drop of under 5%% within the window", got)
+	}
+	if n := len(m.equity["USDT"]); n != 2 {
+		t.Errorf("kept %d samples, want the two within the window", n)
+	}
+
+	// Currencies no longer reported are forgotten.
+	ex.balances = nil
+	m.Poll(context.Background(), &r)
+	if _, ok := m.equity["USDT"]; ok {
+		t.Error("samples kept for a currency without a balance")
+	}
+}
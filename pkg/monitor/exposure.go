@@ -0,0 +1,125 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// Exposure is the combined size of all legs on one underlying, in units of
+// the underlying (e.g. BTC), across every contract quoted on it.
+type Exposure struct {
+	Underlying string
+	Long       float64
+	Short      float64
+	// Legs are the positions that were netted.
+	Legs []mexc.Position
+}
+
+// Net is long minus short exposure.
+func (e Exposure) Net() float64 {
+	return e.Long - e.Short
+}
+
+// Hedged reports whether there are legs on both sides.
+func (e Exposure) Hedged() bool {
+	return e.Long > 0 && e.Short > 0
+}
+
+// Drift is the net exposure as a fraction of the larger leg: zero for a
+// perfect hedge, one when only one side is held.
+func (e Exposure) Drift() float64 {
+	larger := math.Max(e.Long, e.Short)
+	if larger == 0 {
+		return 0
+	}
+	return math.Abs(e.Net()) / larger
+}
+
+// Underlying returns the base asset of a contract symbol, e.g. "BTC" for
+// "BTC_USDT" and for "binance:BTC_USDT", so legs held on different exchanges
+// net against each other.
+func Underlying(symbol string) string {
+	base, _, _ := strings.Cut(exchange.BareSymbol(symbol), "_")
+	return base
+}
+
+// Netting sums positions into one Exposure per underlying, sorted by name.
+// contractSize gives the underlying units per contract of a symbol; positions
+// for which it returns zero are skipped.
+func Netting(positions []mexc.Position, contractSize func(symbol string) float64) []Exposure {
+	byUnderlying := make(map[string]*Exposure)
+	for _, pos := range positions {
+		size := contractSize(pos.Symbol)
+		if size == 0 {
+			continue
+		}
+		underlying := Underlying(pos.Symbol)
+		e, ok := byUnderlying[underlying]
+		if !ok {
+			e = &Exposure{Underlying: underlying}
+			byUnderlying[underlying] = e
+		}
+		e.Legs = append(e.Legs, pos)
+		if pos.PositionType == mexc.PositionTypeShort {
+			e.Short += pos.HoldVol * size
+		} else {
+			e.Long += pos.HoldVol * size
+		}
+	}
+
+	exposures := make([]Exposure, 0, len(byUnderlying))
+	for _, e := range byUnderlying {
+		exposures = append(exposures, *e)
+	}
+	sort.Slice(exposures, func(i, j int) bool { return exposures[i].Underlying < exposures[j].Underlying })
+	return exposures
+}
+
+// refreshContractSizes fetches the contract size of any held symbol not seen
+// before. Sizes don't change, so each symbol is fetched once.
+func (m *Monitor) refreshContractSizes(ctx context.Context, symbols []string, h Handler) {
+	if m.opts.HedgeTolerance <= 0 {
+		return
+	}
+	var missing []string
+	for _, symbol := range symbols {
+		if _, ok := m.contractSizes[symbol]; !ok {
+			missing = append(missing, symbol)
+		}
+	}
+	fetch := func(ctx context.Context, symbol string) (float64, error) {
+		return exchange.ContractSize(ctx, m.api, symbol)
+	}
+	for symbol, result := range fetchAll(ctx, missing, m.opts.Concurrency, fetch) {
+		if result.err != nil {
+			h.HandleError(symbol, fmt.Errorf("fetching contract size: %w", result.err))
+			continue
+		}
+		m.contractSizes[symbol] = result.value
+	}
+}
+
+// checkHedges emits a HedgeDrift event for each underlying held on both sides
+// whose drift reaches HedgeTolerance, as far as the alert policy allows.
+func (m *Monitor) checkHedges(tracking []mexc.Position, h Handler) {
+	if m.opts.HedgeTolerance <= 0 {
+		return
+	}
+	contractSize := func(symbol string) float64 { return m.contractSizes[symbol] }
+	for _, e := range Netting(tracking, contractSize) {
+		drift := e.Drift()
+		active := e.Hedged() && drift >= m.opts.HedgeTolerance
+		cleared := !e.Hedged() || drift < m.opts.Alerts.Policy().Rearm(m.opts.HedgeTolerance)
+		m.alert(Event{Kind: HedgeDrift, Exposure: e}, hedgeAlertKey(e.Underlying), active, cleared, h)
+	}
+}
+
+func hedgeAlertKey(underlying string) string {
+	return "hedge:" + underlying
+}
@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/alert"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// sizedExchange is a fakeExchange with contract sizes per symbol.
+type sizedExchange struct {
+	*fakeExchange
+	sizes map[string]float64
+}
+
+func (s sizedExchange) ContractSize(_ context.Context, symbol string) (float64, error) {
+	return s.sizes[symbol], nil
+}
+
+// only lists the recorded events of kind, and resolutions of it, as kinds
+// does.
+func (r *recorder) only(kind EventKind) []string {
+	var matching recorder
+	for _, ev := range r.events {
+		if ev.Kind == kind || ev.Kind == Resolved && ev.Resolves == kind {
+			matching.events = append(matching.events, ev)
+		}
+	}
+	return matching.kinds()
+}
+
+// quiet keeps divergence events out of the tests of other alerts.
+func quiet(string) Threshold { return Threshold{Percent: 100} }
+
+func TestNetting(t *testing.T) {
+	positions := []mexc.Position{
+		{Symbol: "BTC_USDT", PositionType: mexc.PositionTypeLong, HoldVol: 150}, // 0.15 BTC
+		{Symbol: "binance:BTC_USDT", PositionType: mexc.PositionTypeShort, HoldVol: 0.1},
+		{Symbol: "BTC_USDC", PositionType: mexc.PositionTypeShort, HoldVol: 50}, // 0.05 BTC
+		{Symbol: "ETH_USDT", PositionType: mexc.PositionTypeLong, HoldVol: 20},  // 0.2 ETH
+		{Symbol: "SOL_USDT", PositionType: mexc.PositionTypeShort, HoldVol: 5},  // no contract size
+	}
+	sizes := map[string]float64{"BTC_USDT": 0.001, "binance:BTC_USDT": 1, "BTC_USDC": 0.001, "ETH_USDT": 0.01}
+
+	exposures := Netting(positions, func(symbol string) float64 { return sizes[symbol] })
+	if len(exposures) != 2 {
+		t.Fatalf("exposures = %+v, want BTC and ETH", exposures)
+	}
+	btc, eth := exposures[0], exposures[1]
+	if btc.Underlying != "BTC" || len(btc.Legs) != 3 || math.Abs(btc.Long-0.15) > 1e-9 || math.Abs(btc.Short-0.15) > 1e-9 {
+		t.Errorf("BTC = %+v, want 0.15 on each side from three legs", btc)
+	}
+	if !btc.Hedged() || math.Abs(btc.Net()) > 1e-9 || btc.Drift() > 1e-9 {
+		t.Errorf("BTC hedged %v, net %v, drift %v, want a perfect hedge", btc.Hedged(), btc.Net(), btc.Drift())
+	}
+	if eth.Underlying != "ETH" || eth.Hedged() || math.Abs(eth.Net()-0.2) > 1e-9 || eth.Drift() != 1 {
+		t.Errorf("ETH = %+v with drift %v, want an unhedged 0.2 long", eth, eth.Drift())
+	}
+
+	if d := (Exposure{Long: 4, Short: 3}).Drift(); d != 0.25 {
+		t.Errorf("drift of 4 long against 3 short = %v, want 0.25", d)
+	}
+	if d := (Exposure{}).Drift(); d != 0 {
+		t.Errorf("drift without legs = %v, want 0", d)
+	}
+}
+
+func TestHedgeDrift(t *testing.T) {
+	leg := func(id int64, side int, vol float64) mexc.Position {
+		return mexc.Position{PositionID: id, Symbol: "BTC_USDT", PositionType: side, HoldVol: vol, HoldAvgPrice: 60000}
+	}
+	long := leg(1, mexc.PositionTypeLong, 100)
+	short := func(vol float64) mexc.Position { return leg(2, mexc.PositionTypeShort, vol) }
+
+	ex := sizedExchange{
+		fakeExchange: &fakeExchange{prices: map[string]float64{"BTC_USDT": 60000}},
+		sizes:        map[string]float64{"BTC_USDT": 0.001},
+	}
+	m := NewForExchange(ex, Options{
+		Threshold:      quiet,
+		Alerts:         alert.NewManager(alert.Policy{}),
+		HedgeTolerance: 0.2,
+	})
+
+	steps := []struct {
+		name      string
+		positions []mexc.Position
+		want      []string
+	}{
+		{"balanced", []mexc.Position{long, short(100)}, nil},
+		{"within tolerance", []mexc.Position{long, short(85)}, nil},
+		{"drifted", []mexc.Position{long, short(75)}, []string{"hedge_drift"}},
+		{"still drifted", []mexc.Position{long, short(50)}, nil},
+		{"rebalanced", []mexc.Position{long, short(90)}, []string{"resolved(hedge_drift)"}},
+		{"drifted again", []mexc.Position{long, short(60)}, []string{"hedge_drift"}},
+		{"unhedged", []mexc.Position{long}, []string{"resolved(hedge_drift)"}},
+		{"unhedged is not drift", []mexc.Position{long}, nil},
+	}
+	for _, s := range steps {
+		ex.positions = s.positions
+		var r recorder
+		m.Poll(context.Background(), &r)
+		if got := r.only(HedgeDrift); fmt.Sprint(got) != fmt.Sprint(s.want) {
+			t.Fatalf("%s: events %v, want %v", s.name, got, s.want)
+		}
+		for _, ev := range r.events {
+			if ev.Kind == HedgeDrift && (ev.AlertKey != "hedge:BTC" || ev.Exposure.Underlying != "BTC" || ev.Exposure.Long != 0.1 || len(ev.Exposure.Legs) != 2) {
+				t.Errorf("%s: event %+v, want the netted BTC exposure", s.name, ev)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultConcurrency bounds parallel per-symbol requests when not configured.
+const DefaultConcurrency = 8
+
+type result[T any] struct {
+	value T
+	err   error
+}
+
+// fetchAll calls fetch for every symbol with at most limit calls in flight.
+func fetchAll[T any](ctx context.Context, symbols []string, limit int, fetch func(context.Context, string) (T, error)) map[string]result[T] {
+	if limit <= 0 {
+		limit = DefaultConcurrency
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]result[T], len(symbols))
+		slots   = make(chan struct{}, limit)
+	)
+	for _, symbol := range symbols {
+		wg.Add(1)
+		go func(symbol string) {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				mu.Lock()
+				results[symbol] = result[T]{err: ctx.Err()}
+				mu.Unlock()
+				return
+			}
+			defer func() { <-slots }()
+
+			value, err := fetch(ctx, symbol)
+			mu.Lock()
+			results[symbol] = result[T]{value: value, err: err}
+			mu.Unlock()
+		}(symbol)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// Funding is the funding of one symbol: the rate the next settlement will
+// charge and, where the exchange keeps a history, the last settled rate.
+type Funding struct {
+	exchange.FundingRate
+	// Last is the rate of the latest settlement, set when HasLast is true.
+	Last    float64
+	HasLast bool
+}
+
+// Paid is the rate pos pays at the next settlement: positive when it pays
+// funding and negative when it receives it. Longs pay a positive rate and
+// shorts a negative one.
+func (f Funding) Paid(pos mexc.Position) float64 {
+	if pos.PositionType == mexc.PositionTypeShort {
+		return -f.Rate
+	}
+	return f.Rate
+}
+
+// refreshFunding updates the cached funding per symbol. It does nothing when
+// funding is neither reported nor alerted on, or the exchange has no funding
+// rates. The last settlement is best effort: exchanges without a history
+// still report the next rate.
+func (m *Monitor) refreshFunding(ctx context.Context, symbols []string, h Handler) {
+	if !m.opts.FundingInReports && m.opts.FundingThreshold <= 0 {
+		return
+	}
+
+	rates, ok := m.api.(exchange.FundingSource)
+	if !ok {
+		return
+	}
+	history, _ := m.api.(exchange.FundingHistorySource)
+	fetch := func(ctx context.Context, symbol string) (Funding, error) {
+		rate, err := rates.FundingRate(ctx, symbol)
+		if err != nil {
+			return Funding{}, err
+		}
+		f := Funding{FundingRate: rate}
+		if history == nil {
+			return f, nil
+		}
+		last, err := history.LastFunding(ctx, symbol)
+		if err != nil && !errors.Is(err, errors.ErrUnsupported) {
+			return f, fmt.Errorf("fetching funding history: %w", err)
+		}
+		f.Last, f.HasLast = last.Rate, err == nil
+		return f, nil
+	}
+	for symbol, result := range fetchAll(ctx, symbols, m.opts.Concurrency, fetch) {
+		if result.err != nil {
+			delete(m.funding, symbol)
+			// Some exchanges of a combined account may not report funding.
+			if !errors.Is(result.err, errors.ErrUnsupported) {
+				h.HandleError(symbol, fmt.Errorf("fetching funding rate: %w", result.err))
+			}
+			continue
+		}
+		m.funding[symbol] = result.value
+	}
+}
+
+// checkFunding emits a FundingAlert per position that pays at least
+// FundingThreshold at the next settlement, as far as the alert policy
+// allows.
+func (m *Monitor) checkFunding(tracking []mexc.Position, h Handler) {
+	if m.opts.FundingThreshold <= 0 {
+		return
+	}
+
+	for _, pos := range tracking {
+		f, ok := m.funding[pos.Symbol]
+		if !ok {
+			continue
+		}
+		paid := f.Paid(pos)
+		active := paid >= m.opts.FundingThreshold
+		cleared := paid < m.opts.Alerts.Policy().Rearm(m.opts.FundingThreshold)
+		m.alert(Event{
+			Kind:       FundingAlert,
+			Position:   pos,
+			FairPrice:  m.prices[pos.Symbol],
+			Funding:    f,
+			HasFunding: true,
+		}, fundingAlertKey(positionKey(pos)), active, cleared, h)
+	}
+}
+
+func fundingAlertKey(positionKey string) string {
+	return "funding:" + positionKey
+}
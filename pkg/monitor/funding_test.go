@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/alert"
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// fundingExchange is a fakeExchange with funding rates and, when last is
+// set, settled rates per symbol.
+type fundingExchange struct {
+	*fakeExchange
+	rates map[string]float64
+	last  map[string]float64
+}
+
+func (f fundingExchange) FundingRate(_ context.Context, symbol string) (exchange.FundingRate, error) {
+	rate, ok := f.rates[symbol]
+	if !ok {
+		return exchange.FundingRate{}, errors.ErrUnsupported
+	}
+	return exchange.FundingRate{Symbol: symbol, Rate: rate}, nil
+}
+
+func (f fundingExchange) LastFunding(_ context.Context, symbol string) (exchange.FundingSettlement, error) {
+	rate, ok := f.last[symbol]
+	if !ok {
+		return exchange.FundingSettlement{}, errors.ErrUnsupported
+	}
+	return exchange.FundingSettlement{Symbol: symbol, Rate: rate}, nil
+}
+
+func TestFundingPaid(t *testing.T) {
+	long := mexc.Position{PositionType: mexc.PositionTypeLong}
+	short := mexc.Position{PositionType: mexc.PositionTypeShort}
+	f := Funding{FundingRate: exchange.FundingRate{Rate: 0.001}}
+	if f.Paid(long) != 0.001 || f.Paid(short) != -0.001 {
+		t.Errorf("paid at 0.1%%: long %v, short %v, want longs to pay", f.Paid(long), f.Paid(short))
+	}
+}
+
+func TestFundingAlert(t *testing.T) {
+	long := mexc.Position{PositionID: 1, Symbol: "BTC_USDT", PositionType: mexc.PositionTypeLong, HoldVol: 10, HoldAvgPrice: 60000}
+	short := mexc.Position{PositionID: 2, Symbol: "ETH_USDT", PositionType: mexc.PositionTypeShort, HoldVol: 10, HoldAvgPrice: 3000}
+	ex := fundingExchange{
+		fakeExchange: &fakeExchange{
+			positions: []mexc.Position{long, short},
+			prices:    map[string]float64{"BTC_USDT": 60000, "ETH_USDT": 3000},
+		},
+		rates: map[string]float64{},
+		last:  map[string]float64{"BTC_USDT": 0.0008},
+	}
+	m := NewForExchange(ex, Options{
+		Threshold:        quiet,
+		Alerts:           alert.NewManager(alert.Policy{}),
+		FundingThreshold: 0.001,
+	})
+
+	steps := []struct {
+		name     string
+		btc, eth float64
+		want     []string // kind(alert key) of each funding event
+	}{
+		{"below", 0.0005, 0.0005, nil},
+		{"long pays the threshold", 0.001, 0.0005, []string{"funding(funding:BTC_USDT/1)"}},
+		{"still paying", 0.002, 0.0005, nil},
+		{"short pays a negative rate", 0.002, -0.0015, []string{"funding(funding:ETH_USDT/2)"}},
+		{"long re-armed", 0.0009, -0.0015, []string{"resolved(funding:BTC_USDT/1)"}},
+		{"long crossed again", 0.001, -0.002, []string{"funding(funding:BTC_USDT/1)"}},
+	}
+	for _, s := range steps {
+		ex.rates["BTC_USDT"], ex.rates["ETH_USDT"] = s.btc, s.eth
+		var r recorder
+		m.Poll(context.Background(), &r)
+
+		var got []string
+		for _, ev := range r.events {
+			if ev.Kind == FundingAlert || ev.Kind == Resolved && ev.Resolves == FundingAlert {
+				got = append(got, fmt.Sprintf("%s(%s)", ev.Kind, ev.AlertKey))
+			}
+			if ev.Kind == FundingAlert && (!ev.HasFunding || ev.FairPrice == 0) {
+				t.Errorf("%s: event %+v without its funding and fair price", s.name, ev)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(s.want) {
+			t.Fatalf("%s: events %v, want %v", s.name, got, s.want)
+		}
+	}
+
+	// The last settlement is attached where the exchange keeps one.
+	if f := m.funding["BTC_USDT"]; !f.HasLast || f.Last != 0.0008 || f.Rate != 0.001 {
+		t.Errorf("BTC funding = %+v, want the next and last rates", f)
+	}
+	if f := m.funding["ETH_USDT"]; f.HasLast {
+		t.Errorf("ETH funding = %+v, want no last rate", f)
+	}
+}
+
+func TestFundingDisabled(t *testing.T) {
+	ex := fundingExchange{
+		fakeExchange: &fakeExchange{
+			positions: []mexc.Position{{PositionID: 1, Symbol: "BTC_USDT", PositionType: mexc.PositionTypeLong, HoldVol: 10, HoldAvgPrice: 60000}},
+			prices:    map[string]float64{"BTC_USDT": 60000},
+		},
+		rates: map[string]float64{"BTC_USDT": 0.01},
+	}
+	m := NewForExchange(ex, Options{Threshold: quiet})
+
+	var r recorder
+	m.Poll(context.Background(), &r)
+	if got := r.only(FundingAlert); len(got) != 0 || len(m.funding) != 0 {
+		t.Errorf("events %v and funding %v without FundingThreshold or FundingInReports", got, m.funding)
+	}
+}
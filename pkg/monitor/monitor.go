@@ -0,0 +1,829 @@
+// Package monitor tracks open positions and their fair prices, either by
+// polling or from a price stream, and reports only what changed.
+//
+// A Monitor reads from a mexc.Client, or any other exchange.Exchange with
+// NewForExchange, and hands events to a Handler as they happen; Poll also
+// returns a CycleResult covering the whole cycle:
+//
+//	api := mexc.NewClient(accessKey, secretKey, mexc.DefaultBaseURL)
+//	mon := monitor.New(api, monitor.Options{Interval: time.Minute})
+//	mon.Run(ctx, handler)
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/alert"
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// EventKind says what happened to a position since it was last reported.
+type EventKind int
+
+const (
+	// Updated means the position's divergence alert fired: it is new, its
+	// entry or size changed, or the alert policy allowed a repeat.
+	Updated EventKind = iota
+	// Closed means the position was open on the previous refresh but not this one.
+	Closed
+	// ImbalanceAlert means the order book imbalance for the position's symbol
+	// crossed the configured threshold.
+	ImbalanceAlert
+	// Stale means the position has been open longer than the configured limit.
+	Stale
+	// OrderFilled means an order on the position's symbol filled, fully or
+	// partially. Only sent when a private stream is in use.
+	OrderFilled
+	// ADL means the position's auto-deleveraging rank changed. Only sent when
+	// a private stream is in use.
+	ADL
+	// HedgeDrift means long and short legs on one underlying no longer offset
+	// each other within the configured tolerance. Position is unset; see
+	// Event.Exposure.
+	HedgeDrift
+	// DataMissing means the fair price of the position's symbol could not be
+	// fetched, even after a retry at the end of the poll, for
+	// Options.MissingDataAfter polls in a row. Its positions are not checked
+	// until a price comes through; see Event.Failures and Event.Err.
+	DataMissing
+	// Resolved means the condition behind an earlier Updated,
+	// ImbalanceAlert, HedgeDrift, DataMissing, EquityDrop or FundingAlert
+	// event cleared, or that a divergence alert ended because its position
+	// changed. It carries the details of the current state; see
+	// Event.Resolves and Event.Changed.
+	Resolved
+	// EquityDrop means a currency's account equity fell
+	// Options.EquityDropPercent below its peak within the window. Position
+	// is unset; see Event.Drawdown.
+	EquityDrop
+	// FundingAlert means the position pays at least
+	// Options.FundingThreshold at the next funding settlement; see
+	// Event.Funding.
+	FundingAlert
+)
+
+var eventKindNames = [...]string{
+	Updated:        "updated",
+	Closed:         "closed",
+	ImbalanceAlert: "imbalance",
+	Stale:          "stale",
+	OrderFilled:    "order_filled",
+	ADL:            "adl",
+	HedgeDrift:     "hedge_drift",
+	DataMissing:    "data_missing",
+	Resolved:       "resolved",
+	EquityDrop:     "equity_drop",
+	FundingAlert:   "funding",
+}
+
+func (k EventKind) String() string {
+	if k >= 0 && int(k) < len(eventKindNames) {
+		return eventKindNames[k]
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Event describes something worth reporting about a position.
+type Event struct {
+	Kind      EventKind
+	Position  mexc.Position
+	FairPrice float64
+
+	// Imbalance is the order book imbalance in [-1, 1], set when HasImbalance is true.
+	Imbalance    float64
+	HasImbalance bool
+
+	// Funding is the symbol's funding, set when HasFunding is true.
+	Funding    Funding
+	HasFunding bool
+
+	// HeldFor is how long the position has been open, set for Stale events.
+	HeldFor time.Duration
+
+	// Order and FilledVol describe the fill for OrderFilled events; FilledVol
+	// is the volume filled since the previous update of the order.
+	Order     mexc.Order
+	FilledVol float64
+
+	// ADLLevel is the new auto-deleveraging rank (1-5) for ADL events.
+	ADLLevel int
+
+	// Exposure is the netted underlying for HedgeDrift events.
+	Exposure Exposure
+
+	// Drawdown is the fall in equity for EquityDrop events.
+	Drawdown Drawdown
+
+	// Failures is the number of polls in a row without data, and Err the
+	// latest error, for DataMissing events.
+	Failures int
+	Err      error
+
+	// AlertKey names the alert policy key behind events gated by the alert
+	// policy, and for Resolved events the key that re-armed. Closed events
+	// carry the key of the position's divergence alert, which closing ends.
+	AlertKey string
+	// Resolves is the kind of event whose condition cleared, and FiredAt
+	// when its alert was last sent, for Resolved events.
+	Resolves EventKind
+	FiredAt  time.Time
+	// Changed is set on Resolved events that end a divergence alert because
+	// the position's entry price or size changed, rather than because the
+	// divergence cleared. The changed position is judged afresh.
+	Changed bool
+}
+
+// Recorder keeps a history of what the monitor observed.
+type Recorder interface {
+	RecordPrice(symbol string, price float64, at time.Time) error
+	RecordPositions(positions []mexc.Position, at time.Time) error
+}
+
+// Handler receives events and errors from a Monitor.
+type Handler interface {
+	HandleEvent(Event)
+	HandleError(symbol string, err error)
+}
+
+// state is what a position looked like when it was last evaluated. A change
+// resets its divergence alert, so the new entry or size is reported right away.
+type state struct {
+	holdAvgPrice float64
+	holdVol      float64
+}
+
+// Threshold sets how far the fair price must move from the entry before a
+// divergence is reported. A move is reported when it meets any non-zero
+// limit; with both limits at zero every difference is reported.
+type Threshold struct {
+	// Percent is the minimum |divergence| in percent of the entry price.
+	Percent float64
+	// Absolute is the minimum |fair price - entry price|.
+	Absolute float64
+}
+
+// Breached reports whether the move from holdAvgPrice to fairPrice is large enough to report.
+func (t Threshold) Breached(fairPrice, holdAvgPrice float64) bool {
+	difference := math.Abs(fairPrice - holdAvgPrice)
+	if t.Percent <= 0 && t.Absolute <= 0 {
+		return difference > 0
+	}
+	if t.Absolute > 0 && difference >= t.Absolute {
+		return true
+	}
+	return t.Percent > 0 && holdAvgPrice != 0 && difference/holdAvgPrice*100 >= t.Percent
+}
+
+// scaled returns t with both limits multiplied by f.
+func (t Threshold) scaled(f float64) Threshold {
+	return Threshold{Percent: t.Percent * f, Absolute: t.Absolute * f}
+}
+
+// Options configures a Monitor.
+type Options struct {
+	// Interval is the time between position refreshes in Run and RunStream.
+	Interval time.Duration
+	// Include filters which symbols are tracked; nil tracks everything.
+	Include func(symbol string) bool
+	// Concurrency caps parallel per-symbol requests; zero means DefaultConcurrency.
+	Concurrency int
+	// Threshold returns the reporting threshold for a symbol; nil reports
+	// every difference.
+	Threshold func(symbol string) Threshold
+	// Alerts decides when divergence and imbalance alerts may repeat. Nil
+	// alerts once per breach, re-arming only after the condition clears.
+	Alerts *alert.Manager
+	// Recorder, if set, is given every fair price and position set seen.
+	Recorder Recorder
+
+	// ImbalanceLevels is how many order book levels per side are compared.
+	// Zero disables order book fetching, as does an exchange without an
+	// exchange.DepthSource or one whose Depth returns errors.ErrUnsupported.
+	ImbalanceLevels int
+	// ImbalanceInReports attaches the imbalance to Updated events.
+	ImbalanceInReports bool
+	// ImbalanceThreshold emits an ImbalanceAlert when |imbalance| reaches it.
+	// Zero disables the alert.
+	ImbalanceThreshold float64
+
+	// FundingInReports attaches the funding rates to Updated events.
+	FundingInReports bool
+	// FundingThreshold emits a FundingAlert when a position pays at least
+	// this rate, a fraction per settlement, at the next settlement. Zero
+	// disables the alert. Funding is fetched only when either is set and
+	// the exchange is an exchange.FundingSource.
+	FundingThreshold float64
+
+	// StaleAfter emits a Stale event for positions open longer than this.
+	// Zero disables nudges.
+	StaleAfter time.Duration
+	// StaleRepeat repeats the nudge at this interval while the position stays
+	// open. Zero nudges only once.
+	StaleRepeat time.Duration
+
+	// HedgeTolerance emits HedgeDrift when an underlying held on both sides
+	// has a net exposure of at least this fraction of its larger leg. Zero
+	// disables the check.
+	HedgeTolerance float64
+
+	// MissingDataAfter emits DataMissing when a symbol's fair price has
+	// failed this many polls in a row. Zero disables the event; failures are
+	// still passed to Handler.HandleError.
+	MissingDataAfter int
+
+	// EquityDropPercent emits EquityDrop when a currency's account equity
+	// falls this many percent below its highest value within
+	// EquityDropWindow, which defaults to DefaultEquityDropWindow. Zero
+	// disables the check and the balance requests it makes every refresh.
+	EquityDropPercent float64
+	EquityDropWindow  time.Duration
+
+	// OnCycle, if set, is called by Run with the result of every poll.
+	OnCycle func(CycleResult)
+}
+
+// DefaultInterval is the refresh interval used when Options.Interval is unset.
+const DefaultInterval = 30 * time.Second
+
+// Monitor tracks positions across refreshes. It is not safe for concurrent use.
+type Monitor struct {
+	api  exchange.Exchange
+	opts Options
+
+	positions  map[string]mexc.Position // by positionKey
+	reported   map[string]state         // by positionKey
+	prices     map[string]float64       // latest fair price by symbol
+	imbalances map[string]float64       // latest order book imbalance by symbol
+	funding    map[string]Funding       // latest funding by symbol
+	nudged     map[string]time.Time
+	dealt      map[string]float64 // filled volume by order ID
+	adl        map[int64]int      // ADL rank by position ID
+
+	contractSizes map[string]float64        // underlying units per contract by symbol
+	failures      map[string]int            // polls in a row without a fair price, by symbol
+	equity        map[string][]equitySample // equity within the drop window by currency, oldest first
+}
+
+// New returns a Monitor that reads positions from api.
+func New(api *mexc.Client, opts Options) *Monitor {
+	return NewForExchange(exchange.NewMEXC(api, ""), opts)
+}
+
+// NewForExchange returns a Monitor that reads positions from ex.
+func NewForExchange(ex exchange.Exchange, opts Options) *Monitor {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Include == nil {
+		opts.Include = func(string) bool { return true }
+	}
+	if opts.Threshold == nil {
+		opts.Threshold = func(string) Threshold { return Threshold{} }
+	}
+	if opts.Alerts == nil {
+		opts.Alerts = alert.NewManager(alert.Policy{})
+	}
+	if opts.EquityDropWindow <= 0 {
+		opts.EquityDropWindow = DefaultEquityDropWindow
+	}
+	return &Monitor{
+		api:        ex,
+		opts:       opts,
+		positions:  make(map[string]mexc.Position),
+		reported:   make(map[string]state),
+		prices:     make(map[string]float64),
+		imbalances: make(map[string]float64),
+		funding:    make(map[string]Funding),
+		nudged:     make(map[string]time.Time),
+		dealt:      make(map[string]float64),
+		adl:        make(map[int64]int),
+
+		contractSizes: make(map[string]float64),
+		failures:      make(map[string]int),
+		equity:        make(map[string][]equitySample),
+	}
+}
+
+// Run polls immediately and then on every tick until ctx is canceled.
+func (m *Monitor) Run(ctx context.Context, h Handler) error {
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		res := m.Poll(ctx, h)
+		if m.opts.OnCycle != nil {
+			m.opts.OnCycle(res)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll runs a single cycle and reports changes since the previous one to h,
+// which may be nil when the caller only needs the result.
+func (m *Monitor) Poll(ctx context.Context, h Handler) CycleResult {
+	res := CycleResult{Started: time.Now()}
+	m.poll(ctx, &collector{h: h, res: &res}, &res)
+	res.Duration = time.Since(res.Started)
+	return res
+}
+
+func (m *Monitor) poll(ctx context.Context, h Handler, res *CycleResult) {
+	tracking, symbols, err := m.refreshPositions(ctx, h)
+	if err != nil {
+		res.Err = err
+		return
+	}
+	res.Positions = tracking
+
+	fetched := make(map[string]SymbolResult, len(symbols))
+	for symbol, r := range fetchAll(ctx, symbols, m.opts.Concurrency, m.fetchFairPrice) {
+		sr := r.value
+		sr.Symbol, sr.Err = symbol, r.err
+		fetched[symbol] = sr
+	}
+	// Give symbols that failed a second chance once the rest are done, so a
+	// blip on one request doesn't cost a whole poll.
+	for _, symbol := range symbols {
+		first := fetched[symbol]
+		if first.Err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		retry, _ := m.fetchFairPrice(ctx, symbol)
+		retry.Attempts += first.Attempts
+		retry.Duration += first.Duration
+		fetched[symbol] = retry
+	}
+	for _, symbol := range symbols {
+		sr := fetched[symbol]
+		if sr.Err == nil {
+			m.setPrice(symbol, sr.FairPrice, h)
+		} else {
+			// Don't judge the position on a price from an earlier poll.
+			delete(m.prices, symbol)
+			m.failures[symbol]++
+			sr.Failures = m.failures[symbol]
+		}
+		fetched[symbol] = sr
+		res.Symbols = append(res.Symbols, sr)
+	}
+
+	m.afterRefresh(ctx, tracking, symbols, h)
+	m.reportMissing(tracking, fetched, h)
+}
+
+// setPrice stores a fetched fair price and clears the symbol's failures,
+// resolving its DataMissing alert.
+func (m *Monitor) setPrice(symbol string, price float64, h Handler) {
+	m.prices[symbol] = price
+	delete(m.failures, symbol)
+	m.alert(Event{Kind: DataMissing, Position: mexc.Position{Symbol: symbol}}, missingAlertKey(symbol), false, true, h)
+	m.recordPrice(symbol, price, h)
+}
+
+// reportMissing passes on the fair prices that couldn't be fetched this poll
+// and emits DataMissing for symbols that have failed MissingDataAfter polls
+// in a row.
+func (m *Monitor) reportMissing(tracking []mexc.Position, fetched map[string]SymbolResult, h Handler) {
+	reported := make(map[string]bool)
+	for _, pos := range tracking {
+		sr := fetched[pos.Symbol]
+		// Hedge-mode symbols have two positions; handle the symbol once.
+		if sr.Err == nil || reported[pos.Symbol] {
+			continue
+		}
+		reported[pos.Symbol] = true
+		h.HandleError(pos.Symbol, fmt.Errorf("fetching fair price: %w", sr.Err))
+
+		if m.opts.MissingDataAfter <= 0 || sr.Failures < m.opts.MissingDataAfter {
+			continue
+		}
+		m.alert(Event{Kind: DataMissing, Position: pos, Failures: sr.Failures, Err: sr.Err}, missingAlertKey(pos.Symbol), true, false, h)
+	}
+}
+
+// RunStream is like Run, but fair prices come from stream as they are pushed
+// instead of being polled. Positions are still refreshed every Interval, and
+// the stream's subscriptions follow the set of held symbols.
+//
+// If private is non-nil, position, order and ADL pushes from it are applied
+// as they arrive, so changes don't wait for the next refresh.
+func (m *Monitor) RunStream(ctx context.Context, h Handler, stream exchange.PriceStream, private *mexc.PrivateStream) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go stream.Run(ctx)
+
+	// A nil channel never delivers, which leaves the private case idle.
+	var privateEvents <-chan mexc.PrivateEvent
+	if private != nil {
+		go private.Run(ctx)
+		privateEvents = private.Events()
+	}
+
+	refresh := func() {
+		tracking, symbols, err := m.refreshPositions(ctx, h)
+		if err != nil {
+			return
+		}
+		stream.SetSymbols(symbols)
+		m.afterRefresh(ctx, tracking, symbols, h)
+	}
+
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+
+	refresh()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			refresh()
+		case update, ok := <-stream.Updates():
+			if !ok {
+				return ctx.Err()
+			}
+			if !m.holds(update.Symbol) {
+				continue
+			}
+			m.prices[update.Symbol] = update.Price
+			m.recordPrice(update.Symbol, update.Price, h)
+			for _, pos := range m.positions {
+				if pos.Symbol == update.Symbol {
+					m.evaluate(pos, h)
+				}
+			}
+		case ev, ok := <-privateEvents:
+			if !ok {
+				return ctx.Err()
+			}
+			if m.applyPrivate(ev, h) {
+				stream.SetSymbols(m.symbols())
+			}
+		}
+	}
+}
+
+// applyPrivate handles a private stream push. It reports whether the set of
+// held symbols may have changed.
+func (m *Monitor) applyPrivate(ev mexc.PrivateEvent, h Handler) bool {
+	switch ev.Kind {
+	case mexc.PositionChanged:
+		pos := ev.Position
+		if !m.opts.Include(pos.Symbol) {
+			return false
+		}
+		key := positionKey(pos)
+		if pos.State == mexc.PositionStateClosed || pos.HoldVol == 0 {
+			if previous, ok := m.positions[key]; ok {
+				delete(m.positions, key)
+				delete(m.reported, key)
+				delete(m.nudged, key)
+				m.opts.Alerts.Reset(divergenceAlertKey(key))
+				delete(m.adl, previous.PositionID)
+				h.HandleEvent(Event{Kind: Closed, Position: previous, AlertKey: divergenceAlertKey(key)})
+			}
+			return true
+		}
+		m.positions[key] = pos
+		m.evaluate(pos, h)
+		return true
+
+	case mexc.OrderChanged:
+		order := ev.Order
+		if !m.opts.Include(order.Symbol) {
+			return false
+		}
+		filled := order.DealVol - m.dealt[order.OrderID]
+		if order.Done() {
+			delete(m.dealt, order.OrderID)
+		} else {
+			m.dealt[order.OrderID] = order.DealVol
+		}
+		if filled > 0 {
+			h.HandleEvent(Event{
+				Kind:      OrderFilled,
+				Position:  m.positionByID(order.PositionID, order.Symbol),
+				FairPrice: m.prices[order.Symbol],
+				Order:     order,
+				FilledVol: filled,
+			})
+		}
+
+	case mexc.ADLChanged:
+		pos, ok := m.positionWithID(ev.ADL.PositionID)
+		if !ok || m.adl[ev.ADL.PositionID] == ev.ADL.Level {
+			return false
+		}
+		m.adl[ev.ADL.PositionID] = ev.ADL.Level
+		h.HandleEvent(Event{Kind: ADL, Position: pos, FairPrice: m.prices[pos.Symbol], ADLLevel: ev.ADL.Level})
+	}
+	return false
+}
+
+// positionWithID finds a tracked position by its exchange ID.
+func (m *Monitor) positionWithID(id int64) (mexc.Position, bool) {
+	for _, pos := range m.positions {
+		if pos.PositionID == id {
+			return pos, true
+		}
+	}
+	return mexc.Position{}, false
+}
+
+// positionByID is positionWithID with a bare position for symbol as fallback,
+// for orders that opened a position we haven't seen yet.
+func (m *Monitor) positionByID(id int64, symbol string) mexc.Position {
+	if pos, ok := m.positionWithID(id); ok {
+		return pos
+	}
+	return mexc.Position{PositionID: id, Symbol: symbol}
+}
+
+// symbols lists the distinct symbols of tracked positions.
+func (m *Monitor) symbols() []string {
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, pos := range m.positions {
+		if !seen[pos.Symbol] {
+			seen[pos.Symbol] = true
+			symbols = append(symbols, pos.Symbol)
+		}
+	}
+	return symbols
+}
+
+// refreshPositions fetches open positions, reports the ones that closed, and
+// returns the tracked positions along with their distinct symbols. A fetch
+// error has already been passed to h.
+func (m *Monitor) refreshPositions(ctx context.Context, h Handler) ([]mexc.Position, []string, error) {
+	positions, err := m.api.OpenPositions(ctx)
+	if err != nil {
+		err = fmt.Errorf("fetching open positions: %w", err)
+		h.HandleError("", err)
+		return nil, nil, err
+	}
+
+	var tracking []mexc.Position
+	var symbols []string
+	current := make(map[string]mexc.Position)
+	held := make(map[string]bool)
+	for _, pos := range positions {
+		if !m.opts.Include(pos.Symbol) {
+			continue
+		}
+		tracking = append(tracking, pos)
+		current[positionKey(pos)] = pos
+		// Hedge-mode accounts can hold both sides of a symbol; list it once.
+		if !held[pos.Symbol] {
+			held[pos.Symbol] = true
+			symbols = append(symbols, pos.Symbol)
+		}
+	}
+
+	if m.opts.Recorder != nil {
+		if err := m.opts.Recorder.RecordPositions(tracking, time.Now()); err != nil {
+			h.HandleError("", fmt.Errorf("recording positions: %w", err))
+		}
+	}
+
+	for key, pos := range m.positions {
+		if _, open := current[key]; open {
+			continue
+		}
+		delete(m.reported, key)
+		delete(m.nudged, key)
+		delete(m.adl, pos.PositionID)
+		h.HandleEvent(Event{Kind: Closed, Position: pos, AlertKey: divergenceAlertKey(key)})
+	}
+	for symbol := range m.prices {
+		if !held[symbol] {
+			delete(m.prices, symbol)
+			delete(m.imbalances, symbol)
+			delete(m.funding, symbol)
+		}
+	}
+	for symbol := range m.failures {
+		if !held[symbol] {
+			delete(m.failures, symbol)
+		}
+	}
+	// This also drops alert state restored for positions that closed while
+	// the bot was stopped.
+	live := make(map[string]bool, len(current)+len(held))
+	for key := range current {
+		live[divergenceAlertKey(key)] = true
+		live[fundingAlertKey(key)] = true
+	}
+	for symbol := range held {
+		live[imbalanceAlertKey(symbol)] = true
+		live[hedgeAlertKey(Underlying(symbol))] = true
+		live[missingAlertKey(symbol)] = true
+	}
+	for currency := range m.equity {
+		live[equityAlertKey(currency)] = true
+	}
+	m.opts.Alerts.Retain(func(key string) bool { return live[key] })
+
+	m.positions = current
+	return tracking, symbols, nil
+}
+
+func (m *Monitor) recordPrice(symbol string, price float64, h Handler) {
+	if m.opts.Recorder == nil {
+		return
+	}
+	if err := m.opts.Recorder.RecordPrice(symbol, price, time.Now()); err != nil {
+		h.HandleError(symbol, fmt.Errorf("recording fair price: %w", err))
+	}
+}
+
+// afterRefresh evaluates every tracked position against the cached prices
+// and runs the per-refresh checks.
+func (m *Monitor) afterRefresh(ctx context.Context, tracking []mexc.Position, symbols []string, h Handler) {
+	m.refreshImbalances(ctx, symbols, h)
+	m.refreshFunding(ctx, symbols, h)
+	m.refreshContractSizes(ctx, symbols, h)
+	for _, pos := range tracking {
+		m.evaluate(pos, h)
+	}
+	m.checkImbalances(tracking, h)
+	m.checkFunding(tracking, h)
+	m.checkHedges(tracking, h)
+	m.checkStale(tracking, time.Now(), h)
+	m.checkEquity(ctx, h)
+}
+
+// evaluate emits an Updated event when pos's divergence meets the symbol's
+// threshold and the alert policy allows it, and Resolved once it falls back.
+func (m *Monitor) evaluate(pos mexc.Position, h Handler) {
+	fairPrice, ok := m.prices[pos.Symbol]
+	if !ok {
+		return
+	}
+
+	key := positionKey(pos)
+	alertKey := divergenceAlertKey(key)
+	current := state{holdAvgPrice: pos.HoldAvgPrice, holdVol: pos.HoldVol}
+	// A position seen for the first time keeps any alert state restored from
+	// an earlier run.
+	if previous, ok := m.reported[key]; ok && previous != current {
+		if s, ok := m.opts.Alerts.State(alertKey); ok && !s.Armed {
+			h.HandleEvent(Event{
+				Kind: Resolved, Resolves: Updated, Changed: true,
+				Position: pos, FairPrice: fairPrice,
+				AlertKey: alertKey, FiredAt: s.LastFired,
+			})
+		}
+		m.opts.Alerts.Reset(alertKey)
+	}
+	m.reported[key] = current
+
+	threshold := m.opts.Threshold(pos.Symbol)
+	active := threshold.Breached(fairPrice, pos.HoldAvgPrice)
+	cleared := !threshold.scaled(m.opts.Alerts.Policy().Rearm(1)).Breached(fairPrice, pos.HoldAvgPrice)
+	ev := Event{Kind: Updated, Position: pos, FairPrice: fairPrice}
+	if imbalance, ok := m.imbalances[pos.Symbol]; ok && m.opts.ImbalanceInReports {
+		ev.Imbalance, ev.HasImbalance = imbalance, true
+	}
+	if f, ok := m.funding[pos.Symbol]; ok && m.opts.FundingInReports {
+		ev.Funding, ev.HasFunding = f, true
+	}
+	m.alert(ev, alertKey, active, cleared, h)
+}
+
+// alert runs key through the alert policy and emits ev when an alert is
+// due, or a Resolved event with ev's details when a fired alert cleared.
+func (m *Monitor) alert(ev Event, key string, active, cleared bool, h Handler) {
+	ev.AlertKey = key
+	switch m.opts.Alerts.Update(key, active, cleared, time.Now()) {
+	case alert.Fired:
+		h.HandleEvent(ev)
+	case alert.Rearmed:
+		ev.Kind, ev.Resolves = Resolved, ev.Kind
+		if s, ok := m.opts.Alerts.State(key); ok {
+			ev.FiredAt = s.LastFired
+		}
+		h.HandleEvent(ev)
+	}
+}
+
+func (m *Monitor) holds(symbol string) bool {
+	for _, pos := range m.positions {
+		if pos.Symbol == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// checkStale nudges about positions held longer than StaleAfter.
+func (m *Monitor) checkStale(tracking []mexc.Position, now time.Time, h Handler) {
+	if m.opts.StaleAfter <= 0 {
+		return
+	}
+
+	for _, pos := range tracking {
+		if pos.CreateTime == 0 {
+			continue
+		}
+		heldFor := now.Sub(pos.OpenedAt())
+		if heldFor < m.opts.StaleAfter {
+			continue
+		}
+
+		key := positionKey(pos)
+		if last, ok := m.nudged[key]; ok {
+			if m.opts.StaleRepeat <= 0 || now.Sub(last) < m.opts.StaleRepeat {
+				continue
+			}
+		}
+		m.nudged[key] = now
+		h.HandleEvent(Event{Kind: Stale, Position: pos, HeldFor: heldFor})
+	}
+}
+
+// refreshImbalances updates the cached order book imbalance per symbol. It
+// does nothing when imbalance tracking is disabled or the exchange has no
+// order book.
+func (m *Monitor) refreshImbalances(ctx context.Context, symbols []string, h Handler) {
+	if m.opts.ImbalanceLevels <= 0 || (!m.opts.ImbalanceInReports && m.opts.ImbalanceThreshold <= 0) {
+		return
+	}
+
+	books, ok := m.api.(exchange.DepthSource)
+	if !ok {
+		return
+	}
+	depth := func(ctx context.Context, symbol string) (mexc.OrderBook, error) {
+		return books.Depth(ctx, symbol, m.opts.ImbalanceLevels)
+	}
+	for symbol, result := range fetchAll(ctx, symbols, m.opts.Concurrency, depth) {
+		if result.err != nil {
+			delete(m.imbalances, symbol)
+			// Some exchanges of a combined account may not serve depth.
+			if !errors.Is(result.err, errors.ErrUnsupported) {
+				h.HandleError(symbol, fmt.Errorf("fetching order book: %w", result.err))
+			}
+			continue
+		}
+		m.imbalances[symbol] = result.value.Imbalance(m.opts.ImbalanceLevels)
+	}
+}
+
+// checkImbalances emits an ImbalanceAlert per symbol when its imbalance
+// reaches the threshold, as far as the alert policy allows.
+func (m *Monitor) checkImbalances(tracking []mexc.Position, h Handler) {
+	if m.opts.ImbalanceThreshold <= 0 {
+		return
+	}
+
+	checked := make(map[string]bool)
+	for _, pos := range tracking {
+		imbalance, ok := m.imbalances[pos.Symbol]
+		if !ok || checked[pos.Symbol] {
+			continue
+		}
+		checked[pos.Symbol] = true
+
+		magnitude := math.Abs(imbalance)
+		active := magnitude >= m.opts.ImbalanceThreshold
+		cleared := magnitude < m.opts.Alerts.Policy().Rearm(m.opts.ImbalanceThreshold)
+		m.alert(Event{
+			Kind:         ImbalanceAlert,
+			Position:     pos,
+			FairPrice:    m.prices[pos.Symbol],
+			Imbalance:    imbalance,
+			HasImbalance: true,
+		}, imbalanceAlertKey(pos.Symbol), active, cleared, h)
+	}
+}
+
+func divergenceAlertKey(positionKey string) string {
+	return "divergence:" + positionKey
+}
+
+func imbalanceAlertKey(symbol string) string {
+	return "imbalance:" + symbol
+}
+
+func missingAlertKey(symbol string) string {
+	return "missing:" + symbol
+}
+
+// positionKey distinguishes long and short legs of the same symbol in hedge mode.
+func positionKey(pos mexc.Position) string {
+	return fmt.Sprintf("%s/%d", pos.Symbol, pos.PositionType)
+}
@@ -0,0 +1,155 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/alert"
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+)
+
+// fakeExchange holds a set of positions at one fair price per symbol.
+type fakeExchange struct {
+	positions []mexc.Position
+	prices    map[string]float64
+	balances  []exchange.Balance
+}
+
+func (f *fakeExchange) Name() string { return "fake" }
+
+func (f *fakeExchange) OpenPositions(context.Context) ([]exchange.Position, error) {
+	return f.positions, nil
+}
+
+func (f *fakeExchange) FairPrice(_ context.Context, symbol string) (float64, error) {
+	price, ok := f.prices[symbol]
+	if !ok {
+		return 0, fmt.Errorf("no price for %s", symbol)
+	}
+	return price, nil
+}
+
+func (f *fakeExchange) Balances(context.Context) ([]exchange.Balance, error) {
+	return f.balances, nil
+}
+
+func (f *fakeExchange) PlaceOrder(context.Context, exchange.OrderRequest) (string, error) {
+	return "", errors.New("not supported")
+}
+
+func (f *fakeExchange) StreamPrices() exchange.PriceStream { return nil }
+
+// recorder keeps the events a poll emits in a readable form.
+type recorder struct {
+	events []Event
+}
+
+func (r *recorder) HandleEvent(ev Event) { r.events = append(r.events, ev) }
+
+func (r *recorder) HandleError(symbol string, err error) {}
+
+func (r *recorder) kinds() []string {
+	var kinds []string
+	for _, ev := range r.events {
+		kind := ev.Kind.String()
+		switch {
+		case ev.Kind == Resolved && ev.Changed:
+			kind += "(changed)"
+		case ev.Kind == Resolved:
+			kind += "(" + ev.Resolves.String() + ")"
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}
+
+func TestDivergenceTransitions(t *testing.T) {
+	long := func(vol, entry float64) mexc.Position {
+		return mexc.Position{PositionID: 1, Symbol: "BTC_USDT", PositionType: mexc.PositionTypeLong, HoldVol: vol, HoldAvgPrice: entry}
+	}
+	// Each step sets the position, or closes it when nil, and the fair
+	// price; the threshold is 1% of the entry price.
+	type step struct {
+		position *mexc.Position
+		price    float64
+		want     []string
+	}
+	open := func(p mexc.Position) *mexc.Position { return &p }
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"fired then resolved", []step{
+			{open(long(10, 100)), 102, []string{"updated"}},
+			{open(long(10, 100)), 102.5, nil},
+			{open(long(10, 100)), 100.5, []string{"resolved(updated)"}},
+			{open(long(10, 100)), 100.2, nil},
+		}},
+		{"rearmed then fired again", []step{
+			{open(long(10, 100)), 102, []string{"updated"}},
+			{open(long(10, 100)), 100, []string{"resolved(updated)"}},
+			{open(long(10, 100)), 98, []string{"updated"}},
+		}},
+		{"changed while fired", []step{
+			{open(long(10, 100)), 102, []string{"updated"}},
+			{open(long(20, 101)), 102, []string{"resolved(changed)"}},
+			{open(long(30, 101)), 103, []string{"updated"}},
+		}},
+		{"changed and still breached", []step{
+			{open(long(10, 100)), 102, []string{"updated"}},
+			{open(long(20, 100)), 102, []string{"resolved(changed)", "updated"}},
+			{open(long(20, 100)), 100, []string{"resolved(updated)"}},
+		}},
+		{"changed after resolving", []step{
+			{open(long(10, 100)), 102, []string{"updated"}},
+			{open(long(10, 100)), 100, []string{"resolved(updated)"}},
+			{open(long(20, 100)), 100, nil},
+		}},
+		{"changed without an alert", []step{
+			{open(long(10, 100)), 100, nil},
+			{open(long(20, 100)), 100.5, nil},
+			{open(long(30, 100)), 102, []string{"updated"}},
+		}},
+		{"closed", []step{
+			{open(long(10, 100)), 102, []string{"updated"}},
+			{nil, 102, []string{"closed"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ex := &fakeExchange{prices: map[string]float64{}}
+			m := NewForExchange(ex, Options{
+				Threshold: func(string) Threshold { return Threshold{Percent: 1} },
+				Alerts:    alert.NewManager(alert.Policy{}),
+			})
+			for i, s := range tt.steps {
+				ex.positions = nil
+				if s.position != nil {
+					ex.positions = []mexc.Position{*s.position}
+				}
+				ex.prices["BTC_USDT"] = s.price
+
+				var r recorder
+				m.Poll(context.Background(), &r)
+				if got := r.kinds(); fmt.Sprint(got) != fmt.Sprint(s.want) {
+					t.Fatalf("step %d: events %v, want %v", i+1, got, s.want)
+				}
+				for _, ev := range r.events {
+					if ev.AlertKey != "divergence:BTC_USDT/1" {
+						t.Errorf("step %d: %s event has alert key %q", i+1, ev.Kind, ev.AlertKey)
+					}
+					if ev.Kind == Resolved && ev.FiredAt.IsZero() {
+						t.Errorf("step %d: resolution without the time its alert fired", i+1)
+					}
+					if ev.Changed && (ev.Position != *s.position || ev.FairPrice != s.price) {
+						t.Errorf("step %d: changed event for %+v at %v, want the new position", i+1, ev.Position, ev.FairPrice)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Package rebalance restores a hedge ratio between the long and short legs on
+// an underlying by closing part of the oversized side.
+//
+// The rebalancer only ever reduces positions: it never opens or adds to a
+// leg, so a mistake can at worst leave an account less hedged, not more
+// exposed. Every order is bounded by Options' limits.
+package rebalance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+)
+
+var (
+	// ErrNothingToDo means the exposure needs no order, e.g. because the
+	// correction is smaller than one contract.
+	ErrNothingToDo = errors.New("rebalance: nothing to do")
+	// ErrLimitReached means an order was needed but a risk limit held it back.
+	ErrLimitReached = errors.New("rebalance: risk limit reached")
+)
+
+// Options sets the target ratio and the risk limits.
+type Options struct {
+	// Ratio is the target short exposure as a fraction of long exposure;
+	// 1 is delta-neutral.
+	Ratio float64
+	// MaxOrderValue caps the notional of one order, in quote currency. A
+	// call places at most one order, so a correction larger than this is
+	// only finished by later calls, each placing the next capped order.
+	MaxOrderValue float64
+	// MaxOrdersPerDay caps orders over any 24 hours, across all underlyings.
+	MaxOrdersPerDay int
+	// Cooldown is the minimum time between two orders on one underlying.
+	Cooldown time.Duration
+	// DryRun plans orders without placing them.
+	DryRun bool
+}
+
+// Result describes an order that was placed, or would have been in a dry run.
+type Result struct {
+	Underlying string
+	Order      mexc.OrderRequest
+	FairPrice  float64
+	// Value is the order's notional in quote currency.
+	Value float64
+	// Capped means the order was cut down to MaxOrderValue.
+	Capped  bool
+	OrderID string // empty in a dry run
+	DryRun  bool
+}
+
+// Rebalancer places closing orders to bring hedges back to Options.Ratio. It
+// is safe for concurrent use.
+type Rebalancer struct {
+	api  exchange.Exchange
+	opts Options
+
+	mu        sync.Mutex
+	lastOrder map[string]time.Time // by underlying
+	placed    []time.Time          // orders in the last 24 hours
+}
+
+// New returns a Rebalancer that trades through api.
+func New(api *mexc.Client, opts Options) *Rebalancer {
+	return NewForExchange(exchange.NewMEXC(api, ""), opts)
+}
+
+// NewForExchange returns a Rebalancer that trades on ex.
+func NewForExchange(ex exchange.Exchange, opts Options) *Rebalancer {
+	return &Rebalancer{api: ex, opts: opts, lastOrder: make(map[string]time.Time)}
+}
+
+// Rebalance closes part of the oversized side of e to move it towards the
+// target ratio with a single order. It returns ErrNothingToDo or
+// ErrLimitReached (wrapped, with details) when no order is placed for those
+// reasons.
+//
+// When the order is cut down to MaxOrderValue, Result.Capped is set and the
+// rest of the correction is left to the caller: it is placed only if
+// Rebalance is called again with the remaining exposure.
+func (r *Rebalancer) Rebalance(ctx context.Context, e monitor.Exposure) (Result, error) {
+	res := Result{Underlying: e.Underlying, DryRun: r.opts.DryRun}
+	if !e.Hedged() || r.opts.Ratio <= 0 {
+		return res, fmt.Errorf("%w: %s is not hedged", ErrNothingToDo, e.Underlying)
+	}
+
+	// Work out which side is oversized and by how much, in underlying units.
+	closeType, excess := mexc.PositionTypeShort, e.Short-r.opts.Ratio*e.Long
+	if excess < 0 {
+		closeType, excess = mexc.PositionTypeLong, e.Long-e.Short/r.opts.Ratio
+	}
+
+	leg, ok := largestLeg(e.Legs, closeType)
+	if !ok {
+		return res, fmt.Errorf("%w: no %s leg on %s", ErrNothingToDo, sideName(closeType), e.Underlying)
+	}
+	contractSize, err := exchange.ContractSize(ctx, r.api, leg.Symbol)
+	if err != nil {
+		return res, fmt.Errorf("fetching contract size: %w", err)
+	}
+	if contractSize <= 0 {
+		return res, fmt.Errorf("no contract size for %s", leg.Symbol)
+	}
+	fairPrice, err := r.api.FairPrice(ctx, leg.Symbol)
+	if err != nil {
+		return res, fmt.Errorf("fetching fair price: %w", err)
+	}
+	if fairPrice <= 0 {
+		return res, fmt.Errorf("no fair price for %s", leg.Symbol)
+	}
+
+	vol := math.Min(math.Floor(excess/contractSize), leg.HoldVol)
+	if limit := math.Floor(r.opts.MaxOrderValue / (contractSize * fairPrice)); vol > limit {
+		vol, res.Capped = limit, true
+	}
+	if vol < 1 {
+		return res, fmt.Errorf("%w: correction for %s is under one contract within limits", ErrNothingToDo, e.Underlying)
+	}
+
+	side := mexc.OrderSideCloseLong
+	if closeType == mexc.PositionTypeShort {
+		side = mexc.OrderSideCloseShort
+	}
+	openType := leg.OpenType
+	if openType == 0 {
+		openType = mexc.OpenTypeIsolated
+	}
+	res.Order = mexc.OrderRequest{
+		Symbol:     leg.Symbol,
+		Vol:        vol,
+		Side:       side,
+		Type:       mexc.OrderTypeMarket,
+		OpenType:   openType,
+		Leverage:   leg.Leverage,
+		PositionID: leg.PositionID,
+	}
+	res.FairPrice = fairPrice
+	res.Value = vol * contractSize * fairPrice
+
+	if r.opts.DryRun {
+		return res, nil
+	}
+	now := time.Now()
+	if err := r.reserve(e.Underlying, now); err != nil {
+		return res, err
+	}
+	res.OrderID, err = r.api.PlaceOrder(ctx, res.Order)
+	if err != nil {
+		return res, fmt.Errorf("submitting order: %w", err)
+	}
+	return res, nil
+}
+
+// reserve checks the cooldown and daily limit for an order on underlying at
+// now and, if allowed, counts it. Failed submissions still count, so a
+// persistent error can't turn into a burst of attempts.
+func (r *Rebalancer) reserve(underlying string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if last, ok := r.lastOrder[underlying]; ok && now.Sub(last) < r.opts.Cooldown {
+		return fmt.Errorf("%w: last %s order was %s ago (cooldown %s)", ErrLimitReached, underlying, now.Sub(last).Round(time.Second), r.opts.Cooldown)
+	}
+
+	recent := r.placed[:0]
+	for _, t := range r.placed {
+		if now.Sub(t) < 24*time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	r.placed = recent
+	if len(r.placed) >= r.opts.MaxOrdersPerDay {
+		return fmt.Errorf("%w: %d orders in the last 24h", ErrLimitReached, len(r.placed))
+	}
+
+	r.lastOrder[underlying] = now
+	r.placed = append(r.placed, now)
+	return nil
+}
+
+// largestLeg returns the biggest position of positionType among legs.
+func largestLeg(legs []mexc.Position, positionType int) (mexc.Position, bool) {
+	var best mexc.Position
+	found := false
+	for _, leg := range legs {
+		if leg.PositionType == positionType && (!found || leg.HoldVol > best.HoldVol) {
+			best, found = leg, true
+		}
+	}
+	return best, found
+}
+
+func sideName(positionType int) string {
+	if positionType == mexc.PositionTypeShort {
+		return "short"
+	}
+	return "long"
+}
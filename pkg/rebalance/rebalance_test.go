@@ -0,0 +1,299 @@
+package rebalance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/exchange"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+)
+
+// fakeExchange prices every symbol the same and records the orders placed.
+type fakeExchange struct {
+	contractSize float64
+	price        float64
+	orderErr     error
+
+	mu     sync.Mutex
+	orders []exchange.OrderRequest
+}
+
+func (f *fakeExchange) Name() string { return "Fake" }
+
+func (f *fakeExchange) OpenPositions(context.Context) ([]exchange.Position, error) { return nil, nil }
+
+func (f *fakeExchange) FairPrice(context.Context, string) (float64, error) { return f.price, nil }
+
+func (f *fakeExchange) Balances(context.Context) ([]exchange.Balance, error) { return nil, nil }
+
+func (f *fakeExchange) PlaceOrder(_ context.Context, req exchange.OrderRequest) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.orders = append(f.orders, req)
+	if f.orderErr != nil {
+		return "", f.orderErr
+	}
+	return "order-1", nil
+}
+
+func (f *fakeExchange) StreamPrices() exchange.PriceStream { return nil }
+
+func (f *fakeExchange) ContractSize(context.Context, string) (float64, error) {
+	return f.contractSize, nil
+}
+
+// exposure nets legs as the monitor does, with every contract worth
+// contractSize of the underlying.
+func exposure(contractSize float64, legs ...mexc.Position) monitor.Exposure {
+	e := monitor.Exposure{Underlying: "BTC", Legs: legs}
+	for _, leg := range legs {
+		if leg.PositionType == mexc.PositionTypeShort {
+			e.Short += leg.HoldVol * contractSize
+		} else {
+			e.Long += leg.HoldVol * contractSize
+		}
+	}
+	return e
+}
+
+func long(id int64, vol float64) mexc.Position {
+	return mexc.Position{PositionID: id, Symbol: "BTC_USDT", PositionType: mexc.PositionTypeLong, HoldVol: vol, Leverage: 10, OpenType: mexc.OpenTypeCross}
+}
+
+func short(id int64, vol float64) mexc.Position {
+	return mexc.Position{PositionID: id, Symbol: "BTC_USDC", PositionType: mexc.PositionTypeShort, HoldVol: vol, Leverage: 20}
+}
+
+func TestRebalance(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		exposure monitor.Exposure
+		want     mexc.OrderRequest // zero when no order is placed
+		capped   bool
+		err      error
+	}{
+		{
+			name:     "short oversized",
+			opts:     Options{Ratio: 1},
+			exposure: exposure(0.001, long(1, 1000), short(2, 1500)),
+			want:     mexc.OrderRequest{Symbol: "BTC_USDC", Vol: 500, Side: mexc.OrderSideCloseShort, Type: mexc.OrderTypeMarket, OpenType: mexc.OpenTypeIsolated, Leverage: 20, PositionID: 2},
+		},
+		{
+			name:     "long oversized at ratio 0.5",
+			opts:     Options{Ratio: 0.5},
+			exposure: exposure(0.001, long(1, 2000), short(2, 500)),
+			want:     mexc.OrderRequest{Symbol: "BTC_USDT", Vol: 1000, Side: mexc.OrderSideCloseLong, Type: mexc.OrderTypeMarket, OpenType: mexc.OpenTypeCross, Leverage: 10, PositionID: 1},
+		},
+		{
+			name:     "largest leg of the oversized side",
+			opts:     Options{Ratio: 1},
+			exposure: exposure(0.001, long(1, 1000), short(2, 300), short(3, 900), short(4, 800)),
+			want:     mexc.OrderRequest{Symbol: "BTC_USDC", Vol: 900, Side: mexc.OrderSideCloseShort, Type: mexc.OrderTypeMarket, OpenType: mexc.OpenTypeIsolated, Leverage: 20, PositionID: 3},
+		},
+		{
+			name:     "never more than the leg holds",
+			opts:     Options{Ratio: 1},
+			exposure: exposure(0.001, long(1, 100), short(2, 800), short(3, 700)),
+			want:     mexc.OrderRequest{Symbol: "BTC_USDC", Vol: 800, Side: mexc.OrderSideCloseShort, Type: mexc.OrderTypeMarket, OpenType: mexc.OpenTypeIsolated, Leverage: 20, PositionID: 2},
+		},
+		{
+			name:     "capped by max order value",
+			opts:     Options{Ratio: 1, MaxOrderValue: 6100},
+			exposure: exposure(0.001, long(1, 1000), short(2, 1500)),
+			want:     mexc.OrderRequest{Symbol: "BTC_USDC", Vol: 101, Side: mexc.OrderSideCloseShort, Type: mexc.OrderTypeMarket, OpenType: mexc.OpenTypeIsolated, Leverage: 20, PositionID: 2},
+			capped:   true,
+		},
+		{
+			name:     "cap under one contract",
+			opts:     Options{Ratio: 1, MaxOrderValue: 50},
+			exposure: exposure(0.001, long(1, 1000), short(2, 1500)),
+			err:      ErrNothingToDo,
+		},
+		{
+			name:     "correction under one contract",
+			opts:     Options{Ratio: 1},
+			exposure: exposure(0.001, long(1, 1000), short(2, 1000)),
+			err:      ErrNothingToDo,
+		},
+		{
+			name:     "unhedged",
+			opts:     Options{Ratio: 1},
+			exposure: exposure(0.001, long(1, 1000)),
+			err:      ErrNothingToDo,
+		},
+		{
+			name:     "no target ratio",
+			opts:     Options{},
+			exposure: exposure(0.001, long(1, 1000), short(2, 1500)),
+			err:      ErrNothingToDo,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.opts.MaxOrderValue == 0 {
+				tt.opts.MaxOrderValue = 1e9
+			}
+			tt.opts.MaxOrdersPerDay = 10
+			ex := &fakeExchange{contractSize: 0.001, price: 60000}
+			res, err := NewForExchange(ex, tt.opts).Rebalance(context.Background(), tt.exposure)
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				if len(ex.orders) != 0 {
+					t.Errorf("placed %+v", ex.orders)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Order != tt.want || res.Capped != tt.capped || res.OrderID != "order-1" {
+				t.Errorf("Result = %+v, want order %+v, capped %t", res, tt.want, tt.capped)
+			}
+			if want := tt.want.Vol * 0.001 * 60000; res.Value != want || res.FairPrice != 60000 {
+				t.Errorf("Value = %v at %v, want %v", res.Value, res.FairPrice, want)
+			}
+			if len(ex.orders) != 1 || ex.orders[0] != tt.want {
+				t.Errorf("placed %+v, want one order", ex.orders)
+			}
+		})
+	}
+}
+
+func TestRebalanceDryRun(t *testing.T) {
+	ex := &fakeExchange{contractSize: 0.001, price: 60000}
+	r := NewForExchange(ex, Options{Ratio: 1, MaxOrderValue: 1e9, MaxOrdersPerDay: 1, DryRun: true})
+	for i := 0; i < 3; i++ {
+		res, err := r.Rebalance(context.Background(), exposure(0.001, long(1, 1000), short(2, 1500)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.DryRun || res.Order.Vol != 500 || res.OrderID != "" {
+			t.Errorf("Result = %+v, want a dry run order of 500", res)
+		}
+	}
+	if len(ex.orders) != 0 {
+		t.Errorf("dry run placed %+v", ex.orders)
+	}
+}
+
+// A capped correction places one order per call; the rest is only placed if
+// Rebalance is called again.
+func TestRebalanceCappedCorrectionContinues(t *testing.T) {
+	ex := &fakeExchange{contractSize: 0.001, price: 60000}
+	r := NewForExchange(ex, Options{Ratio: 1, MaxOrderValue: 12000, MaxOrdersPerDay: 2})
+
+	shortVol := 1500.0
+	for _, want := range []float64{200, 200} {
+		res, err := r.Rebalance(context.Background(), exposure(0.001, long(1, 1000), short(2, shortVol)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Order.Vol != want || !res.Capped {
+			t.Fatalf("Result = %+v, want a capped order of %g", res, want)
+		}
+		shortVol -= res.Order.Vol
+	}
+	if len(ex.orders) != 2 {
+		t.Errorf("placed %d orders, want one per call", len(ex.orders))
+	}
+
+	_, err := r.Rebalance(context.Background(), exposure(0.001, long(1, 1000), short(2, shortVol)))
+	if !errors.Is(err, ErrLimitReached) {
+		t.Errorf("err = %v, want ErrLimitReached after max_orders_per_day", err)
+	}
+}
+
+func TestRebalanceFailedOrderCounts(t *testing.T) {
+	ex := &fakeExchange{contractSize: 0.001, price: 60000, orderErr: errors.New("insufficient margin")}
+	r := NewForExchange(ex, Options{Ratio: 1, MaxOrderValue: 1e9, MaxOrdersPerDay: 1})
+	e := exposure(0.001, long(1, 1000), short(2, 1500))
+
+	if _, err := r.Rebalance(context.Background(), e); err == nil || errors.Is(err, ErrLimitReached) {
+		t.Fatalf("err = %v, want the submission error", err)
+	}
+	if _, err := r.Rebalance(context.Background(), e); !errors.Is(err, ErrLimitReached) {
+		t.Errorf("err = %v, want ErrLimitReached as the failed order counts", err)
+	}
+	if len(ex.orders) != 1 {
+		t.Errorf("submitted %d orders, want 1", len(ex.orders))
+	}
+}
+
+func TestReserve(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	type step struct {
+		underlying string
+		at         time.Duration // since start
+		ok         bool
+	}
+	tests := []struct {
+		name  string
+		opts  Options
+		steps []step
+	}{
+		{"cooldown per underlying", Options{MaxOrdersPerDay: 10, Cooldown: time.Hour}, []step{
+			{"BTC", 0, true},
+			{"BTC", 30 * time.Minute, false},
+			{"ETH", 30 * time.Minute, true},
+			{"BTC", time.Hour, true},
+		}},
+		{"rejected orders don't restart the cooldown", Options{MaxOrdersPerDay: 10, Cooldown: time.Hour}, []step{
+			{"BTC", 0, true},
+			{"BTC", 59 * time.Minute, false},
+			{"BTC", 61 * time.Minute, true},
+		}},
+		{"daily limit across underlyings", Options{MaxOrdersPerDay: 2}, []step{
+			{"BTC", 0, true},
+			{"ETH", time.Hour, true},
+			{"SOL", 2 * time.Hour, false},
+			{"BTC", 23 * time.Hour, false},
+			// The first order has left the 24 hour window.
+			{"SOL", 24 * time.Hour, true},
+			{"SOL", 24*time.Hour + time.Minute, false},
+			{"SOL", 25 * time.Hour, true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewForExchange(&fakeExchange{}, tt.opts)
+			for i, s := range tt.steps {
+				err := r.reserve(s.underlying, start.Add(s.at))
+				if s.ok && err != nil {
+					t.Errorf("step %d: %s at %s: %v", i, s.underlying, s.at, err)
+				}
+				if !s.ok && !errors.Is(err, ErrLimitReached) {
+					t.Errorf("step %d: %s at %s: err = %v, want ErrLimitReached", i, s.underlying, s.at, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLargestLeg(t *testing.T) {
+	legs := []mexc.Position{long(1, 5), short(2, 3), long(3, 9), short(4, 7), long(5, 9)}
+	tests := []struct {
+		positionType int
+		wantID       int64
+		ok           bool
+	}{
+		{mexc.PositionTypeLong, 3, true}, // the first of equal legs
+		{mexc.PositionTypeShort, 4, true},
+	}
+	for _, tt := range tests {
+		leg, ok := largestLeg(legs, tt.positionType)
+		if ok != tt.ok || leg.PositionID != tt.wantID {
+			t.Errorf("largestLeg(%d) = %d, %t, want %d", tt.positionType, leg.PositionID, ok, tt.wantID)
+		}
+	}
+	if _, ok := largestLeg([]mexc.Position{long(1, 5)}, mexc.PositionTypeShort); ok {
+		t.Error("largestLeg found a short among longs only")
+	}
+}
@@ -0,0 +1,100 @@
+package telegram
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Login errors returned by VerifyLogin.
+var (
+	// ErrLoginInvalid means the data was not signed with the bot's token.
+	ErrLoginInvalid = errors.New("telegram: login data is not signed by the bot")
+	// ErrLoginExpired means the login is older than the allowed age.
+	ErrLoginExpired = errors.New("telegram: login data has expired")
+	// ErrLoginForbidden means the login is genuine but the user is not the
+	// one the bot answers.
+	ErrLoginForbidden = errors.New("telegram: user is not allowed")
+)
+
+// LoginUser is the identity the Telegram Login Widget hands to its callback.
+type LoginUser struct {
+	ID        int64
+	FirstName string
+	LastName  string
+	Username  string
+	PhotoURL  string
+	AuthDate  time.Time
+}
+
+// VerifyLogin checks the fields the Login Widget passes to its callback, as
+// the query string or as the JSON object's keys and values, and returns the
+// user they describe. The hash must be the HMAC of the other fields keyed
+// with the SHA-256 of token, and logins older than maxAge are rejected;
+// zero accepts any age. See https://core.telegram.org/widgets/login.
+func VerifyLogin(token string, fields url.Values, maxAge time.Duration) (LoginUser, error) {
+	hash, err := hex.DecodeString(fields.Get("hash"))
+	if err != nil || len(hash) == 0 {
+		return LoginUser{}, ErrLoginInvalid
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if k != "hash" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = k + "=" + fields.Get(k)
+	}
+	secret := sha256.Sum256([]byte(token))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	if !hmac.Equal(mac.Sum(nil), hash) {
+		return LoginUser{}, ErrLoginInvalid
+	}
+
+	id, err := strconv.ParseInt(fields.Get("id"), 10, 64)
+	if err != nil {
+		return LoginUser{}, ErrLoginInvalid
+	}
+	authDate, err := strconv.ParseInt(fields.Get("auth_date"), 10, 64)
+	if err != nil {
+		return LoginUser{}, ErrLoginInvalid
+	}
+	user := LoginUser{
+		ID:        id,
+		FirstName: fields.Get("first_name"),
+		LastName:  fields.Get("last_name"),
+		Username:  fields.Get("username"),
+		PhotoURL:  fields.Get("photo_url"),
+		AuthDate:  time.Unix(authDate, 0),
+	}
+	if maxAge > 0 && time.Since(user.AuthDate) > maxAge {
+		return LoginUser{}, ErrLoginExpired
+	}
+	return user, nil
+}
+
+// VerifyLogin is the package-level VerifyLogin with the client's bot token,
+// and also requires the user to be the client's configured chat. That ties
+// logins to the same identity the Router answers: the configured chat ID of
+// a private chat is the user's ID.
+func (c *Client) VerifyLogin(fields url.Values, maxAge time.Duration) (LoginUser, error) {
+	user, err := VerifyLogin(c.token, fields, maxAge)
+	if err != nil {
+		return LoginUser{}, err
+	}
+	if strconv.FormatInt(user.ID, 10) != c.chatID {
+		return LoginUser{}, ErrLoginForbidden
+	}
+	return user, nil
+}
@@ -0,0 +1,141 @@
+package telegram
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "123456:ABC-DEF"
+
+// goldenLogin is signed with testToken; its hash was computed separately
+// from this package, following the Login Widget documentation.
+func goldenLogin() url.Values {
+	return url.Values{
+		"id":         {"42"},
+		"first_name": {"Ada"},
+		"username":   {"ada"},
+		"auth_date":  {"1700000000"},
+		"hash":       {"66beca94ffb45e51be6d44f230080b366f33537a237cbfc15bd24fc0069c713e"},
+	}
+}
+
+// signLogin returns fields with a hash over them, as the widget sends them.
+func signLogin(fields url.Values) url.Values {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = k + "=" + fields.Get(k)
+	}
+	secret := sha256.Sum256([]byte(testToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	fields.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return fields
+}
+
+func freshLogin(age time.Duration) url.Values {
+	return signLogin(url.Values{
+		"id":         {"42"},
+		"first_name": {"Ada"},
+		"last_name":  {"Lovelace"},
+		"username":   {"ada"},
+		"photo_url":  {"https://t.me/i/userpic/320/ada.jpg"},
+		"auth_date":  {strconv.FormatInt(time.Now().Add(-age).Unix(), 10)},
+	})
+}
+
+func TestVerifyLogin(t *testing.T) {
+	tamper := func(fields url.Values, key, value string) url.Values {
+		fields.Set(key, value)
+		return fields
+	}
+	without := func(fields url.Values, key string) url.Values {
+		fields.Del(key)
+		return fields
+	}
+
+	tests := []struct {
+		name   string
+		fields url.Values
+		maxAge time.Duration
+		want   error
+	}{
+		{"golden", goldenLogin(), 0, nil},
+		{"fresh", freshLogin(time.Minute), time.Hour, nil},
+		{"tampered id", tamper(freshLogin(time.Minute), "id", "43"), time.Hour, ErrLoginInvalid},
+		{"tampered name", tamper(goldenLogin(), "first_name", "Eve"), 0, ErrLoginInvalid},
+		{"added field", tamper(goldenLogin(), "last_name", "Lovelace"), 0, ErrLoginInvalid},
+		{"removed field", without(goldenLogin(), "username"), 0, ErrLoginInvalid},
+		{"tampered hash", tamper(goldenLogin(), "hash", strings.Repeat("0", 64)), 0, ErrLoginInvalid},
+		{"malformed hash", tamper(goldenLogin(), "hash", "not hex"), 0, ErrLoginInvalid},
+		{"missing hash", without(goldenLogin(), "hash"), 0, ErrLoginInvalid},
+		{"expired", freshLogin(2 * time.Hour), time.Hour, ErrLoginExpired},
+		{"old without max age", freshLogin(48 * time.Hour), 0, nil},
+		{"non-numeric auth_date", signLogin(tamper(without(freshLogin(0), "hash"), "auth_date", "yesterday")), time.Hour, ErrLoginInvalid},
+		{"non-numeric id", signLogin(tamper(without(freshLogin(0), "hash"), "id", "ada")), time.Hour, ErrLoginInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := VerifyLogin(testToken, tt.fields, tt.maxAge)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if err == nil && (user.ID != 42 || user.FirstName != "Ada" || user.Username != "ada") {
+				t.Errorf("user = %+v, want Ada (42)", user)
+			}
+		})
+	}
+
+	if _, err := VerifyLogin("654321:other", goldenLogin(), 0); !errors.Is(err, ErrLoginInvalid) {
+		t.Errorf("login signed for another bot: err = %v, want ErrLoginInvalid", err)
+	}
+}
+
+func TestVerifyLoginFields(t *testing.T) {
+	fields := freshLogin(time.Minute)
+	user, err := VerifyLogin(testToken, fields, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authDate, _ := strconv.ParseInt(fields.Get("auth_date"), 10, 64)
+	want := LoginUser{
+		ID:        42,
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Username:  "ada",
+		PhotoURL:  "https://t.me/i/userpic/320/ada.jpg",
+		AuthDate:  time.Unix(authDate, 0),
+	}
+	if user != want {
+		t.Errorf("user = %+v, want %+v", user, want)
+	}
+}
+
+func TestClientVerifyLogin(t *testing.T) {
+	tests := []struct {
+		chatID string
+		want   error
+	}{
+		{"42", nil},
+		{"43", ErrLoginForbidden},
+		{"-1001234567890", ErrLoginForbidden},
+	}
+	for _, tt := range tests {
+		c := NewClient(testToken, tt.chatID)
+		if _, err := c.VerifyLogin(goldenLogin(), 0); !errors.Is(err, tt.want) {
+			t.Errorf("chat %s: err = %v, want %v", tt.chatID, err, tt.want)
+		}
+	}
+}
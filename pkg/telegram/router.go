@@ -0,0 +1,157 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// HandlerFunc answers a command. args holds the whitespace-separated words
+// following the command; the returned text is sent back to the chat.
+type HandlerFunc func(ctx context.Context, args []string) (string, error)
+
+type command struct {
+	name        string
+	usage       string
+	description string
+	handler     HandlerFunc
+}
+
+// Router dispatches bot commands received through getUpdates. It only
+// answers messages from the client's configured chat, so the bot can't be
+// used to query the account from anywhere else.
+type Router struct {
+	client   *Client
+	commands map[string]*command
+	order    []string
+}
+
+// NewRouter returns a Router that receives and replies through client. A
+// /help command listing every registered command is built in.
+func NewRouter(client *Client) *Router {
+	r := &Router{
+		client:   client,
+		commands: make(map[string]*command),
+	}
+	r.Handle("help", "", "Show this message", r.help)
+	return r
+}
+
+// Handle registers h for /name. usage documents the arguments, if any.
+func (r *Router) Handle(name, usage, description string, h HandlerFunc) {
+	if _, exists := r.commands[name]; !exists {
+		r.order = append(r.order, name)
+	}
+	r.commands[name] = &command{name: name, usage: usage, description: description, handler: h}
+}
+
+// Listen polls for updates and dispatches commands until ctx is canceled.
+// Polling errors are logged to slog's default logger and retried after a
+// short pause.
+func (r *Router) Listen(ctx context.Context) error {
+	var offset int64
+	for {
+		updates, err := r.client.GetUpdates(ctx, offset)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.Error("polling Telegram updates", errAttrs(err)...)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(r.client.retryDelay):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			if update.Message == nil {
+				continue
+			}
+			r.dispatch(ctx, update.Message)
+		}
+	}
+}
+
+func (r *Router) dispatch(ctx context.Context, msg *Message) {
+	chatID := msg.Chat.ChatIDString()
+	if chatID != r.client.chatID {
+		return
+	}
+
+	name, args, ok := parseCommand(msg.Text)
+	if !ok {
+		return
+	}
+
+	cmd, found := r.commands[name]
+	if !found {
+		r.reply(ctx, chatID, fmt.Sprintf("Unknown command /%s. Send /help for the list of commands.", name))
+		return
+	}
+
+	reply, err := cmd.handler(ctx, args)
+	if ctx.Err() != nil {
+		// Shutting down; the handler was cut short.
+		return
+	}
+	if err != nil {
+		reply = fmt.Sprintf("/%s failed: %v", name, err)
+	}
+	if reply != "" {
+		r.reply(ctx, chatID, reply)
+	}
+}
+
+func (r *Router) reply(ctx context.Context, chatID, text string) {
+	if err := r.client.SendMessageToContext(ctx, chatID, text); err != nil {
+		slog.Error("sending Telegram reply", errAttrs(err, "chat", chatID)...)
+	}
+}
+
+// errAttrs returns err as slog arguments, with the Bot API error code when
+// there is one.
+func errAttrs(err error, args ...any) []any {
+	args = append(args, "err", err)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		args = append(args, "status", apiErr.Code)
+	}
+	return args
+}
+
+func (r *Router) help(ctx context.Context, args []string) (string, error) {
+	var b strings.Builder
+	b.WriteString("Available commands:\n")
+	for _, name := range r.order {
+		cmd := r.commands[name]
+		b.WriteString("/" + cmd.name)
+		if cmd.usage != "" {
+			b.WriteString(" " + cmd.usage)
+		}
+		b.WriteString(" - " + cmd.description + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// parseCommand splits "/price@MyBot BTC_USDT" into "price" and ["BTC_USDT"].
+func parseCommand(text string) (string, []string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", nil, false
+	}
+
+	name := strings.TrimPrefix(fields[0], "/")
+	if at := strings.Index(name, "@"); at >= 0 {
+		name = name[:at]
+	}
+	if name == "" {
+		return "", nil, false
+	}
+	return strings.ToLower(name), fields[1:], true
+}
@@ -0,0 +1,78 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestRouter returns a router for chat 42 with /price and /fail
+// registered, and the replies it sends.
+func newTestRouter(t *testing.T) (*Router, *[]messagePayload) {
+	t.Helper()
+	var replies []messagePayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload messagePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		replies = append(replies, payload)
+		w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	r := NewRouter(NewClientWithBaseURL(testToken, "42", srv.URL))
+	r.Handle("price", "<symbol>", "Show a price", func(ctx context.Context, args []string) (string, error) {
+		return "price of " + strings.Join(args, ","), nil
+	})
+	r.Handle("fail", "", "Always fails", func(ctx context.Context, args []string) (string, error) {
+		return "", errors.New("boom")
+	})
+	r.Handle("quiet", "", "Replies with nothing", func(ctx context.Context, args []string) (string, error) {
+		return "", nil
+	})
+	return r, &replies
+}
+
+func TestRouterDispatch(t *testing.T) {
+	tests := []struct {
+		name string
+		chat int64
+		text string
+		want string // reply; empty for none
+	}{
+		{"command", 42, "/price BTC_USDT ETH_USDT", "price of BTC_USDT,ETH_USDT"},
+		{"bot suffix and case", 42, "/PRICE@MyBot BTC_USDT", "price of BTC_USDT"},
+		{"other chat", 7, "/price BTC_USDT", ""},
+		{"other chat, unknown command", 7, "/nope", ""},
+		{"not a command", 42, "price BTC_USDT", ""},
+		{"bare slash", 42, "/", ""},
+		{"unknown command", 42, "/nope", "Unknown command /nope. Send /help for the list of commands."},
+		{"handler error", 42, "/fail", "/fail failed: boom"},
+		{"empty reply", 42, "/quiet", ""},
+		{"help", 42, "/help", "Available commands:\n/help - Show this message\n/price <symbol> - Show a price\n/fail - Always fails\n/quiet - Replies with nothing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, replies := newTestRouter(t)
+			r.dispatch(context.Background(), &Message{Text: tt.text, Chat: Chat{ID: tt.chat}})
+
+			if tt.want == "" {
+				if len(*replies) != 0 {
+					t.Errorf("replies = %+v, want none", *replies)
+				}
+				return
+			}
+			if len(*replies) != 1 {
+				t.Fatalf("replies = %+v, want one", *replies)
+			}
+			if got := (*replies)[0]; got.ChatID != "42" || got.Text != tt.want {
+				t.Errorf("reply to %s: %q, want %q", got.ChatID, got.Text, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+// Package telegram is a minimal client for the Telegram Bot API.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseURL     = "https://api.telegram.org"
+	defaultMaxAttempts = 3
+	defaultRetryDelay  = time.Second
+)
+
+// Client sends messages to a single chat on behalf of a bot.
+type Client struct {
+	token      string
+	chatID     string
+	baseURL    string
+	httpClient *http.Client
+
+	maxAttempts int
+	retryDelay  time.Duration
+	// sleep waits between retries; nil uses a timer. Tests replace it.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewClient returns a Client that posts to chatID using the given bot token.
+func NewClient(token, chatID string) *Client {
+	return NewClientWithBaseURL(token, chatID, defaultBaseURL)
+}
+
+// NewClientWithBaseURL is NewClient for a Bot API server other than
+// api.telegram.org, such as a local telegram-bot-api server.
+func NewClientWithBaseURL(token, chatID, baseURL string) *Client {
+	return &Client{
+		token:       token,
+		chatID:      chatID,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		maxAttempts: defaultMaxAttempts,
+		retryDelay:  defaultRetryDelay,
+	}
+}
+
+// APIError is returned when the Bot API rejects a request.
+type APIError struct {
+	Code        int
+	Description string
+	RetryAfter  time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram: %d %s", e.Code, e.Description)
+}
+
+// temporary reports whether the request may succeed if retried.
+func (e *APIError) temporary() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code >= 500
+}
+
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// SendMessage posts text to the configured chat. Network errors, server
+// errors and rate limiting are retried with a growing delay; rate limit
+// responses honor the retry_after hint from Telegram.
+func (c *Client) SendMessage(text string) error {
+	return c.SendMessageContext(context.Background(), text)
+}
+
+// SendMessageContext is like SendMessage but gives up, including between
+// retries, when ctx is done.
+func (c *Client) SendMessageContext(ctx context.Context, text string) error {
+	return c.SendMessageToContext(ctx, c.chatID, text)
+}
+
+// SendMessageTo posts text to chatID with the same retry behavior as SendMessage.
+func (c *Client) SendMessageTo(chatID, text string) error {
+	return c.SendMessageToContext(context.Background(), chatID, text)
+}
+
+// SendMessageToContext is like SendMessageTo but gives up when ctx is done.
+func (c *Client) SendMessageToContext(ctx context.Context, chatID, text string) error {
+	payload := map[string]string{
+		"chat_id": chatID,
+		"text":    text,
+	}
+	return c.send(ctx, "sendMessage", payload, nil)
+}
+
+// replyParameters is the reply_parameters object of sendMessage.
+type replyParameters struct {
+	MessageID                int64 `json:"message_id"`
+	AllowSendingWithoutReply bool  `json:"allow_sending_without_reply"`
+}
+
+// SendReplyContext posts text to the configured chat as a reply to the
+// message with ID replyTo, or as a new message when replyTo is zero, and
+// returns the ID of the message sent. It is still sent if replyTo was
+// deleted in the meantime. Retries are as for SendMessage.
+func (c *Client) SendReplyContext(ctx context.Context, text string, replyTo int64) (int64, error) {
+	return c.SendContext(ctx, OutgoingMessage{Text: text, ReplyTo: replyTo})
+}
+
+// OutgoingMessage is a message to send or an edit to make, with the options
+// SendMessage doesn't take.
+type OutgoingMessage struct {
+	Text string
+	// HTML parses Text as Telegram's HTML subset; see
+	// https://core.telegram.org/bots/api#html-style.
+	HTML bool
+	// ReplyTo is the ID of the message this one replies to, if non-zero.
+	// Ignored by EditMessageContext.
+	ReplyTo int64
+}
+
+// messagePayload is the part of sendMessage and editMessageText that
+// OutgoingMessage describes.
+type messagePayload struct {
+	ChatID          string           `json:"chat_id"`
+	MessageID       int64            `json:"message_id,omitempty"`
+	Text            string           `json:"text"`
+	ParseMode       string           `json:"parse_mode,omitempty"`
+	ReplyParameters *replyParameters `json:"reply_parameters,omitempty"`
+}
+
+func (c *Client) payload(msg OutgoingMessage) messagePayload {
+	payload := messagePayload{ChatID: c.chatID, Text: msg.Text}
+	if msg.HTML {
+		payload.ParseMode = "HTML"
+	}
+	return payload
+}
+
+// SendContext posts msg to the configured chat and returns the ID of the
+// message sent. Like SendReplyContext, a reply is still sent if the message
+// it replies to is gone. Retries are as for SendMessage.
+func (c *Client) SendContext(ctx context.Context, msg OutgoingMessage) (int64, error) {
+	payload := c.payload(msg)
+	if msg.ReplyTo != 0 {
+		payload.ReplyParameters = &replyParameters{MessageID: msg.ReplyTo, AllowSendingWithoutReply: true}
+	}
+	var sent struct {
+		MessageID int64 `json:"message_id"`
+	}
+	if err := c.send(ctx, "sendMessage", payload, &sent); err != nil {
+		return 0, err
+	}
+	return sent.MessageID, nil
+}
+
+// EditMessageContext replaces the text of the message with ID messageID in
+// the configured chat with msg. Edits don't notify anyone. Retries are as
+// for SendMessage.
+func (c *Client) EditMessageContext(ctx context.Context, messageID int64, msg OutgoingMessage) error {
+	payload := c.payload(msg)
+	payload.MessageID = messageID
+	return c.send(ctx, "editMessageText", payload, nil)
+}
+
+// send calls method with payload, retrying as described on SendMessage, and
+// decodes the result into out, if non-nil.
+func (c *Client) send(ctx context.Context, method string, payload interface{}, out interface{}) error {
+	delay := c.retryDelay
+	var err error
+	for attempt := 1; attempt <= c.maxAttempts; attempt++ {
+		err = c.call(ctx, method, payload, out)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			if !apiErr.temporary() {
+				return err
+			}
+			if apiErr.RetryAfter > delay {
+				delay = apiErr.RetryAfter
+			}
+		}
+
+		if attempt < c.maxAttempts {
+			if c.wait(ctx, delay) != nil {
+				return err
+			}
+			delay *= 2
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", c.maxAttempts, err)
+}
+
+// wait blocks for d, or until ctx is done.
+func (c *Client) wait(ctx context.Context, d time.Duration) error {
+	if c.sleep != nil {
+		return c.sleep(ctx, d)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// call invokes a Bot API method and decodes its result into out, if non-nil.
+func (c *Client) call(ctx context.Context, method string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/bot%s/%s", c.baseURL, c.token, method)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	response, err := c.httpClient.Do(req)
+	if err != nil {
+		// The request URL embeds the bot token, so don't let it leak into logs.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("telegram %s: reading response: %w", method, err)
+	}
+
+	var apiResp apiResponse
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		return &APIError{Code: response.StatusCode, Description: http.StatusText(response.StatusCode)}
+	}
+	if !apiResp.OK {
+		return &APIError{
+			Code:        apiResp.ErrorCode,
+			Description: apiResp.Description,
+			RetryAfter:  time.Duration(apiResp.Parameters.RetryAfter) * time.Second,
+		}
+	}
+
+	if out != nil {
+		return json.Unmarshal(apiResp.Result, out)
+	}
+	return nil
+}
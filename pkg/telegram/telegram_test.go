@@ -0,0 +1,110 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client for a server replying with responses in
+// turn, as status and body, and records the waits between retries.
+func newTestClient(t *testing.T, responses ...string) (*Client, *[]messagePayload, *[]time.Duration) {
+	t.Helper()
+	var requests []messagePayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bot"+testToken+"/sendMessage" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var payload messagePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, payload)
+		if len(requests) > len(responses) {
+			t.Errorf("unexpected request %d", len(requests))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		status, body, _ := strings.Cut(responses[len(requests)-1], " ")
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(code)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	var waits []time.Duration
+	c := NewClientWithBaseURL(testToken, "42", srv.URL+"/")
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return c, &requests, &waits
+}
+
+func TestSendRetriesAfterRateLimit(t *testing.T) {
+	c, requests, waits := newTestClient(t,
+		`429 {"ok":false,"error_code":429,"description":"Too Many Requests: retry after 5","parameters":{"retry_after":5}}`,
+		`200 {"ok":true,"result":{"message_id":7}}`,
+	)
+
+	id, err := c.SendContext(context.Background(), OutgoingMessage{Text: "hello", ReplyTo: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 7 {
+		t.Errorf("message ID = %d, want 7", id)
+	}
+	if len(*requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(*requests))
+	}
+	if p := (*requests)[1]; p.ChatID != "42" || p.Text != "hello" || p.ReplyParameters == nil || p.ReplyParameters.MessageID != 3 {
+		t.Errorf("retried payload = %+v", p)
+	}
+	// retry_after outweighs the one second base delay.
+	if len(*waits) != 1 || (*waits)[0] != 5*time.Second {
+		t.Errorf("waits = %v, want one of 5s", *waits)
+	}
+}
+
+func TestSendGivesUpOnBadRequest(t *testing.T) {
+	c, requests, waits := newTestClient(t,
+		`400 {"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`,
+	)
+
+	err := c.SendMessage("hello")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest || apiErr.Description != "Bad Request: chat not found" {
+		t.Fatalf("err = %v, want the 400 APIError", err)
+	}
+	if len(*requests) != 1 || len(*waits) != 0 {
+		t.Errorf("sent %d requests with waits %v, want one without retrying", len(*requests), *waits)
+	}
+}
+
+func TestSendGivesUpAfterMaxAttempts(t *testing.T) {
+	c, requests, waits := newTestClient(t,
+		`500 {"ok":false,"error_code":500,"description":"Internal Server Error"}`,
+		`500 not json`,
+		`500 {"ok":false,"error_code":502,"description":"Bad Gateway"}`,
+	)
+
+	err := c.SendMessage("hello")
+	if err == nil || !strings.Contains(err.Error(), "giving up after 3 attempts") {
+		t.Fatalf("err = %v, want giving up after 3 attempts", err)
+	}
+	if len(*requests) != 3 {
+		t.Errorf("sent %d requests, want 3", len(*requests))
+	}
+	if len(*waits) != 2 || (*waits)[0] != time.Second || (*waits)[1] != 2*time.Second {
+		t.Errorf("waits = %v, want 1s then 2s", *waits)
+	}
+}
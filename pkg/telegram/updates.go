@@ -0,0 +1,55 @@
+package telegram
+
+import (
+	"context"
+	"strconv"
+)
+
+// Update is an incoming event from getUpdates. Only message updates are decoded.
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+// Message is a chat message sent to the bot.
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	Text      string `json:"text"`
+	Chat      Chat   `json:"chat"`
+	From      *User  `json:"from"`
+}
+
+// Chat identifies the conversation a message belongs to.
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+// User is the sender of a message.
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// ChatIDString returns the chat ID in the form accepted by SendMessageTo.
+func (c Chat) ChatIDString() string {
+	return strconv.FormatInt(c.ID, 10)
+}
+
+// longPollTimeout is how long getUpdates may hold a request open, in seconds.
+// It must stay below the HTTP client timeout.
+const longPollTimeout = 25
+
+// GetUpdates long-polls for updates with IDs of at least offset.
+func (c *Client) GetUpdates(ctx context.Context, offset int64) ([]Update, error) {
+	payload := map[string]interface{}{
+		"offset":          offset,
+		"timeout":         longPollTimeout,
+		"allowed_updates": []string{"message"},
+	}
+
+	var updates []Update
+	if err := c.call(ctx, "getUpdates", payload, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
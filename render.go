@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+)
+
+// Console report layouts, chosen with --layout.
+const (
+	layoutVerbose = "verbose"
+	layoutCompact = "compact"
+	layoutTable   = "table"
+)
+
+func checkLayout(layout string) error {
+	switch layout {
+	case layoutVerbose, layoutCompact, layoutTable:
+		return nil
+	}
+	return fmt.Errorf("unknown layout %q (want verbose, compact or table)", layout)
+}
+
+// reportTemplates define one template per layout. Alerts and missing prices
+// are worded by the same eventLine and missingLine as Telegram messages.
+var reportTemplates = template.Must(template.New("report").Funcs(template.FuncMap{
+	"event": func(ev monitor.Event) string {
+		line, color := eventLine(ev)
+		return colorize(color, line)
+	},
+	"missing": func(sr monitor.SymbolResult) string {
+		return colorize(ansiYellow, missingLine(sr.Symbol, sr.Failures, sr.Err))
+	},
+	"paint": colorize,
+}).Parse(`
+{{- define "verbose"}}
+{{- range .Events}}{{with event .}}{{.}}
+{{end}}{{end}}
+{{- range .Missing}}{{missing .}}
+{{end}}
+{{- end}}
+
+{{- define "compact"}}
+{{- range .Rows}}
+{{- if .Priced}}{{paint .Color (printf "%s %s %dx: %f -> %f (%+.2f%%)" .Symbol .Side .Leverage .EntryPrice .FairPrice .DifferencePercent)}}
+{{else}}{{paint .Color (printf "%s %s %dx: %f -> no fair price" .Symbol .Side .Leverage .EntryPrice)}}
+{{end}}
+{{- else}}No open positions.
+{{end}}
+{{- template "alerts" .}}
+{{- end}}
+
+{{- define "table"}}
+{{- if .Rows}}SYMBOL	SIDE	LEVERAGE	CONTRACTS	ENTRY PRICE	FAIR PRICE	DIFFERENCE
+{{range .Rows}}{{.Symbol}}	{{.Side}}	{{.Leverage}}x	{{.Contracts}}	{{printf "%f" .EntryPrice}}	{{if .Priced}}{{printf "%f" .FairPrice}}	{{paint .Color (printf "%+f (%+.2f%%)" .Difference .DifferencePercent)}}{{else}}-	{{paint .Color "no fair price"}}{{end}}
+{{end}}
+{{- else}}No open positions.
+{{end}}
+{{- template "alerts" .}}
+{{- end}}
+
+{{- define "alerts"}}
+{{- range .Alerts}}{{with event .}}{{.}}
+{{end}}{{end}}
+{{- range .Missing}}{{missing .}}
+{{end}}
+{{- end}}
+`))
+
+// reportView is what the report templates see.
+type reportView struct {
+	// Rows has one entry per position.
+	Rows []reportRow
+	// Events are everything the poll reported, in order, and Alerts the
+	// ones other than the divergence updates covered by Rows.
+	Events []monitor.Event
+	Alerts []monitor.Event
+	// Missing are the symbols without a fair price.
+	Missing []monitor.SymbolResult
+}
+
+type reportRow struct {
+	Symbol            string
+	Side              string
+	Leverage          int
+	Contracts         float64
+	EntryPrice        float64
+	Priced            bool
+	FairPrice         float64
+	Difference        float64
+	DifferencePercent float64
+	// Breached is set when the divergence is past the symbol's threshold.
+	Breached bool
+	// Color is the ANSI code for the row on the console. The table layout
+	// only paints the last column, whose width tabwriter doesn't align.
+	Color string
+}
+
+// newReportView builds the view of a poll, marking rows past threshold,
+// which may be nil.
+func newReportView(res monitor.CycleResult, threshold func(symbol string) monitor.Threshold) reportView {
+	view := reportView{Events: res.Events, Missing: res.Failed()}
+	for _, ev := range res.Events {
+		if ev.Kind != monitor.Updated {
+			view.Alerts = append(view.Alerts, ev)
+		}
+	}
+	for _, c := range comparisons(res) {
+		row := reportRow{
+			Symbol:     c.Symbol,
+			Side:       c.Side,
+			Leverage:   c.Leverage,
+			Contracts:  c.Contracts,
+			EntryPrice: c.EntryPrice,
+			Priced:     c.FairPrice != nil,
+		}
+		if row.Priced {
+			row.FairPrice, row.Difference = *c.FairPrice, *c.Difference
+			if c.DifferencePercent != nil {
+				row.DifferencePercent = *c.DifferencePercent
+			}
+			if threshold != nil {
+				row.Breached = threshold(c.Symbol).Breached(row.FairPrice, row.EntryPrice)
+			}
+			if row.Difference != 0 {
+				row.Color = breachColor(divergenceColor(c.positionType, row.Difference), row.Breached)
+			}
+		} else {
+			row.Color = ansiYellow
+		}
+		view.Rows = append(view.Rows, row)
+	}
+	return view
+}
+
+// renderReport writes the console report of a poll in layout, in bold for
+// positions past threshold.
+func renderReport(w io.Writer, layout string, res monitor.CycleResult, threshold func(symbol string) monitor.Threshold) error {
+	view := newReportView(res, threshold)
+	if layout != layoutTable {
+		return reportTemplates.ExecuteTemplate(w, layout, view)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if err := reportTemplates.ExecuteTemplate(tw, layout, view); err != nil {
+		return err
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+)
+
+func testCycle() monitor.CycleResult {
+	return monitor.CycleResult{
+		Positions: []mexc.Position{
+			{Symbol: "BTC_USDT", PositionType: mexc.PositionTypeLong, HoldVol: 10, HoldAvgPrice: 60000, Leverage: 10},
+			{Symbol: "ETH_USDT", PositionType: mexc.PositionTypeShort, HoldVol: 5, HoldAvgPrice: 3000, Leverage: 20},
+		},
+		Symbols: []monitor.SymbolResult{
+			{Symbol: "BTC_USDT", FairPrice: 63000}, // +5%
+			{Symbol: "ETH_USDT", FairPrice: 3030},  // +1%, against the short
+		},
+	}
+}
+
+func twoPercent(string) monitor.Threshold { return monitor.Threshold{Percent: 2} }
+
+func TestRenderReportBreaches(t *testing.T) {
+	defer func(was bool) { useColor = was }(useColor)
+
+	tests := []struct {
+		layout   string
+		useColor bool
+		want     []string
+		wantNot  []string
+	}{
+		{layoutCompact, true,
+			[]string{ansiBold + ansiGreen + "BTC_USDT long", ansiRed + "ETH_USDT short"},
+			[]string{ansiBold + ansiRed}},
+		{layoutTable, true,
+			[]string{ansiBold + ansiGreen + "+3000.000000 (+5.00%)" + ansiReset, ansiRed + "+30.000000 (+1.00%)" + ansiReset},
+			[]string{ansiBold + ansiRed}},
+		{layoutCompact, false, nil, []string{"\033["}},
+		{layoutTable, false, nil, []string{"\033["}},
+	}
+	for _, tt := range tests {
+		useColor = tt.useColor
+		var b bytes.Buffer
+		if err := renderReport(&b, tt.layout, testCycle(), twoPercent); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("%s layout, color %v: %q doesn't contain %q", tt.layout, tt.useColor, b.String(), want)
+			}
+		}
+		for _, not := range tt.wantNot {
+			if strings.Contains(b.String(), not) {
+				t.Errorf("%s layout, color %v: %q contains %q", tt.layout, tt.useColor, b.String(), not)
+			}
+		}
+	}
+}
+
+func TestRenderReportTableAligned(t *testing.T) {
+	defer func(was bool) { useColor = was }(useColor)
+	useColor = true
+
+	var b bytes.Buffer
+	if err := renderReport(&b, layoutTable, testCycle(), twoPercent); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want a header and two rows:\n%s", len(lines), b.String())
+	}
+	column := strings.Index(lines[0], "DIFFERENCE")
+	for _, line := range lines[1:] {
+		if got := strings.Index(line, "\033["); got != column {
+			t.Errorf("difference starts at %d in %q, want %d", got, line, column)
+		}
+	}
+}
+
+func TestRenderReportGolden(t *testing.T) {
+	defer func(was bool) { useColor = was }(useColor)
+	useColor = false
+
+	res := testCycle()
+	res.Positions = append(res.Positions, mexc.Position{Symbol: "SOL_USDT", PositionType: mexc.PositionTypeLong, HoldVol: 2.5, HoldAvgPrice: 150, Leverage: 5})
+	res.Symbols = append(res.Symbols, monitor.SymbolResult{Symbol: "SOL_USDT", Err: errors.New("timeout"), Failures: 3})
+	res.Events = []monitor.Event{
+		{Kind: monitor.Updated, Position: res.Positions[0], FairPrice: 63000},
+		{Kind: monitor.Stale, Position: res.Positions[1], HeldFor: 50 * time.Hour},
+	}
+
+	want := map[string]string{
+		layoutVerbose: `For BTC_USDT, FairPrice (63000.000000) is greater than HoldAvgPrice (60000.000000) by: 3000.000000 (5.00%)
+ETH_USDT short has been open for 2d 2h; still the plan?
+SOL_USDT: no fair price for 3 polls, so its positions are not being checked (timeout)
+`,
+		layoutCompact: `BTC_USDT long 10x: 60000.000000 -> 63000.000000 (+5.00%)
+ETH_USDT short 20x: 3000.000000 -> 3030.000000 (+1.00%)
+SOL_USDT long 5x: 150.000000 -> no fair price
+ETH_USDT short has been open for 2d 2h; still the plan?
+SOL_USDT: no fair price for 3 polls, so its positions are not being checked (timeout)
+`,
+		layoutTable: `SYMBOL    SIDE   LEVERAGE  CONTRACTS  ENTRY PRICE   FAIR PRICE    DIFFERENCE
+BTC_USDT  long   10x       10         60000.000000  63000.000000  +3000.000000 (+5.00%)
+ETH_USDT  short  20x       5          3000.000000   3030.000000   +30.000000 (+1.00%)
+SOL_USDT  long   5x        2.5        150.000000    -             no fair price
+ETH_USDT short has been open for 2d 2h; still the plan?
+SOL_USDT: no fair price for 3 polls, so its positions are not being checked (timeout)
+`,
+	}
+	for _, layout := range []string{layoutVerbose, layoutCompact, layoutTable} {
+		var b bytes.Buffer
+		if err := renderReport(&b, layout, res, twoPercent); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != want[layout] {
+			t.Errorf("%s layout:\n%s\nwant:\n%s", layout, got, want[layout])
+		}
+	}
+
+	// Without positions, the poll's other alerts still show.
+	res.Positions, res.Symbols = nil, nil
+	var b bytes.Buffer
+	if err := renderReport(&b, layoutCompact, res, twoPercent); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "No open positions.\nETH_USDT short has been open for 2d 2h; still the plan?\n"; got != want {
+		t.Errorf("compact layout without positions = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,530 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/internal/storage"
+	"github.com/killabayte/golang-telegram-bot/pkg/alert"
+	"github.com/killabayte/golang-telegram-bot/pkg/binance"
+	"github.com/killabayte/golang-telegram-bot/pkg/bybit"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+	"github.com/killabayte/golang-telegram-bot/pkg/rebalance"
+	"github.com/killabayte/golang-telegram-bot/pkg/telegram"
+)
+
+// accountProblemRepeat is how often the same account problem is repeated to
+// Telegram while it persists.
+const accountProblemRepeat = time.Hour
+
+// accountProblems are errors that won't go away without the user's help, so
+// they are sent to Telegram as well as logged.
+var accountProblems = []error{
+	mexc.ErrUnauthorized,
+	mexc.ErrInvalidSignature,
+	mexc.ErrIPNotWhitelisted,
+	mexc.ErrRequestExpired,
+	mexc.ErrPermission,
+	binance.ErrUnauthorized,
+	binance.ErrInvalidSignature,
+	binance.ErrRequestExpired,
+	bybit.ErrUnauthorized,
+	bybit.ErrInvalidSignature,
+	bybit.ErrIPNotWhitelisted,
+	bybit.ErrRequestExpired,
+	bybit.ErrPermission,
+}
+
+// outboxSize is how many Telegram messages may queue before send blocks.
+const outboxSize = 64
+
+// flushTimeout is how long shutdown waits for queued Telegram messages.
+const flushTimeout = 10 * time.Second
+
+// reporter delivers report lines to Telegram when configured, and to the
+// console otherwise. Telegram messages are queued and sent in order by a
+// background goroutine, so a slow Bot API doesn't hold up the monitor.
+type reporter struct {
+	notifier *telegram.Client
+	// problems throttles account problem notifications.
+	problems *alert.Manager
+
+	outbox chan outgoing
+	// sent is the Telegram message ID of the latest alert per alert key,
+	// which its resolution replies to. Only deliver uses it.
+	sent map[string]int64
+	// incidents groups bursts of alerts, if enabled. Only deliver uses it.
+	incidents *incidents
+	done      chan struct{}
+	// sendCtx is cancelled by abort when flush runs out of time.
+	sendCtx context.Context
+	abort   context.CancelFunc
+}
+
+// newReporter returns a reporter for notifier, or for the console when it is
+// nil. incidents, if non-nil, groups bursts of Telegram alerts.
+func newReporter(notifier *telegram.Client, incidents *incidents) *reporter {
+	r := &reporter{
+		notifier:  notifier,
+		problems:  alert.NewManager(alert.Policy{Repeat: accountProblemRepeat}),
+		incidents: incidents,
+	}
+	if notifier != nil {
+		r.outbox = make(chan outgoing, outboxSize)
+		r.sent = make(map[string]int64)
+		r.done = make(chan struct{})
+		r.sendCtx, r.abort = context.WithCancel(context.Background())
+		go r.deliver()
+	}
+	return r
+}
+
+// outgoing is a queued Telegram message. A message with an alertKey is an
+// alert, or with reply set the end of one, sent as a reply to the alert.
+type outgoing struct {
+	text     string
+	alertKey string
+	reply    bool
+	// kind names the event kind of an alert, for incident summaries.
+	kind string
+}
+
+// send delivers line; color is only used on the console.
+func (r *reporter) send(line, color string) {
+	r.sendAlert(outgoing{text: line}, color)
+}
+
+func (r *reporter) sendAlert(msg outgoing, color string) {
+	if r.notifier != nil {
+		r.outbox <- msg
+		return
+	}
+	fmt.Println(colorize(color, msg.text))
+}
+
+func (r *reporter) deliver() {
+	defer close(r.done)
+	dropped := 0
+	for msg := range r.outbox {
+		if r.sendCtx.Err() != nil {
+			dropped++
+			continue
+		}
+		if !r.group(msg) {
+			r.deliverOne(msg)
+		}
+		// Edits wait until the queue is drained, so a burst of alerts
+		// updates the incident once.
+		if len(r.outbox) == 0 {
+			r.editIncident()
+		}
+	}
+	if dropped > 0 {
+		slog.Warn("dropped unsent Telegram messages at shutdown", "count", dropped)
+	}
+}
+
+func (r *reporter) deliverOne(msg outgoing) {
+	var replyTo int64
+	if msg.reply {
+		replyTo = r.sent[msg.alertKey]
+		delete(r.sent, msg.alertKey)
+	}
+	id, err := r.notifier.SendReplyContext(r.sendCtx, msg.text, replyTo)
+	if err != nil {
+		slog.Error("sending Telegram message", errAttrs(err)...)
+		return
+	}
+	if msg.alertKey != "" && !msg.reply {
+		r.sent[msg.alertKey] = id
+	}
+}
+
+// group adds msg to the current incident, opening one when msg completes a
+// burst, and reports whether it did. Grouped messages are not sent on their
+// own: the incident's head message lists them. Once every alert of an
+// incident resolved, a reply to the head says so.
+func (r *reporter) group(msg outgoing) bool {
+	g := r.incidents
+	if g == nil || msg.alertKey == "" {
+		return false
+	}
+	now := time.Now()
+	if msg.reply {
+		found, over := g.resolve(msg.alertKey)
+		if !found {
+			return false
+		}
+		delete(r.sent, msg.alertKey)
+		if over {
+			inc := g.current
+			r.editIncident()
+			g.current = nil
+			text := fmt.Sprintf("Incident over: all %d alerts resolved after %s", len(inc.alerts), formatAlertAge(now.Sub(inc.alerts[0].at)))
+			if _, err := r.notifier.SendReplyContext(r.sendCtx, text, inc.head); err != nil {
+				slog.Error("sending Telegram message", errAttrs(err)...)
+			}
+		}
+		return true
+	}
+
+	if !g.add(msg, now) {
+		return false
+	}
+	inc := g.current
+	if inc.head == 0 {
+		id, err := r.notifier.SendContext(r.sendCtx, telegram.OutgoingMessage{Text: inc.text(), HTML: true})
+		if err != nil {
+			slog.Error("sending Telegram incident", errAttrs(err)...)
+			g.current = nil
+			return false
+		}
+		inc.head, inc.dirty = id, false
+	}
+	// Should a later incident replace this one, the alert's resolution
+	// replies to the head instead.
+	r.sent[msg.alertKey] = inc.head
+	return true
+}
+
+// editIncident brings the current incident's head message up to date.
+func (r *reporter) editIncident() {
+	g := r.incidents
+	if g == nil || g.current == nil || g.current.head == 0 || !g.current.dirty {
+		return
+	}
+	inc := g.current
+	inc.dirty = false
+	if err := r.notifier.EditMessageContext(r.sendCtx, inc.head, telegram.OutgoingMessage{Text: inc.text(), HTML: true}); err != nil {
+		slog.Error("updating Telegram incident", errAttrs(err)...)
+	}
+}
+
+// flush waits up to timeout for queued messages to be sent and abandons the
+// rest. send must not be called afterwards.
+func (r *reporter) flush(timeout time.Duration) {
+	if r.outbox == nil {
+		return
+	}
+	close(r.outbox)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-r.done:
+	case <-timer.C:
+		r.abort()
+		<-r.done
+	}
+	r.abort()
+}
+
+// divergenceEventLine words the fair price comparison for ev's position,
+// with the order book imbalance and funding appended when present. It is
+// empty when there is no difference.
+func divergenceEventLine(ev monitor.Event) (line, color string) {
+	pos := ev.Position
+	line = divergenceLine(pos.Symbol, ev.FairPrice, pos.HoldAvgPrice)
+	if line == "" {
+		return "", ""
+	}
+	if ev.HasImbalance {
+		line += fmt.Sprintf(", book imbalance %s", formatImbalance(ev.Imbalance))
+	}
+	if ev.HasFunding {
+		line += ", funding " + formatFunding(ev.Funding)
+	}
+	// Divergence alerts only fire while the threshold is breached.
+	return line, breachColor(divergenceColor(pos.PositionType, ev.FairPrice-pos.HoldAvgPrice), true)
+}
+
+// formatImbalance renders an imbalance like "+0.42 (bid-heavy)".
+func formatImbalance(imbalance float64) string {
+	side := "balanced"
+	switch {
+	case imbalance > 0:
+		side = "bid-heavy"
+	case imbalance < 0:
+		side = "ask-heavy"
+	}
+	return fmt.Sprintf("%+.2f (%s)", imbalance, side)
+}
+
+// formatFunding renders funding like "+0.0100% next, +0.0080% last", leaving
+// out the last settlement when the exchange has no history.
+func formatFunding(f monitor.Funding) string {
+	s := fmt.Sprintf("%+.4f%% next", f.Rate*100)
+	if f.HasLast {
+		s += fmt.Sprintf(", %+.4f%% last", f.Last*100)
+	}
+	return s
+}
+
+// fundingLine words a FundingAlert: the rate the position pays and when.
+func fundingLine(ev monitor.Event) string {
+	pos, f := ev.Position, ev.Funding
+	line := fmt.Sprintf("%s %s pays %.4f%% funding at the next settlement", pos.Symbol, pos.Side(), f.Paid(pos)*100)
+	if until := time.Until(time.UnixMilli(f.NextSettleTime)); f.NextSettleTime > 0 && until > 0 {
+		line += " in " + formatAlertAge(until)
+	}
+	if f.HasLast {
+		line += fmt.Sprintf("; the last one settled at %+.4f%%", f.Last*100)
+	}
+	return line
+}
+
+// eventLine words ev for the console and Telegram; color is only used on the
+// console. line is empty for events that aren't reported.
+func eventLine(ev monitor.Event) (line, color string) {
+	switch ev.Kind {
+	case monitor.Updated:
+		return divergenceEventLine(ev)
+	case monitor.ImbalanceAlert:
+		return fmt.Sprintf("%s order book imbalance %s near fair price %f", ev.Position.Symbol, formatImbalance(ev.Imbalance), ev.FairPrice), ansiYellow
+	case monitor.Stale:
+		return fmt.Sprintf("%s %s has been open for %s; still the plan?", ev.Position.Symbol, ev.Position.Side(), formatHeldFor(ev.HeldFor)), ansiYellow
+	case monitor.OrderFilled:
+		o := ev.Order
+		status := "partially filled"
+		if o.State == mexc.OrderStateCompleted {
+			status = "filled"
+		}
+		return fmt.Sprintf("%s %s order %s: %g of %g contracts @ %f", o.Symbol, o.SideName(), status, o.DealVol, o.Vol, o.DealAvgPrice), ""
+	case monitor.ADL:
+		if ev.ADLLevel >= 4 {
+			color = ansiRed
+		}
+		return fmt.Sprintf("%s %s auto-deleveraging rank is now %d/5", ev.Position.Symbol, ev.Position.Side(), ev.ADLLevel), color
+	case monitor.Closed:
+		return fmt.Sprintf("%s %s position closed", ev.Position.Symbol, ev.Position.Side()), ""
+	case monitor.HedgeDrift:
+		e := ev.Exposure
+		return fmt.Sprintf("%s hedge out of balance: net %s (%.0f%% of the larger leg)", e.Underlying, formatExposure(e), e.Drift()*100), ansiYellow
+	case monitor.DataMissing:
+		return missingLine(ev.Position.Symbol, ev.Failures, ev.Err), ansiYellow
+	case monitor.EquityDrop:
+		d := ev.Drawdown
+		return fmt.Sprintf("%s equity fell %.2f%% within %s: %.4f, down from %.4f", d.Currency, d.Percent(), formatHeldFor(d.Window), d.Equity, d.Peak), ansiRed
+	case monitor.FundingAlert:
+		return fundingLine(ev), ansiYellow
+	case monitor.Resolved:
+		return resolvedLine(ev), ansiGreen
+	}
+	return "", ""
+}
+
+// resolvedLine words a Resolved event: what is back to normal, and how long
+// after the alert.
+func resolvedLine(ev monitor.Event) string {
+	pos := ev.Position
+	var what string
+	switch ev.Resolves {
+	case monitor.Updated:
+		if ev.Changed {
+			what = fmt.Sprintf("%s %s changed to %g contracts at entry %f, so its divergence alert starts over", pos.Symbol, pos.Side(), pos.HoldVol, pos.HoldAvgPrice)
+		} else {
+			what = fmt.Sprintf("%s %s is back within its threshold: fair price %f vs entry %f", pos.Symbol, pos.Side(), ev.FairPrice, pos.HoldAvgPrice)
+		}
+	case monitor.ImbalanceAlert:
+		what = fmt.Sprintf("%s order book imbalance is back to %s", pos.Symbol, formatImbalance(ev.Imbalance))
+	case monitor.HedgeDrift:
+		e := ev.Exposure
+		if !e.Hedged() {
+			what = fmt.Sprintf("%s is no longer held on both sides: net %s", e.Underlying, formatExposure(e))
+		} else {
+			what = fmt.Sprintf("%s hedge is back in balance: net %s (%.0f%% of the larger leg)", e.Underlying, formatExposure(e), e.Drift()*100)
+		}
+	case monitor.DataMissing:
+		what = fmt.Sprintf("%s fair prices are coming through again", pos.Symbol)
+	case monitor.EquityDrop:
+		d := ev.Drawdown
+		what = fmt.Sprintf("%s equity recovered to %.4f, %.2f%% below its peak of %.4f", d.Currency, d.Equity, d.Percent(), d.Peak)
+	case monitor.FundingAlert:
+		what = fmt.Sprintf("%s %s funding is back to %s", pos.Symbol, pos.Side(), formatFunding(ev.Funding))
+	default:
+		what = fmt.Sprintf("%s %s alert cleared", eventSymbol(ev), ev.Resolves)
+	}
+	if ev.FiredAt.IsZero() {
+		return "Resolved: " + what
+	}
+	return fmt.Sprintf("Resolved after %s: %s", formatAlertAge(time.Since(ev.FiredAt)), what)
+}
+
+// HandleEvent implements monitor.Handler. Alerts are remembered by their
+// alert key, so that a Resolved or Closed event replies to the alert it
+// ends.
+func (r *reporter) HandleEvent(ev monitor.Event) {
+	line, color := eventLine(ev)
+	if line == "" {
+		return
+	}
+	reply := ev.Kind == monitor.Resolved || ev.Kind == monitor.Closed
+	r.sendAlert(outgoing{text: line, alertKey: ev.AlertKey, reply: reply, kind: ev.Kind.String()}, color)
+}
+
+// missingLine reports that symbol has had no fair price for failures polls.
+func missingLine(symbol string, failures int, err error) string {
+	when := "this poll"
+	if failures > 1 {
+		when = fmt.Sprintf("for %d polls", failures)
+	}
+	return fmt.Sprintf("%s: no fair price %s, so its positions are not being checked (%v)", symbol, when, err)
+}
+
+// HandleError implements monitor.Handler. Errors are logged; account
+// problems such as a rejected signature are also sent to Telegram, at most
+// once per accountProblemRepeat each.
+func (r *reporter) HandleError(symbol string, err error) {
+	logMonitorError(symbol, err)
+
+	if r.notifier == nil {
+		return
+	}
+	for _, problem := range accountProblems {
+		if errors.Is(err, problem) && r.problems.Check(problem.Error(), true, false, time.Now()) {
+			r.send(fmt.Sprintf("Exchange API problem: %v", err), "")
+			return
+		}
+	}
+}
+
+// logMonitorError logs an error passed to monitor.Handler.HandleError.
+func logMonitorError(symbol string, err error) {
+	if symbol == "" {
+		slog.Error("monitor", errAttrs(err)...)
+		return
+	}
+	slog.Error("monitor", errAttrs(err, "symbol", symbol)...)
+}
+
+// historyHandler records every event in the history database before passing
+// it on. Resolved events mark the alerts they resolve instead, with the time
+// to resolution, and a Closed event resolves the position's divergence
+// alerts. Resolutions of a changed position are recorded as such.
+type historyHandler struct {
+	monitor.Handler
+	history *storage.DB
+}
+
+func (h *historyHandler) HandleEvent(ev monitor.Event) {
+	symbol := eventSymbol(ev)
+	now := time.Now()
+	switch ev.Kind {
+	case monitor.Resolved:
+		// The first alert of the episode, which the history may know of
+		// when the alert repeated, times the resolution.
+		resolution := storage.ResolutionCleared
+		if ev.Changed {
+			resolution = storage.ResolutionChanged
+		}
+		firstSent, ok, err := h.history.ResolveAlerts(ev.AlertKey, resolution, now)
+		if err != nil {
+			h.HandleError(symbol, fmt.Errorf("resolving alert: %w", err))
+		}
+		if ok {
+			ev.FiredAt = firstSent
+		}
+	case monitor.Closed:
+		if _, _, err := h.history.ResolveAlerts(ev.AlertKey, storage.ResolutionClosed, now); err != nil {
+			h.HandleError(symbol, fmt.Errorf("resolving alert: %w", err))
+		}
+		if err := h.history.RecordAlert(ev.Kind.String(), symbol, "", ev.Position.PositionID, ev.FairPrice, now); err != nil {
+			h.HandleError(symbol, fmt.Errorf("recording alert: %w", err))
+		}
+	default:
+		if err := h.history.RecordAlert(ev.Kind.String(), symbol, ev.AlertKey, ev.Position.PositionID, ev.FairPrice, now); err != nil {
+			h.HandleError(symbol, fmt.Errorf("recording alert: %w", err))
+		}
+	}
+	h.Handler.HandleEvent(ev)
+}
+
+// eventSymbol is the symbol ev is about: the underlying for hedge drift and
+// the currency for equity drops.
+func eventSymbol(ev monitor.Event) string {
+	kind := ev.Kind
+	if kind == monitor.Resolved {
+		kind = ev.Resolves
+	}
+	switch kind {
+	case monitor.HedgeDrift:
+		return ev.Exposure.Underlying
+	case monitor.EquityDrop:
+		return ev.Drawdown.Currency
+	}
+	return ev.Position.Symbol
+}
+
+// rebalanceHandler asks the rebalancer to correct every hedge drift alert
+// before passing the event on, and reports what it did.
+type rebalanceHandler struct {
+	monitor.Handler
+	ctx        context.Context
+	rebalancer *rebalance.Rebalancer
+	out        *reporter
+}
+
+func (h *rebalanceHandler) HandleEvent(ev monitor.Event) {
+	h.Handler.HandleEvent(ev)
+	if ev.Kind != monitor.HedgeDrift {
+		return
+	}
+
+	underlying := ev.Exposure.Underlying
+	res, err := h.rebalancer.Rebalance(h.ctx, ev.Exposure)
+	switch {
+	case errors.Is(err, rebalance.ErrNothingToDo):
+		slog.Info("hedge rebalance skipped", errAttrs(err, "underlying", underlying)...)
+		return
+	case errors.Is(err, rebalance.ErrLimitReached):
+		h.out.send(fmt.Sprintf("%s hedge: %v", underlying, err), ansiYellow)
+		return
+	case err != nil:
+		h.HandleError(res.Order.Symbol, fmt.Errorf("rebalancing %s hedge: %w", underlying, err))
+		h.out.send(fmt.Sprintf("%s hedge rebalance failed: %v", underlying, err), ansiRed)
+		return
+	}
+
+	order := fmt.Sprintf("%s %g %s contracts at market (~%.2f at fair price %f)", orderSideName(res.Order.Side), res.Order.Vol, res.Order.Symbol, res.Value, res.FairPrice)
+	if res.Capped {
+		order += ", capped by max_order_value; the rest waits for the next drift alert"
+	}
+	if res.DryRun {
+		h.out.send(fmt.Sprintf("%s hedge rebalance (dry run): would %s", underlying, order), "")
+		return
+	}
+	h.out.send(fmt.Sprintf("%s hedge rebalanced: %s, order %s", underlying, order, res.OrderID), "")
+}
+
+// orderSideName describes an order side, e.g. "close short".
+func orderSideName(side int) string {
+	return mexc.Order{Side: side}.SideName()
+}
+
+// formatExposure renders an exposure like "+0.5 (long 1.5, short 1)".
+func formatExposure(e monitor.Exposure) string {
+	return fmt.Sprintf("%+.6g (long %.6g, short %.6g)", e.Net(), e.Long, e.Short)
+}
+
+// formatSensitivity renders the PnL effect of a +1% price move, a +0.01%
+// funding rate change and a day of funding at the current rate.
+func formatSensitivity(s mexc.Sensitivity) string {
+	return fmt.Sprintf("+1%% price %+.2f, +0.01%% funding %+.4f per settlement, funding %+.4f/day", s.PerPercent, s.PerFundingStep, s.FundingPerDay)
+}
+
+// formatHeldFor renders a duration in days and hours, e.g. "3d 4h", or in
+// minutes when it is under an hour.
+func formatHeldFor(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dm", int(d/time.Minute))
+}
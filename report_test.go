@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/killabayte/golang-telegram-bot/internal/storage"
+	"github.com/killabayte/golang-telegram-bot/pkg/mexc"
+	"github.com/killabayte/golang-telegram-bot/pkg/monitor"
+	"github.com/killabayte/golang-telegram-bot/pkg/telegram"
+)
+
+// sentMessage is a sendMessage or editMessageText call to fakeTelegram.
+type sentMessage struct {
+	Method  string `json:"-"`
+	ID      int64  `json:"-"` // assigned to sent messages, or edited
+	ReplyTo int64  `json:"-"`
+
+	Text            string `json:"text"`
+	MessageID       int64  `json:"message_id"`
+	ReplyParameters *struct {
+		MessageID int64 `json:"message_id"`
+	} `json:"reply_parameters"`
+}
+
+// fakeTelegram serves the Bot API methods the reporter calls, numbering
+// sent messages from 1.
+type fakeTelegram struct {
+	mu       sync.Mutex
+	messages []sentMessage
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var msg sentMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	msg.Method = r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	if msg.ReplyParameters != nil {
+		msg.ReplyTo = msg.ReplyParameters.MessageID
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if msg.Method == "editMessageText" {
+		msg.ID = msg.MessageID
+		f.messages = append(f.messages, msg)
+		fmt.Fprint(w, `{"ok":true,"result":true}`)
+		return
+	}
+	msg.ID = int64(len(f.messages) + 1)
+	f.messages = append(f.messages, msg)
+	fmt.Fprintf(w, `{"ok":true,"result":{"message_id":%d}}`, msg.ID)
+}
+
+// newTestReporter returns a reporter sending to a fakeTelegram.
+func newTestReporter(t *testing.T, g *incidents) (*reporter, *fakeTelegram) {
+	t.Helper()
+	f := &fakeTelegram{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return newReporter(telegram.NewClientWithBaseURL("123:abc", "42", srv.URL), g), f
+}
+
+func divergenceEvent(kind monitor.EventKind, symbol string) monitor.Event {
+	return monitor.Event{
+		Kind:      kind,
+		Position:  mexc.Position{Symbol: symbol, PositionType: mexc.PositionTypeLong, HoldVol: 10, HoldAvgPrice: 100},
+		FairPrice: 102,
+		AlertKey:  "divergence:" + symbol + "/1",
+		Resolves:  monitor.Updated,
+	}
+}
+
+func TestReporterRepliesToAlert(t *testing.T) {
+	fired := func(symbol string) monitor.Event { return divergenceEvent(monitor.Updated, symbol) }
+	resolved := func(symbol string) monitor.Event { return divergenceEvent(monitor.Resolved, symbol) }
+	changed := func(symbol string) monitor.Event {
+		ev := resolved(symbol)
+		ev.Changed = true
+		return ev
+	}
+	closed := func(symbol string) monitor.Event { return divergenceEvent(monitor.Closed, symbol) }
+
+	tests := []struct {
+		name   string
+		events []monitor.Event
+		// replyTo has the message each sent message replies to, by ID.
+		replyTo []int64
+	}{
+		{"resolved", []monitor.Event{fired("BTC_USDT"), resolved("BTC_USDT")}, []int64{0, 1}},
+		{"closed", []monitor.Event{fired("BTC_USDT"), closed("BTC_USDT")}, []int64{0, 1}},
+		{"repeated alert", []monitor.Event{fired("BTC_USDT"), fired("BTC_USDT"), resolved("BTC_USDT")}, []int64{0, 0, 2}},
+		{"changed then fired again", []monitor.Event{fired("BTC_USDT"), changed("BTC_USDT"), fired("BTC_USDT"), resolved("BTC_USDT")}, []int64{0, 1, 0, 3}},
+		{"separate keys", []monitor.Event{fired("BTC_USDT"), fired("ETH_USDT"), resolved("BTC_USDT"), resolved("ETH_USDT")}, []int64{0, 0, 1, 2}},
+		{"resolution replies once", []monitor.Event{fired("BTC_USDT"), resolved("BTC_USDT"), resolved("BTC_USDT")}, []int64{0, 1, 0}},
+		{"resolution without alert", []monitor.Event{resolved("BTC_USDT")}, []int64{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, f := newTestReporter(t, nil)
+			for _, ev := range tt.events {
+				r.HandleEvent(ev)
+			}
+			r.flush(5 * time.Second)
+
+			if len(f.messages) != len(tt.replyTo) {
+				t.Fatalf("sent %d messages, want %d", len(f.messages), len(tt.replyTo))
+			}
+			for i, msg := range f.messages {
+				if msg.ReplyTo != tt.replyTo[i] {
+					t.Errorf("message %d %q replies to %d, want %d", msg.ID, msg.Text, msg.ReplyTo, tt.replyTo[i])
+				}
+			}
+		})
+	}
+}
+
+// handlerFunc passes events on to a function.
+type handlerFunc func(monitor.Event)
+
+func (f handlerFunc) HandleEvent(ev monitor.Event)         { f(ev) }
+func (f handlerFunc) HandleError(symbol string, err error) {}
+
+func TestHistoryHandlerResolutions(t *testing.T) {
+	changed := divergenceEvent(monitor.Resolved, "BTC_USDT")
+	changed.Changed = true
+
+	tests := []struct {
+		name   string
+		events []monitor.Event
+		// want has the status of each alert recorded, oldest first: its
+		// resolution, or "active".
+		want []string
+	}{
+		{"active", []monitor.Event{divergenceEvent(monitor.Updated, "BTC_USDT")}, []string{"active"}},
+		{"cleared", []monitor.Event{divergenceEvent(monitor.Updated, "BTC_USDT"), divergenceEvent(monitor.Resolved, "BTC_USDT")}, []string{storage.ResolutionCleared}},
+		{"changed", []monitor.Event{divergenceEvent(monitor.Updated, "BTC_USDT"), changed}, []string{storage.ResolutionChanged}},
+		{"closed", []monitor.Event{divergenceEvent(monitor.Updated, "BTC_USDT"), divergenceEvent(monitor.Closed, "BTC_USDT")}, []string{storage.ResolutionClosed, ""}},
+		{"repeated then cleared", []monitor.Event{divergenceEvent(monitor.Updated, "BTC_USDT"), divergenceEvent(monitor.Updated, "BTC_USDT"), divergenceEvent(monitor.Resolved, "BTC_USDT")}, []string{storage.ResolutionCleared, storage.ResolutionCleared}},
+		{"changed then fired again", []monitor.Event{divergenceEvent(monitor.Updated, "BTC_USDT"), changed, divergenceEvent(monitor.Updated, "BTC_USDT")}, []string{storage.ResolutionChanged, "active"}},
+		{"other key untouched", []monitor.Event{divergenceEvent(monitor.Updated, "BTC_USDT"), divergenceEvent(monitor.Updated, "ETH_USDT"), changed}, []string{storage.ResolutionChanged, "active"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := storage.Open(filepath.Join(t.TempDir(), "history.db"), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			var passed []monitor.Event
+			h := &historyHandler{Handler: handlerFunc(func(ev monitor.Event) { passed = append(passed, ev) }), history: db}
+			for _, ev := range tt.events {
+				h.HandleEvent(ev)
+				// Alerts are stored to the millisecond; keep them apart.
+				time.Sleep(2 * time.Millisecond)
+			}
+
+			alerts, err := db.Alerts(time.Time{}, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for i := len(alerts) - 1; i >= 0; i-- {
+				status := alerts[i].Resolution
+				if alerts[i].Active() {
+					status = "active"
+				}
+				got = append(got, status)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("alerts %v, want %v", got, tt.want)
+			}
+
+			if len(passed) != len(tt.events) {
+				t.Fatalf("passed on %d events, want %d", len(passed), len(tt.events))
+			}
+			for _, ev := range passed {
+				if ev.Kind == monitor.Resolved && ev.FiredAt.IsZero() {
+					t.Error("resolution passed on without the time the alert was first sent")
+				}
+			}
+		})
+	}
+}
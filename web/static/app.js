@@ -0,0 +1,209 @@
+// Dashboard front end: polls the positions API, draws the fair price chart
+// of the selected symbol and follows monitor events over server-sent events.
+"use strict";
+
+const positionsRefresh = 30000;
+const maxEvents = 200;
+const alertsPerPage = 25;
+
+const $ = (selector) => document.querySelector(selector);
+
+async function api(path) {
+  const response = await fetch(path, { credentials: "same-origin" });
+  if (response.status === 401) {
+    location.href = "/login";
+    throw new Error("not logged in");
+  }
+  const body = await response.json();
+  if (!response.ok) {
+    throw new Error(body.error || response.statusText);
+  }
+  return body;
+}
+
+function cell(row, text, className) {
+  const td = row.insertCell();
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+function number(v, digits) {
+  return v === null || v === undefined ? "–" : Number(v).toFixed(digits);
+}
+
+async function loadPositions() {
+  const view = await api("/api/positions");
+  $("#updated").textContent = "as of " + new Date(view.updated).toLocaleTimeString();
+  const body = $("#positions tbody");
+  body.replaceChildren();
+  const symbols = new Set();
+  for (const p of view.positions) {
+    symbols.add(p.symbol);
+    const row = body.insertRow();
+    cell(row, p.symbol);
+    cell(row, p.side);
+    cell(row, p.leverage + "x");
+    cell(row, p.contracts);
+    cell(row, number(p.entry_price, 4));
+    if (p.error) {
+      const td = cell(row, p.error, "error");
+      td.colSpan = 2;
+      continue;
+    }
+    cell(row, number(p.fair_price, 4));
+    const trend = p.difference > 0 ? "up" : p.difference < 0 ? "down" : "";
+    cell(row, number(p.difference, 4) + " (" + number(p.difference_percent, 2) + "%)", trend);
+  }
+  if (view.positions.length === 0) {
+    cell(body.insertRow(), "No open positions.").colSpan = 7;
+  }
+  updateSymbols([...symbols].sort(), view.positions);
+}
+
+let entries = {};
+
+function updateSymbols(symbols, positions) {
+  entries = {};
+  for (const p of positions) {
+    entries[p.symbol] = p.entry_price;
+  }
+  const select = $("#symbol");
+  const current = select.value;
+  if (symbols.join() === [...select.options].map((o) => o.value).join()) {
+    return;
+  }
+  select.replaceChildren(...symbols.map((s) => new Option(s, s)));
+  select.value = symbols.includes(current) ? current : symbols[0] || "";
+  loadChart();
+}
+
+async function loadChart() {
+  const symbol = $("#symbol").value;
+  const svg = $("#chart");
+  const note = $("#chart-note");
+  svg.replaceChildren();
+  note.textContent = "";
+  if (!symbol) {
+    return;
+  }
+  let samples;
+  try {
+    samples = await api("/api/prices?symbol=" + encodeURIComponent(symbol) + "&window=" + $("#window").value);
+  } catch (err) {
+    note.textContent = err.message;
+    return;
+  }
+  if (samples.length < 2) {
+    note.textContent = "Not enough stored prices for " + symbol + " yet.";
+    return;
+  }
+
+  const times = samples.map((s) => Date.parse(s.time));
+  const prices = samples.map((s) => s.price);
+  const entry = entries[symbol];
+  let low = Math.min(...prices);
+  let high = Math.max(...prices);
+  if (entry) {
+    low = Math.min(low, entry);
+    high = Math.max(high, entry);
+  }
+  const span = high - low || 1;
+  const x = (t) => ((t - times[0]) / (times[times.length - 1] - times[0] || 1)) * 800;
+  const y = (p) => 230 - ((p - low) / span) * 220;
+
+  const ns = "http://www.w3.org/2000/svg";
+  const line = document.createElementNS(ns, "polyline");
+  line.setAttribute("points", samples.map((s, i) => x(times[i]) + "," + y(prices[i])).join(" "));
+  svg.append(line);
+  if (entry) {
+    const mark = document.createElementNS(ns, "line");
+    mark.setAttribute("x1", 0);
+    mark.setAttribute("x2", 800);
+    mark.setAttribute("y1", y(entry));
+    mark.setAttribute("y2", y(entry));
+    svg.append(mark);
+  }
+  note.textContent = "Low " + low.toFixed(4) + ", high " + high.toFixed(4) + (entry ? ", dashed line at entry " + entry.toFixed(4) : "");
+}
+
+let alertPage = 1;
+
+function alertStatus(a) {
+  if (a.resolved_at) {
+    const after = Math.round((a.resolved_after_seconds || 0) / 60);
+    const how = { closed: "position closed", changed: "position changed" }[a.resolution] || "resolved";
+    return how + " after " + after + "m";
+  }
+  return a.alert_key ? "active" : "";
+}
+
+async function loadAlerts() {
+  const body = $("#alerts tbody");
+  body.replaceChildren();
+  let view;
+  try {
+    view = await api("/api/alerts/history?limit=" + alertsPerPage + "&page=" + alertPage);
+  } catch (err) {
+    cell(body.insertRow(), err.message).colSpan = 5;
+    return;
+  }
+  for (const a of view.alerts) {
+    const row = body.insertRow();
+    cell(row, new Date(a.time).toLocaleString());
+    cell(row, a.kind);
+    cell(row, a.symbol);
+    cell(row, number(a.fair_price, 4));
+    cell(row, alertStatus(a), a.resolved_at || !a.alert_key ? "" : "active");
+  }
+  if (view.total === 0) {
+    cell(body.insertRow(), "No alerts recorded yet.").colSpan = 5;
+  }
+  $("#page").textContent = "page " + view.page + " of " + Math.max(view.pages, 1);
+  $("#newer").disabled = view.page <= 1;
+  $("#older").disabled = view.page >= view.pages;
+}
+
+function turnAlertPage(delta) {
+  alertPage = Math.max(alertPage + delta, 1);
+  loadAlerts();
+}
+
+function followEvents() {
+  const source = new EventSource("/api/events");
+  source.onopen = () => {
+    $("#status").textContent = "live";
+  };
+  source.onerror = () => {
+    $("#status").textContent = "reconnecting…";
+  };
+  source.onmessage = (message) => {
+    const ev = JSON.parse(message.data);
+    const item = document.createElement("li");
+    const time = document.createElement("time");
+    time.textContent = new Date(ev.time).toLocaleTimeString() + " ";
+    item.append(time, ev.text);
+    const list = $("#events");
+    list.prepend(item);
+    while (list.children.length > maxEvents) {
+      list.lastChild.remove();
+    }
+    loadPositions().catch(showError);
+    loadAlerts();
+  };
+}
+
+function showError(err) {
+  $("#status").textContent = err.message;
+}
+
+$("#symbol").addEventListener("change", loadChart);
+$("#window").addEventListener("change", loadChart);
+$("#newer").addEventListener("click", () => turnAlertPage(-1));
+$("#older").addEventListener("click", () => turnAlertPage(1));
+loadPositions().catch(showError);
+loadAlerts();
+followEvents();
+setInterval(() => loadPositions().catch(showError), positionsRefresh);